@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/arcetri/goprime/ledger"
+	"github.com/arcetri/goprime/output"
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// runBatch tests all the Riesel numbers listed in the given file with a pool
+// of the given number of workers and options, and prints the results as they
+// complete, as records of out if it is not nil.
+// The results are also appended to the given ledger, if it is not nil, and the
+// certificates of the primes found are written to the certificates directory.
+//
+// The file has the format read by rieseltest.ScanBatch. When opts.Stop is
+// closed, the tests in progress are interrupted and rieseltest.ErrInterrupted
+// is returned.
+func runBatch(path string, workers int, checkpoints, certificates string, opts *rieseltest.Options,
+	l *ledger.Ledger, out *output.Writer) error {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	// Keep a few numbers queued for every worker, so that they can be
+	// ordered by cost without reading the whole batch in memory.
+	pool, err := rieseltest.NewPool(workers, 4*workers, checkpoints, opts)
+	if err != nil {
+		return err
+	}
+
+	// Read the batch while the workers are running
+	errc := make(chan error, 1)
+	go func() {
+		defer pool.Close()
+		errc <- rieseltest.ScanBatch(in, pool.Submit)
+	}()
+
+	failed := false
+	for r := range pool.Results() {
+		if err := writeResult(out, r); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+
+		if r.Err != nil {
+			failed = true
+			continue
+		}
+
+		// The results of a previous run are already in the ledger
+		if r.Worker == -1 {
+			continue
+		}
+		if err := appendResult(l, r.Result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+		if err := saveCertificate(certificates, r.Result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+
+	// Once stopped, the submission fails on the closed pool
+	err = <-errc
+	select {
+	case <-opts.Stop:
+		if checkpoints != "" {
+			fmt.Fprintf(os.Stderr, "The checkpoints were saved to %v: run the same command to resume.\n",
+				checkpoints)
+		}
+		return rieseltest.ErrInterrupted
+	default:
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("%v: %v", path, err))
+	}
+	if failed {
+		return errors.New("Some numbers of the batch could not be tested")
+	}
+
+	return nil
+}
+
+// writeResult prints the result of a test of a pool, as a record of out if it
+// is not nil.
+func writeResult(out *output.Writer, r *rieseltest.PoolResult) error {
+	switch {
+	case out == nil && r.Err != nil:
+		fmt.Printf("%v: %v\n", r.R, r.Err)
+	case out == nil:
+		fmt.Printf("%v: %v\n", r.R, r.Prime)
+	case r.Err != nil:
+		return out.Write(output.FromError(r.R, r.Err))
+	default:
+		return out.Write(output.FromResult(r.Result))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/arcetri/goprime/bench"
+)
+
+// defaultBenchSizes are the n of the numbers h*2^n-1 timed by goprime bench.
+const defaultBenchSizes = "1000,10000,100000"
+
+// runBench implements the "goprime bench" command, which runs the standard
+// benchmarks of the steps of the test, and compares their report with the
+// report of a previous run.
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime bench [-h h] [-sizes n,n,...] [-benchtime d] [-run regexp] [-diff old.out]\n")
+		fmt.Print("  goprime bench -diff old.out new.out\n\n")
+		fmt.Print("Times GenV1 with each method, GenU2 with and without parallel products, and an iteration\n")
+		fmt.Print("of U(i), for the numbers h*2^n-1 of the given sizes. The report is printed in the format of\n")
+		fmt.Print("go test -bench, naming the arithmetic backend goprime was built with, so that the reports\n")
+		fmt.Print("of two machines, versions or backends can be compared. With -diff, the report is compared\n")
+		fmt.Print("with a previous one, and goprime exits with status 1 if a benchmark regressed.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	hPtr := fs.Int64("h", bench.DefaultH, "The h of the numbers h*2^n-1 timed.")
+	sizesPtr := fs.String("sizes", defaultBenchSizes, "Comma separated n of the numbers timed.")
+	benchTimePtr := fs.Duration("benchtime", bench.DefaultBenchTime, "Minimum time of the measure of every " +
+		"benchmark.")
+	runPtr := fs.String("run", "", "Only run the benchmarks whose name matches this regular expression.")
+	diffPtr := fs.String("diff", "", "Previous report to compare the results with.")
+	thresholdPtr := fs.Float64("threshold", 0.1, "Slowdown from which a benchmark is reported as a " +
+		"regression by -diff, e.g. 0.1 for 10%.")
+	configureLogger := loggerFlags(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if fs.NArg() > 1 || (fs.NArg() == 1 && *diffPtr == "") || *benchTimePtr <= 0 || *thresholdPtr < 0 {
+		return usageError(fs, "")
+	}
+
+	var old *bench.Report
+	var err error
+	if *diffPtr != "" {
+		if old, err = readReport(*diffPtr); err != nil {
+			return err
+		}
+	}
+
+	// Compare two existing reports
+	if fs.NArg() == 1 {
+		report, err := readReport(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		return diffReports(old, report, *diffPtr, *thresholdPtr, os.Stdout)
+	}
+
+	s := &bench.Suite{H: *hPtr, BenchTime: *benchTimePtr}
+	for _, arg := range strings.Split(*sizesPtr, ",") {
+		n, err := parseInt("size", strings.TrimSpace(arg))
+		if err != nil {
+			return err
+		}
+		s.Sizes = append(s.Sizes, n)
+	}
+	if *runPtr != "" {
+		if s.Filter, err = regexp.Compile(*runPtr); err != nil {
+			return usageError(fs, fmt.Sprintf("Invalid -run: %v", err))
+		}
+	}
+
+	report, err := s.Run(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	// The report is on stdout, so that it can be saved for a later comparison
+	if old != nil {
+		fmt.Fprintln(os.Stderr)
+		return diffReports(old, report, *diffPtr, *thresholdPtr, os.Stderr)
+	}
+	return nil
+}
+
+// readReport reads the benchmark report at path.
+func readReport(path string) (*bench.Report, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	report, err := bench.ParseReport(file)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%v: %v", path, err))
+	}
+	return report, nil
+}
+
+// diffReports writes the comparison of the report with the old one to w, and
+// returns an error if some benchmarks regressed by more than threshold.
+func diffReports(old, report *bench.Report, oldPath string, threshold float64, w *os.File) error {
+	regressions, err := bench.WriteDiff(w, old, report, threshold)
+	if err != nil {
+		return err
+	}
+	if regressions > 0 {
+		return errors.New(fmt.Sprintf("%v benchmarks are more than %v%% slower than in %v", regressions,
+			100 * threshold, oldPath))
+	}
+	return nil
+}
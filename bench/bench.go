@@ -0,0 +1,233 @@
+// Package bench runs the standard benchmarks of goprime, and writes their
+// results in the format of "go test -bench", as the files of
+// rieseltest/benchmarks:
+//
+//		goos: linux
+//		backend: math/big
+//		BenchmarkGenV1/Rodseth/n=10000-8    	   12204	     98302 ns/op
+//		BenchmarkGenUN/n=10000-8            	  145436	      8256 ns/op
+//
+// Since the reports of two runs name the same benchmarks, they can be compared
+// to spot regressions, either by Compare or by tools such as benchstat. The
+// arithmetic backend is selected when goprime is built, so the backends are
+// compared through the reports of goprime built with each of them.
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"time"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// Default settings of a Suite.
+const (
+	DefaultH         = 391581
+	DefaultBenchTime = time.Second
+)
+
+// DefaultSizes are the n of the numbers h*2^n-1 benchmarked by default.
+var DefaultSizes = []int64{1000, 10000, 100000}
+
+// maxCount is the largest number of operations of a benchmark run.
+const maxCount = 1000000000
+
+// A Suite is the set of the standard benchmarks, run on the numbers h*2^n-1
+// for a fixed h and the n of a size sweep (see Suite.benchmarks):
+//
+//		GenV1/Riesel/n=N, GenV1/Rodseth/n=N and GenV1/Penne/n=N time GenV1 with each method
+//		GenU2/Parallel/n=N and GenU2/Sequential/n=N time GenU2 and GenU2Sequential
+//		GenUN/n=N times one iteration of U(i+1) = U(i)^2 - 2 mod N
+type Suite struct {
+
+	// H is the h of the numbers. When 0, DefaultH is used.
+	H int64
+
+	// Sizes are the n of the numbers. When empty, DefaultSizes are used.
+	Sizes []int64
+
+	// BenchTime is the minimum duration of the measure of every benchmark.
+	// When 0, DefaultBenchTime is used.
+	BenchTime time.Duration
+
+	// Filter, when not nil, selects the benchmarks whose name it matches.
+	Filter *regexp.Regexp
+}
+
+// A benchmark is one of the benchmarks of a Suite, which runs its operation
+// count times and returns the time taken.
+type benchmark struct {
+	name string
+	run  func(count int64) (time.Duration, error)
+}
+
+// Run runs the benchmarks of the suite, writing the report to w as soon as
+// every result is measured, and returns the complete report.
+func (s *Suite) Run(w io.Writer) (*Report, error) {
+	h := s.H
+	if h == 0 {
+		h = DefaultH
+	}
+	sizes := s.Sizes
+	if len(sizes) == 0 {
+		sizes = DefaultSizes
+	}
+	benchTime := s.BenchTime
+	if benchTime <= 0 {
+		benchTime = DefaultBenchTime
+	}
+
+	report := &Report{Config: []Setting{
+		{"goos", runtime.GOOS},
+		{"goarch", runtime.GOARCH},
+		{"cpus", fmt.Sprint(runtime.NumCPU())},
+		{"backend", rieseltest.Backend()},
+		{"version", rieseltest.Version},
+		{"h", fmt.Sprint(h)},
+	}}
+	if err := report.writeConfig(w); err != nil {
+		return nil, err
+	}
+
+	for _, n := range sizes {
+		benchmarks, err := s.benchmarks(h, n)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range benchmarks {
+			count, elapsed, err := measure(benchTime, b.run)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("%v: %v", b.name, err))
+			}
+
+			r := Result{Name: b.name, Procs: runtime.GOMAXPROCS(0), Runs: count,
+				NsPerOp: float64(elapsed.Nanoseconds()) / float64(count)}
+			report.Results = append(report.Results, r)
+			if _, err = fmt.Fprintln(w, r.String()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// maxSizeShift is how far above a size of the sweep a number without small
+// factors is looked for.
+const maxSizeShift = 1000
+
+// benchmarks returns the benchmarks of the suite for the size n. Since GenV1
+// refuses the numbers with a small factor, the number benchmarked is h*2^m-1
+// for the smallest m >= n for which N has none, and the benchmarks are named
+// after m.
+func (s *Suite) benchmarks(h, n int64) ([]benchmark, error) {
+	var R *rieseltest.RieselNumber
+	var v1 int64
+	var err error
+	for m := n; m < n + maxSizeShift; m++ {
+		if R, err = rieseltest.NewRieselNumber(h, m); err != nil {
+			return nil, err
+		}
+
+		// The operations following GenV1 all start from the same V(1) and U(2)
+		if v1, err = rieseltest.GenV1(R, rieseltest.RODSETH); err == nil {
+			n = m
+			break
+		}
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("No h*2^n-1 with %v <= n < %v can be benchmarked: %v", n,
+			n + maxSizeShift, err))
+	}
+	u, err := rieseltest.GenU2(R, v1)
+	if err != nil {
+		return nil, err
+	}
+
+	var benchmarks []benchmark
+	add := func(name string, run func(count int64) (time.Duration, error)) {
+		name = fmt.Sprintf("%v/n=%v", name, n)
+		if s.Filter == nil || s.Filter.MatchString(name) {
+			benchmarks = append(benchmarks, benchmark{name, run})
+		}
+	}
+
+	for _, m := range []struct {
+		name   string
+		method uint8
+	}{
+		{"Riesel", rieseltest.RIESEL},
+		{"Rodseth", rieseltest.RODSETH},
+		{"Penne", rieseltest.PENNE},
+	} {
+		method := m.method
+		add("GenV1/" + m.name, repeat(func() error {
+			_, err := rieseltest.GenV1(R, method)
+			return err
+		}))
+	}
+
+	add("GenU2/Parallel", repeat(func() error {
+		_, err := rieseltest.GenU2(R, v1)
+		return err
+	}))
+	add("GenU2/Sequential", repeat(func() error {
+		_, err := rieseltest.GenU2Sequential(R, v1)
+		return err
+	}))
+
+	add("GenUN", func(count int64) (time.Duration, error) {
+		return rieseltest.TimeIterations(R, u, count)
+	})
+
+	return benchmarks, nil
+}
+
+// repeat returns a benchmark run calling op count times.
+func repeat(op func() error) func(count int64) (time.Duration, error) {
+	return func(count int64) (time.Duration, error) {
+		begin := time.Now()
+		for i := int64(0); i < count; i++ {
+			if err := op(); err != nil {
+				return 0, err
+			}
+		}
+		return time.Since(begin), nil
+	}
+}
+
+// measure runs the benchmark with an increasing count of operations, as the
+// testing package does, until a run takes at least d. It returns the count and
+// the duration of that run.
+func measure(d time.Duration, run func(count int64) (time.Duration, error)) (int64, time.Duration, error) {
+	count := int64(1)
+	for {
+		elapsed, err := run(count)
+		if err != nil {
+			return 0, 0, err
+		}
+		if elapsed >= d || count >= maxCount {
+			return count, elapsed, nil
+		}
+
+		// Aim 20% above d, growing by at most 100 times
+		next := 100 * count
+		if elapsed > 0 {
+			if predicted := int64(1.2 * float64(count) * float64(d) / float64(elapsed)); predicted < next {
+				next = predicted
+			}
+		}
+		if next <= count {
+			next = count + 1
+		}
+		if next > maxCount {
+			next = maxCount
+		}
+		count = next
+	}
+}
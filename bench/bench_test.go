@@ -0,0 +1,74 @@
+package bench
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSuiteRun(t *testing.T) {
+
+	// 391581*2^100-1 and 391581*2^300-1 have small factors, so the next n are benchmarked
+	var testCases = []struct {
+		filter         string
+		expected_names []string
+	}{
+		{"", []string{"GenV1/Riesel/n=101", "GenV1/Rodseth/n=101", "GenV1/Penne/n=101", "GenU2/Parallel/n=101",
+			"GenU2/Sequential/n=101", "GenUN/n=101", "GenV1/Riesel/n=301", "GenV1/Rodseth/n=301",
+			"GenV1/Penne/n=301", "GenU2/Parallel/n=301", "GenU2/Sequential/n=301", "GenUN/n=301"}},
+		{"GenU2", []string{"GenU2/Parallel/n=101", "GenU2/Sequential/n=101", "GenU2/Parallel/n=301",
+			"GenU2/Sequential/n=301"}},
+		{"UN/n=301", []string{"GenUN/n=301"}},
+	}
+
+	for _, c := range testCases {
+		s := &Suite{Sizes: []int64{100, 300}, BenchTime: time.Millisecond}
+		if c.filter != "" {
+			s.Filter = regexp.MustCompile(c.filter)
+		}
+
+		var b bytes.Buffer
+		report, err := s.Run(&b)
+		if err != nil {
+			t.Fatalf("Run(%q) returned an error: %v", c.filter, err)
+		}
+
+		if len(report.Results) != len(c.expected_names) {
+			t.Fatalf("Run(%q) returned %v results, but we expected %v", c.filter, len(report.Results),
+				len(c.expected_names))
+		}
+		for i, r := range report.Results {
+			if r.Name != c.expected_names[i] || r.Runs < 1 || r.NsPerOp <= 0 {
+				t.Errorf("Run(%q) returned the result %+v, but we expected the benchmark %v", c.filter, r,
+					c.expected_names[i])
+			}
+		}
+
+		// The report written as the benchmarks run reads back as the returned one
+		parsed, err := ParseReport(&b)
+		if err != nil {
+			t.Fatalf("ParseReport returned an error: %v", err)
+		}
+		if len(parsed.Results) != len(report.Results) || parsed.Value("h") != "391581" ||
+			parsed.Value("backend") == "" {
+			t.Errorf("ParseReport read %+v from the report %+v", parsed, report)
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	var counts []int64
+	count, elapsed, err := measure(10 * time.Millisecond, func(count int64) (time.Duration, error) {
+		counts = append(counts, count)
+		return time.Duration(count) * time.Microsecond, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 run takes 1µs, 100 take 100µs, 10000 take 10ms
+	if count != 10000 || elapsed != 10 * time.Millisecond || len(counts) != 3 {
+		t.Errorf("measure ran %v, and returned %v in %v", counts, count, elapsed)
+	}
+}
@@ -0,0 +1,219 @@
+package bench
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// A Report holds the results of a run of the benchmarks.
+type Report struct {
+
+	// Config describes the machine and the build of goprime which ran the
+	// benchmarks, as the "key: value" lines at the top of the report.
+	Config []Setting
+
+	Results []Result
+}
+
+// A Setting is one line of the configuration of a Report.
+type Setting struct {
+	Key, Value string
+}
+
+// A Result is the measure of one benchmark.
+type Result struct {
+	Name    string  // name of the benchmark, e.g. "GenUN/n=10000"
+	Procs   int     // GOMAXPROCS of the run, 0 if unknown
+	Runs    int64   // number of operations timed
+	NsPerOp float64 // average time of one operation, in nanoseconds
+}
+
+// String returns the line of the result in a report, as printed by "go test -bench".
+func (r *Result) String() string {
+	name := "Benchmark" + r.Name
+	if r.Procs > 0 {
+		name = fmt.Sprintf("%v-%d", name, r.Procs)
+	}
+	return fmt.Sprintf("%-36v\t%8d\t%v ns/op", name, r.Runs, formatNs(r.NsPerOp))
+}
+
+// formatNs formats a time in nanoseconds, with decimals only for the shortest times.
+func formatNs(ns float64) string {
+	if ns < 100 {
+		return fmt.Sprintf("%10.2f", ns)
+	}
+	return fmt.Sprintf("%10.0f", ns)
+}
+
+// Value returns the value of the given key of the configuration, or "" if the
+// report does not have it.
+func (r *Report) Value(key string) string {
+	for _, s := range r.Config {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// Write writes the report to w, in the format read by ParseReport.
+func (r *Report) Write(w io.Writer) error {
+	if err := r.writeConfig(w); err != nil {
+		return err
+	}
+	for i := range r.Results {
+		if _, err := fmt.Fprintln(w, r.Results[i].String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeConfig writes the configuration lines of the report to w.
+func (r *Report) writeConfig(w io.Writer) error {
+	for _, s := range r.Config {
+		if _, err := fmt.Fprintf(w, "%v: %v\n", s.Key, s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseReport reads a report written by Run or Write, or by "go test -bench"
+// as the files of rieseltest/benchmarks. The lines which are neither a
+// "key: value" setting nor the result of a benchmark, such as PASS, are ignored.
+func ParseReport(rd io.Reader) (*Report, error) {
+	r := new(Report)
+
+	s := bufio.NewScanner(rd)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		fields := strings.Fields(text)
+
+		switch {
+		case len(fields) > 0 && strings.HasPrefix(fields[0], "Benchmark"):
+			result, err := parseResult(fields)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("line %v: %v", line, err))
+			}
+			r.Results = append(r.Results, *result)
+
+		case len(fields) > 0 && strings.HasSuffix(fields[0], ":") && len(r.Results) == 0:
+			r.Config = append(r.Config, Setting{strings.TrimSuffix(fields[0], ":"),
+				strings.TrimSpace(strings.TrimPrefix(text, fields[0]))})
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// parseResult parses the fields of the line of a benchmark:
+//
+//		BenchmarkGenV1Riesel-24    20000    139868831 ns/op
+func parseResult(fields []string) (*Result, error) {
+	if len(fields) < 4 || fields[3] != "ns/op" {
+		return nil, errors.New(fmt.Sprintf("Expected name, runs and ns/op, but received %q",
+			strings.Join(fields, " ")))
+	}
+
+	r := &Result{Name: strings.TrimPrefix(fields[0], "Benchmark")}
+	if i := strings.LastIndex(r.Name, "-"); i >= 0 {
+		if procs, err := strconv.Atoi(r.Name[i + 1:]); err == nil {
+			r.Name, r.Procs = r.Name[:i], procs
+		}
+	}
+
+	var err error
+	if r.Runs, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid number of runs %q", fields[1]))
+	}
+	if r.NsPerOp, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid ns/op %q", fields[2]))
+	}
+	return r, nil
+}
+
+// A Delta compares the results of a benchmark in two reports. Old or New is 0
+// when the benchmark is missing from the report.
+type Delta struct {
+	Name     string
+	Old, New float64 // ns/op
+}
+
+// Change returns the relative change of the time of the benchmark, e.g. 0.1
+// when it takes 10% longer, or 0 when it is missing from one of the reports.
+func (d *Delta) Change() float64 {
+	if d.Old == 0 || d.New == 0 {
+		return 0
+	}
+	return d.New / d.Old - 1
+}
+
+// Compare returns the deltas of the benchmarks of the two reports, in the
+// order of the new report, followed by the benchmarks only in the old one.
+// The benchmarks are matched by name, regardless of their GOMAXPROCS.
+func Compare(old, new *Report) []Delta {
+	var deltas []Delta
+	index := make(map[string]int)
+	for _, r := range new.Results {
+		if _, ok := index[r.Name]; !ok {
+			index[r.Name] = len(deltas)
+			deltas = append(deltas, Delta{Name: r.Name, New: r.NsPerOp})
+		}
+	}
+	for _, r := range old.Results {
+		if i, ok := index[r.Name]; ok {
+			deltas[i].Old = r.NsPerOp
+		} else {
+			index[r.Name] = len(deltas)
+			deltas = append(deltas, Delta{Name: r.Name, Old: r.NsPerOp})
+		}
+	}
+	return deltas
+}
+
+// WriteDiff writes the comparison of the two reports to w: the settings which
+// differ, such as the backend, followed by a table of the deltas, where the
+// benchmarks slower by more than threshold (e.g. 0.1 for 10%) are marked as
+// regressions. It returns the number of regressions.
+func WriteDiff(w io.Writer, old, new *Report, threshold float64) (int, error) {
+	for _, s := range new.Config {
+		if v := old.Value(s.Key); v != "" && v != s.Value {
+			fmt.Fprintf(w, "%v: %v -> %v\n", s.Key, v, s.Value)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprint(tw, "benchmark\told ns/op\tnew ns/op\tdelta\t\n")
+
+	regressions := 0
+	for _, d := range Compare(old, new) {
+		delta, mark := "", ""
+		if d.Old != 0 && d.New != 0 {
+			delta = fmt.Sprintf("%+.2f%%", 100 * d.Change())
+			if d.Change() > threshold {
+				mark = "regression"
+				regressions++
+			}
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", d.Name, nsOrDash(d.Old), nsOrDash(d.New), delta, mark)
+	}
+
+	return regressions, tw.Flush()
+}
+
+// nsOrDash formats a time of a Delta, or a dash for a missing benchmark.
+func nsOrDash(ns float64) string {
+	if ns == 0 {
+		return "-"
+	}
+	return strings.TrimSpace(formatNs(ns))
+}
@@ -0,0 +1,109 @@
+package bench
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseReport(t *testing.T) {
+	file, err := os.Open("../rieseltest/benchmarks/benchmark_genV1.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	report, err := ParseReport(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []Result{
+		{"GenV1Riesel", 24, 20000, 139868831},
+		{"GenV1Rodseth", 24, 20000, 158104522},
+		{"GenV1Penne", 24, 30000, 87032453},
+	}
+	if len(report.Results) != len(expected) {
+		t.Fatalf("ParseReport read %+v, but we expected %+v", report.Results, expected)
+	}
+	for i, r := range report.Results {
+		if r != expected[i] {
+			t.Errorf("ParseReport read %+v, but we expected %+v", r, expected[i])
+		}
+	}
+
+	if _, err = ParseReport(strings.NewReader("BenchmarkGenUN-8 many 12 ns/op\n")); err == nil {
+		t.Errorf("ParseReport did not return an error for an invalid number of runs")
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	report := &Report{
+		Config: []Setting{{"goos", "linux"}, {"backend", "math/big"}},
+		Results: []Result{
+			{"GenV1/Rodseth/n=1000", 8, 12204, 98302},
+			{"GenUN/n=1000", 8, 1000000, 12.5},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := report.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseReport(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parsed.Config) != 2 || parsed.Value("backend") != "math/big" || len(parsed.Results) != 2 ||
+		parsed.Results[0] != report.Results[0] || parsed.Results[1] != report.Results[1] {
+		t.Errorf("Write and ParseReport returned %+v, but we expected %+v", parsed, report)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	old := &Report{
+		Config: []Setting{{"backend", "math/big"}, {"cpus", "8"}},
+		Results: []Result{
+			{"GenUN/n=1000", 8, 100, 1000},
+			{"GenU2/Parallel/n=1000", 8, 100, 5000},
+			{"GenV1/Penne/n=1000", 8, 100, 300},
+		},
+	}
+	new := &Report{
+		Config: []Setting{{"backend", "github.com/arcetri/gmp"}, {"cpus", "8"}},
+		Results: []Result{
+			{"GenUN/n=1000", 4, 100, 1500},
+			{"GenU2/Parallel/n=1000", 4, 100, 4000},
+			{"GenU2/Sequential/n=1000", 4, 100, 7000},
+		},
+	}
+
+	var testCases = []struct {
+		threshold            float64
+		expected_regressions int
+	}{
+		{0.1, 1},
+		{0.5, 0},
+	}
+
+	for _, c := range testCases {
+		var b bytes.Buffer
+		regressions, err := WriteDiff(&b, old, new, c.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if regressions != c.expected_regressions {
+			t.Errorf("WriteDiff(%v) found %v regressions, but we expected %v:\n%v", c.threshold, regressions,
+				c.expected_regressions, b.String())
+		}
+
+		lines := strings.Split(b.String(), "\n")
+		if lines[0] != "backend: math/big -> github.com/arcetri/gmp" || len(lines) != 7 ||
+			!strings.Contains(lines[2], "+50.00%") || !strings.Contains(lines[3], "-20.00%") ||
+			!strings.Contains(lines[4], "-") || !strings.HasPrefix(lines[5], "GenV1/Penne/n=1000") {
+			t.Errorf("WriteDiff(%v) wrote:\n%v", c.threshold, b.String())
+		}
+	}
+}
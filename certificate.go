@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// saveCertificate writes the certificate of the given result to the directory,
+// if the result has one and the directory is not empty.
+func saveCertificate(dir string, result *rieseltest.Result) error {
+	if dir == "" || result.Certificate == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return rieseltest.WriteCertificate(filepath.Join(dir, result.Certificate.FileName()), result.Certificate)
+}
+
+// runVerifyCert implements the "goprime verify-cert" command, which checks
+// the given certificates.
+func runVerifyCert(args []string) error {
+	fs := flag.NewFlagSet("verify-cert", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime verify-cert [-replay=false] file...\n\n")
+		fmt.Print("Checks the V(1) of the given certificates, and replays their tests to prove that the numbers\n")
+		fmt.Print("are prime. With -replay=false, only V(1) is checked, which does not prove anything about N.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	replayPtr := fs.Bool("replay", true, "Replay the test and compare its interim residues with " +
+		"the certificate. This takes as long as the original test, and is required to prove that N is prime.")
+	configureLogger := loggerFlags(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if fs.NArg() == 0 {
+		return usageError(fs, "")
+	}
+
+	failed := 0
+	for _, path := range fs.Args() {
+		c, err := rieseltest.LoadCertificate(path)
+		if err == nil {
+			err = c.Verify(*replayPtr)
+		}
+
+		if err != nil {
+			fmt.Printf("%v: INVALID: %v\n", path, err)
+			failed++
+		} else if *replayPtr {
+			fmt.Printf("%v: %v * 2^%v - 1 is prime\n", path, c.H, c.N)
+		} else {
+			fmt.Printf("%v: V(1) = %v is valid, but the test was not replayed\n", path, c.V1)
+		}
+	}
+
+	if failed > 0 {
+		return errors.New(fmt.Sprintf("%v of %v certificates are invalid", failed, fs.NArg()))
+	}
+	return nil
+}
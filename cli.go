@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/arcetri/goprime/metrics"
+	"github.com/arcetri/goprime/output"
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// Exit statuses of goprime.
+const (
+	exitOK          = 0 // the command completed
+	exitError       = 1 // the command failed, see the error message
+	exitUsage       = 2 // the command was invoked with wrong arguments or flags
+	exitInterrupted = 3 // stopped by SIGINT or SIGTERM, after saving the checkpoints
+	exitForced      = 4 // stopped by a second SIGINT or SIGTERM, without saving anything
+)
+
+// errUsage is returned by the commands invoked with wrong arguments, once
+// their usage message has been printed.
+var errUsage = errors.New("invalid usage")
+
+// usageError prints the message, if not empty, followed by the usage of the
+// command, and returns errUsage.
+func usageError(fs *flag.FlagSet, message string) error {
+	if message != "" {
+		fmt.Fprintf(os.Stderr, "%v\n\n", message)
+	}
+	fs.Usage()
+	return errUsage
+}
+
+// exitStatus reports the error returned by a command on stderr, and returns
+// the exit status of goprime for it.
+func exitStatus(err error) int {
+	switch err {
+	case nil:
+		return exitOK
+	case errUsage:
+		return exitUsage
+	}
+
+	fmt.Fprintf(os.Stderr, "goprime: %v\n", err)
+	if err == rieseltest.ErrInterrupted {
+		return exitInterrupted
+	}
+	return exitError
+}
+
+// parseInt parses the argument named name as an int64.
+func parseInt(name, arg string) (int64, error) {
+	v, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid %v %q: expected an integer", name, arg))
+	}
+	return v, nil
+}
+
+// parseCandidate parses the number to test given on the command line, either
+// as the two arguments "h n", or as a single argument accepted by
+// rieseltest.ParseRieselNumber: "3*2^1000-1", "2^127-1" or the decimal N.
+func parseCandidate(args []string) (*rieseltest.RieselNumber, error) {
+	h, n, err := parseParams(args)
+	if err != nil {
+		return nil, err
+	}
+	return rieseltest.NewRieselNumber(h, n)
+}
+
+// parseParams parses the number given on the command line like
+// parseCandidate, and returns its h and n without computing N.
+func parseParams(args []string) (int64, int64, error) {
+	switch len(args) {
+	case 1:
+		return rieseltest.ParseRieselParams(args[0])
+
+	case 2:
+		h, err := parseInt("h", args[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		n, err := parseInt("n", args[1])
+		if err != nil {
+			return 0, 0, err
+		}
+		return rieseltest.RieselParams(h, n)
+	}
+
+	return 0, 0, errors.New(fmt.Sprintf("Expected h and n, or a number such as 3*2^1000-1, but received %v " +
+		"arguments", len(args)))
+}
+
+// outputFlag defines the '-output' flag in the given flag set. The returned
+// function must be called once the flag set is parsed: it returns the writer
+// of the records on stdout, or nil for the human-readable text output.
+func outputFlag(fs *flag.FlagSet) func() (*output.Writer, error) {
+	formatPtr := fs.String("output", output.Text, "Format of the results: text, or one record per number " +
+		"in json or tsv, written as soon as its test completes.")
+
+	return func() (*output.Writer, error) {
+		if *formatPtr == output.Text {
+			return nil, nil
+		}
+		if *formatPtr != output.JSON && *formatPtr != output.TSV {
+			return nil, errors.New(fmt.Sprintf("Unknown output format %v, expected %v, %v or %v", *formatPtr,
+				output.Text, output.JSON, output.TSV))
+		}
+		return output.NewWriter(os.Stdout, *formatPtr)
+	}
+}
+
+// infoWriter returns where the messages meant for humans are written: stdout,
+// or stderr when stdout holds the records of out.
+func infoWriter(out *output.Writer) *os.File {
+	if out != nil {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// metricsFlag defines the '-metrics' flag in the given flag set. The returned
+// function must be called once the flag set is parsed: it starts serving the
+// metrics of the tests on the given address, and returns the monitor to set in
+// their options, or nil when no address was given.
+func metricsFlag(fs *flag.FlagSet) func() (rieseltest.Monitor, error) {
+	addrPtr := fs.String("metrics", "", "Address, such as localhost:9100, on which the progress of the tests " +
+		"is served in the Prometheus text format at " + metrics.Path + " ('' to disable).")
+
+	return func() (rieseltest.Monitor, error) {
+		if *addrPtr == "" {
+			return nil, nil
+		}
+		c := metrics.NewCollector()
+		if err := c.Serve(*addrPtr); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+}
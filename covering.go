@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/arcetri/goprime/rieseltest/covering"
+)
+
+// runCovering implements the "goprime covering" command, which looks for a
+// covering set proving that h is a Riesel number.
+func runCovering(args []string) error {
+	fs := flag.NewFlagSet("covering", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime covering -h h [-modulus M] [-primes limit] [-classes]\n\n")
+		fmt.Print("Looks for a covering set of primes proving that h*2^n-1 is composite for every n.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	hPtr := fs.Int64("h", 0, "The h to analyze.")
+	modulusPtr := fs.Int64("modulus", covering.DefaultMaxModulus, "Largest ord_p(2) of the primes considered.")
+	primesPtr := fs.Uint64("primes", covering.DefaultPrimeLimit, "Largest prime considered.")
+	classesPtr := fs.Bool("classes", false, "List the classes of n for which h*2^n-1 is always composite.")
+	configureLogger := loggerFlags(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if *hPtr < 1 {
+		return usageError(fs, "")
+	}
+
+	a, err := covering.Analyze(*hPtr, *modulusPtr, *primesPtr)
+	if err != nil {
+		return err
+	}
+
+	if *classesPtr {
+		for _, c := range a.Classes {
+			fmt.Println(c)
+		}
+	}
+
+	if !a.Riesel {
+		fmt.Printf("No covering set found for h = %v with ord_p(2) <= %v and p <= %v\n", a.H, *modulusPtr, *primesPtr)
+		return nil
+	}
+
+	fmt.Printf("%v is a Riesel number, with the covering set mod %v:\n", a.H, a.Modulus)
+	for _, c := range a.Cover {
+		fmt.Println(c)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/arcetri/goprime/distrib"
+	"github.com/arcetri/goprime/ledger"
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// runServer implements the "goprime server" command, which distributes the
+// numbers of a batch to the workers connecting to it.
+func runServer(args []string) error {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime server -batch file -ledger file [-addr address] [-lease duration]\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	batchPtr := fs.String("batch", "", "File with the h and n of the numbers to test, one \"h n\" pair per line.")
+	addrPtr := fs.String("addr", "localhost:8080", "Address on which the server listens.")
+	leasePtr := fs.Duration("lease", distrib.DefaultLeaseDuration, "Time after which a lease without " +
+		"heartbeats expires and its number is handed out again.")
+	ledgerPtr := fs.String("ledger", "", "Ledger to which the results are appended, and from which a " +
+		"restarted server restores them.")
+	configureLogger := loggerFlags(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if *batchPtr == "" || *ledgerPtr == "" {
+		return usageError(fs, "Expected the batch and the ledger of the results.")
+	}
+
+	file, err := os.Open(*batchPtr)
+	if err != nil {
+		return err
+	}
+
+	var batch []*rieseltest.RieselNumber
+	err = rieseltest.ScanBatch(file, func(R *rieseltest.RieselNumber) error {
+		batch = append(batch, R)
+		return nil
+	})
+	file.Close()
+	if err != nil {
+		return errors.New(fmt.Sprintf("%v: %v", *batchPtr, err))
+	}
+
+	l, err := ledger.Open(*ledgerPtr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	// Restore the results of a previous run of the server
+	server := distrib.NewServer(batch, *leasePtr, l)
+	err = ledger.Read(*ledgerPtr, func(rec *ledger.Record) error {
+		server.Restore(rec)
+		return nil
+	})
+	if err != nil {
+		return errors.New(fmt.Sprintf("%v: %v", *ledgerPtr, err))
+	}
+
+	// The first SIGINT or SIGTERM stops the server once the requests in
+	// progress are answered, so that their results are in the ledger
+	srv := &http.Server{Addr: *addrPtr, Handler: server}
+	stop := handleSignals()
+	go func() {
+		<-stop
+		srv.Shutdown(context.Background())
+	}()
+
+	fmt.Printf("Serving %v numbers on %v\n", len(batch), *addrPtr)
+	if err = srv.ListenAndServe(); err == http.ErrServerClosed {
+		return rieseltest.ErrInterrupted
+	}
+	return err
+}
+
+// runWorker implements the "goprime worker" command, which tests the numbers
+// leased by a server until its batch is complete.
+func runWorker(args []string) error {
+	hostname, _ := os.Hostname()
+
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime worker -server URL [-id name] [-checkpoints dir] [-certificates dir] [-metrics addr]\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	serverPtr := fs.String("server", "", "URL of the server, e.g. http://localhost:8080.")
+	idPtr := fs.String("id", fmt.Sprintf("%v-%v", hostname, os.Getpid()), "Name of the worker.")
+	checkpointsPtr := fs.String("checkpoints", "", "Directory where the checkpoints are saved.")
+	certificatesPtr := fs.String("certificates", "", "Directory where the certificates of the primes " +
+		"found are written.")
+	interimPtr := fs.Int64("interim", 0, "Record the RES64 of U(i) every this many iterations " +
+		"(0 to disable), so that mismatching results can be compared.")
+	heartbeatPtr := fs.Duration("heartbeat", distrib.DefaultHeartbeatInterval, "Time between two heartbeats.")
+	configureLogger := loggerFlags(fs)
+	serveMetrics := metricsFlag(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if *serverPtr == "" {
+		return usageError(fs, "")
+	}
+	monitor, err := serveMetrics()
+	if err != nil {
+		return err
+	}
+
+	w := &distrib.Worker{
+		URL:               *serverPtr,
+		ID:                *idPtr,
+		CheckpointDir:     *checkpointsPtr,
+		CertificateDir:    *certificatesPtr,
+		InterimInterval:   *interimPtr,
+		HeartbeatInterval: *heartbeatPtr,
+		Client:            &http.Client{Timeout: time.Minute},
+		Monitor:           monitor,
+		Stop:              handleSignals(),
+	}
+
+	return w.Run()
+}
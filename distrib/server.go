@@ -0,0 +1,510 @@
+// Package distrib implements a simple HTTP/JSON protocol to distribute the
+// testing of a batch of Riesel numbers among many workers.
+//
+// A Server hands out the numbers of a batch as leases. A Worker first
+// registers (POST /register), receiving an identity issued by the server, and
+// then repeatedly:
+//
+//		1) asks for a lease under that identity (POST /lease)
+//		2) tests the leased number with rieseltest.Test, sending a heartbeat
+//		   (POST /heartbeat) from time to time to keep the lease alive
+//		3) submits the result, together with its RES64 residue (POST /submit)
+//
+// A lease expires if no heartbeat is received for the lease duration, and the
+// number is then handed out again to the next worker asking for work.
+//
+// A single result is not trusted: every number is tested by two distinct
+// workers, and it is verified only when their residues match. Since the
+// identities of the workers are issued by the server, a worker cannot pass
+// for another one by choosing its name. On a mismatch,
+// the number is tested again by another worker, until two results agree.
+// Thus, the server needs at least two workers to complete a batch.
+package distrib
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arcetri/goprime/ledger"
+	"github.com/arcetri/goprime/rieseltest"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("distrib")
+
+// Registration is the body of a POST /register request, and of its response.
+// The worker sends its Name, which only appears in the logs and in the
+// identity of the worker, and the server responds with the Worker identity
+// it issued.
+type Registration struct {
+	Name   string `json:"name"`
+	Worker string `json:"worker,omitempty"`
+}
+
+// LeaseRequest is the body of a POST /lease request. Worker is the identity
+// issued to the worker by POST /register.
+type LeaseRequest struct {
+	Worker string `json:"worker"`
+}
+
+// Lease is the body of the response to a successful POST /lease request.
+// It assigns the number H*2^N-1 to the worker until Expires.
+type Lease struct {
+	ID      string    `json:"lease"`
+	H       int64     `json:"h"`
+	N       int64     `json:"n"`
+	Expires time.Time `json:"expires"`
+}
+
+// Heartbeat is the body of a POST /heartbeat request.
+type Heartbeat struct {
+	Lease string `json:"lease"`
+}
+
+// Submission is the body of a POST /submit request. The Worker and Verified
+// fields of the record are set by the server.
+type Submission struct {
+	Lease string `json:"lease"`
+	ledger.Record
+}
+
+// Status is the body of the response to a GET /status request.
+type Status struct {
+	Total      int `json:"total"`
+	Leased     int `json:"leased"`
+	Verified   int `json:"verified"`
+	Mismatches int `json:"mismatches"`
+}
+
+// DefaultLeaseDuration is the lease duration used when none is given to NewServer.
+const DefaultLeaseDuration = 10 * time.Minute
+
+// candidate is a number of the batch served by a Server.
+type candidate struct {
+	h, n     int64
+	screened bool // decided by rieseltest.Test without a residue
+	leases   map[string]*lease // by worker
+	results  []*ledger.Record
+	verified bool
+}
+
+// matches returns true if the two records have the same verdict and residue.
+func matches(a, b *ledger.Record) bool {
+	return a.Verdict == b.Verdict && a.Residue == b.Residue
+}
+
+// mismatch returns true if the results of c contain different residues.
+func (c *candidate) mismatch() bool {
+	for _, r := range c.results {
+		if !matches(r, c.results[0]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runs returns the number of results that c needs to be verified, assuming
+// that the next result will match one of the previous ones.
+func (c *candidate) runs() int {
+	if len(c.results) < 2 {
+		return 2
+	}
+
+	return len(c.results) + 1
+}
+
+// leasable returns true if c can be leased to the given worker at the given time.
+// The expired leases of c are removed.
+func (c *candidate) leasable(worker string, now time.Time, s *Server) bool {
+	if c.verified {
+		return false
+	}
+
+	for w, l := range c.leases {
+		if !now.Before(l.expires) {
+			log.Infof("The lease of %v * 2^%v - 1 to %v expired", c.h, c.n, w)
+			delete(s.leases, l.id)
+			delete(c.leases, w)
+		}
+	}
+
+	// The same worker must not test the same number twice
+	if _, ok := c.leases[worker]; ok {
+		return false
+	}
+	for _, r := range c.results {
+		if r.Worker == worker {
+			return false
+		}
+	}
+
+	return len(c.results)+len(c.leases) < c.runs()
+}
+
+// lease is a candidate assigned to a worker.
+type lease struct {
+	id      string
+	worker  string
+	c       *candidate
+	expires time.Time
+}
+
+// Server serves the numbers of a batch to the workers, and collects their results.
+//
+// It implements http.Handler, so it can be used with any http.Server, or with
+// an httptest.Server for testing.
+type Server struct {
+	mu         sync.Mutex
+	candidates []*candidate
+	leases     map[string]*lease
+	workers    map[string]bool
+	duration   time.Duration
+	ledger     *ledger.Ledger
+	mux        *http.ServeMux
+
+	// now returns the current time. It can be replaced for testing.
+	now func() time.Time
+}
+
+// NewServer creates a Server for the given batch of numbers.
+//
+// Leases expire after the given duration without heartbeats (DefaultLeaseDuration
+// if duration is 0). Every accepted result is appended to the given ledger,
+// if it is not nil.
+func NewServer(batch []*rieseltest.RieselNumber, duration time.Duration, l *ledger.Ledger) *Server {
+	if duration <= 0 {
+		duration = DefaultLeaseDuration
+	}
+
+	s := &Server{
+		leases:   make(map[string]*lease),
+		workers:  make(map[string]bool),
+		duration: duration,
+		ledger:   l,
+		mux:      http.NewServeMux(),
+		now:      time.Now,
+	}
+
+	for _, R := range batch {
+		h, n := R.Params()
+		s.candidates = append(s.candidates, &candidate{h: h, n: n, screened: rieseltest.Screened(R),
+			leases: make(map[string]*lease)})
+	}
+
+	s.mux.HandleFunc("/register", s.handleRegister)
+	s.mux.HandleFunc("/lease", s.handleLease)
+	s.mux.HandleFunc("/heartbeat", s.handleHeartbeat)
+	s.mux.HandleFunc("/submit", s.handleSubmit)
+	s.mux.HandleFunc("/status", s.handleStatus)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Done returns true when all the numbers of the batch are verified.
+func (s *Server) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.candidates {
+		if !c.verified {
+			return false
+		}
+	}
+
+	return true
+}
+
+// handleRegister issues a new identity to the requesting worker, made of its
+// name and of a random suffix. It responds with 400 if the name is empty.
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var reg Registration
+	if !decode(w, r, &reg) {
+		return
+	}
+	if reg.Name == "" {
+		http.Error(w, "Expected the name of the worker", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reg.Worker = fmt.Sprintf("%v-%v", reg.Name, newID(4))
+	s.workers[reg.Worker] = true
+
+	log.Infof("Registered the worker %v", reg.Worker)
+	respond(w, http.StatusOK, &reg)
+}
+
+// handleLease assigns the first number which still needs to be tested to
+// the requesting worker. A number is never assigned twice to the same worker.
+//
+// It responds with:
+//		200 and a Lease, if a number was assigned
+//		204 if no number can be assigned to the worker at the moment
+//		403 if the worker was not registered, e.g. before a restart of the server
+//		410 if all the numbers of the batch are verified
+func (s *Server) handleLease(w http.ResponseWriter, r *http.Request) {
+	var req LeaseRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.workers[req.Worker] {
+		http.Error(w, "Unknown worker", http.StatusForbidden)
+		return
+	}
+
+	now := s.now()
+	done := true
+
+	for _, c := range s.candidates {
+		if c.verified {
+			continue
+		}
+		done = false
+
+		if !c.leasable(req.Worker, now, s) {
+			continue
+		}
+
+		l := &lease{id: newID(16), worker: req.Worker, c: c, expires: now.Add(s.duration)}
+		c.leases[req.Worker] = l
+		s.leases[l.id] = l
+
+		log.Infof("Leased %v * 2^%v - 1 to %v", c.h, c.n, req.Worker)
+		respond(w, http.StatusOK, &Lease{ID: l.id, H: c.h, N: c.n, Expires: l.expires})
+		return
+	}
+
+	if done {
+		w.WriteHeader(http.StatusGone)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleHeartbeat extends a valid lease. It responds with 404 if the lease
+// is unknown or expired, in which case the worker should abandon the test.
+func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	var hb Heartbeat
+	if !decode(w, r, &hb) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.validLease(hb.Lease)
+	if !ok {
+		http.Error(w, "Unknown or expired lease", http.StatusNotFound)
+		return
+	}
+
+	l.expires = s.now().Add(s.duration)
+	log.Debugf("Heartbeat from %v for %v * 2^%v - 1", l.worker, l.c.h, l.c.n)
+	respond(w, http.StatusOK, &Lease{ID: l.id, H: l.c.h, N: l.c.n, Expires: l.expires})
+}
+
+// handleSubmit records the result of a valid lease, and verifies the number if
+// the result matches one submitted by another worker. It responds with 404 if
+// the lease is unknown or expired, and with 400 if the result does not match it
+// or has no valid RES64 residue. Only the numbers with a factor < 257, which
+// the server screens itself, have no residue.
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var sub Submission
+	if !decode(w, r, &sub) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.validLease(sub.Lease)
+	if !ok {
+		http.Error(w, "Unknown or expired lease", http.StatusNotFound)
+		return
+	}
+	if sub.H != l.c.h || sub.N != l.c.n {
+		http.Error(w, "The result does not match the lease", http.StatusBadRequest)
+		return
+	}
+	if sub.Verdict != ledger.Prime && sub.Verdict != ledger.Composite {
+		http.Error(w, "Invalid verdict", http.StatusBadRequest)
+		return
+	}
+	if sub.Residue == "" && !l.c.screened {
+		http.Error(w, "Expected the residue of the test", http.StatusBadRequest)
+		return
+	}
+	if _, err := strconv.ParseUint(sub.Residue, 16, 64); sub.Residue != "" && (err != nil || len(sub.Residue) != 16) {
+		http.Error(w, "Invalid residue", http.StatusBadRequest)
+		return
+	}
+
+	c := l.c
+	delete(s.leases, l.id)
+	delete(c.leases, l.worker)
+	rec := &sub.Record
+	rec.Worker = l.worker
+	rec.Verified = false
+
+	// The number is verified if another worker submitted the same result
+	var matched []*ledger.Record
+	if !c.verified {
+		for _, r := range c.results {
+			if matches(r, rec) {
+				matched = append(matched, r)
+			}
+		}
+	}
+	rec.Verified = len(matched) > 0
+
+	c.results = append(c.results, rec)
+	c.verified = c.verified || rec.Verified
+
+	if err := s.record(rec); err != nil {
+		log.Errorf("Could not record the result of %v * 2^%v - 1: %v", sub.H, sub.N, err)
+	}
+
+	// The results matched are verified too. Since the ledger is append-only,
+	// they are recorded again, marked as verified.
+	for _, r := range matched {
+		r.Verified = true
+		if err := s.record(r); err != nil {
+			log.Errorf("Could not record the result of %v * 2^%v - 1: %v", sub.H, sub.N, err)
+		}
+	}
+
+	switch {
+	case rec.Verified:
+		log.Infof("%v * 2^%v - 1 is %v (RES64: %v, verified by %v)", sub.H, sub.N, sub.Verdict,
+			sub.Residue, rec.Worker)
+	case c.mismatch():
+		log.Warningf("Mismatching results for %v * 2^%v - 1: scheduling another test", sub.H, sub.N)
+		for _, r := range c.results[:len(c.results)-1] {
+			agree, differ := rieseltest.LocateDivergence(r.InterimResidues(), rec.InterimResidues())
+			if differ != 0 {
+				log.Warningf("The results of %v and %v diverged between U(%v) and U(%v)", r.Worker, rec.Worker,
+					agree, differ)
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleStatus responds with the Status of the batch.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &Status{Total: len(s.candidates)}
+	now := s.now()
+	for _, c := range s.candidates {
+		if c.verified {
+			status.Verified++
+		} else if c.mismatch() {
+			status.Mismatches++
+		}
+
+		for _, l := range c.leases {
+			if now.Before(l.expires) {
+				status.Leased++
+			}
+		}
+	}
+
+	respond(w, http.StatusOK, status)
+}
+
+// validLease returns the lease with the given id, if it exists and has not expired.
+func (s *Server) validLease(id string) (*lease, bool) {
+	l, ok := s.leases[id]
+	if !ok || !s.now().Before(l.expires) {
+		return nil, false
+	}
+
+	return l, true
+}
+
+// record appends an accepted result to the ledger.
+func (s *Server) record(rec *ledger.Record) error {
+	if s.ledger == nil {
+		return nil
+	}
+
+	return s.ledger.Append(rec)
+}
+
+// Restore adds a result recorded in the ledger by a previous run of the server,
+// so that a restarted server does not hand out the numbers already verified.
+// Records of numbers which are not in the batch, or which were not tested by
+// a worker of a server, are ignored.
+func (s *Server) Restore(rec *ledger.Record) {
+	if rec.Worker == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.candidates {
+		if c.h == rec.H && c.n == rec.N {
+			c.results = append(c.results, rec)
+			c.verified = c.verified || rec.Verified
+		}
+	}
+}
+
+// newID returns a random identifier of the given size in bytes, for a lease
+// or a worker.
+func newID(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// decode reads the JSON body of a POST request into v. If that is not
+// possible, it responds with an error and returns false.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Expected a POST request", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// respond writes v as the JSON body of a response with the given status code.
+func respond(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warningf("Could not write the response: %v", err)
+	}
+}
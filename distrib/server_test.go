@@ -0,0 +1,338 @@
+package distrib
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arcetri/goprime/ledger"
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// newBatch returns the Riesel numbers with the given h and n.
+func newBatch(t *testing.T, hn [][2]int64) []*rieseltest.RieselNumber {
+	var batch []*rieseltest.RieselNumber
+	for _, c := range hn {
+		R, err := rieseltest.NewRieselNumber(c[0], c[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch = append(batch, R)
+	}
+
+	return batch
+}
+
+// newLedger opens a new ledger in a temporary directory, and returns it
+// together with a function that reads back its records.
+func newLedger(t *testing.T) (*ledger.Ledger, func() []*ledger.Record) {
+	path := filepath.Join(t.TempDir(), "test.ledger")
+	l, err := ledger.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	return l, func() []*ledger.Record {
+		var records []*ledger.Record
+		if err := ledger.Read(path, func(r *ledger.Record) error {
+			records = append(records, r)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return records
+	}
+}
+
+func TestServerAndWorkers(t *testing.T) {
+	expected := map[[2]int64]bool{
+		{15, 5}:     true,
+		{9, 7}:      true,
+		{507, 2005}: false,
+		{3, 1274}:   true,
+		{375, 9}:    true,
+		{3, 5000}:   false, // screened, with the factor 13
+	}
+
+	var hn [][2]int64
+	for c := range expected {
+		hn = append(hn, c)
+	}
+
+	l, records := newLedger(t)
+	server := NewServer(newBatch(t, hn), time.Minute, l)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for _, id := range []string{"alice", "bob"} {
+		w := &Worker{URL: ts.URL, ID: id, CheckpointDir: t.TempDir(), PollInterval: 10 * time.Millisecond}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.Run(); err != nil {
+				t.Errorf("Worker %v returned an error: %v", w.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !server.Done() {
+		t.Fatalf("The workers returned before the batch was complete")
+	}
+
+	// Every number is tested by both the workers, and the first result is
+	// recorded again once verified by the second one
+	recs := records()
+	if len(recs) != 3*len(expected) {
+		t.Fatalf("The server recorded %v results, but we expected %v", len(recs), 3*len(expected))
+	}
+
+	verified := 0
+	for _, rec := range recs {
+		if (rec.Verdict == ledger.Prime) != expected[[2]int64{rec.H, rec.N}] {
+			t.Errorf("The server recorded %v * 2^%v - 1 as %v, which is not what we expected",
+				rec.H, rec.N, rec.Verdict)
+		}
+		if rec.Verified {
+			verified++
+		}
+	}
+
+	if verified != 2*len(expected) {
+		t.Errorf("The server verified %v results, but we expected %v", verified, 2*len(expected))
+	}
+}
+
+// post sends v to the given path of the server and returns the response code,
+// decoding the response body in out if it is not nil.
+func post(t *testing.T, s http.Handler, path string, v, out interface{}) int {
+	body, _ := json.Marshal(v)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rec.Code
+}
+
+// register registers a worker with the given name, and returns its identity.
+func register(t *testing.T, s http.Handler, name string) string {
+	var reg Registration
+	if code := post(t, s, "/register", &Registration{Name: name}, &reg); code != http.StatusOK {
+		t.Fatalf("/register of %v returned %v, but we expected 200", name, code)
+	}
+	return reg.Worker
+}
+
+func TestRegister(t *testing.T) {
+	server := NewServer(newBatch(t, [][2]int64{{15, 5}}), time.Minute, nil)
+
+	// The identities are issued by the server
+	alice, other := register(t, server, "alice"), register(t, server, "alice")
+	if alice == other || !strings.HasPrefix(alice, "alice-") {
+		t.Errorf("/register issued %v and %v to two workers named alice", alice, other)
+	}
+	if code := post(t, server, "/register", &Registration{}, nil); code != http.StatusBadRequest {
+		t.Errorf("/register without a name returned %v, but we expected 400", code)
+	}
+
+	// A worker cannot lease under an identity that the server did not issue
+	for _, worker := range []string{"", "alice", "bob"} {
+		if code := post(t, server, "/lease", &LeaseRequest{Worker: worker}, nil); code != http.StatusForbidden {
+			t.Errorf("/lease to the unknown worker %q returned %v, but we expected 403", worker, code)
+		}
+	}
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	now := time.Now()
+	server := NewServer(newBatch(t, [][2]int64{{15, 5}}), time.Minute, nil)
+	server.now = func() time.Time { return now }
+	workers := map[string]string{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		workers[name] = register(t, server, name)
+	}
+
+	// The number is leased to two distinct workers
+	var alice, bob, carol Lease
+	if code := post(t, server, "/lease", &LeaseRequest{Worker: workers["alice"]}, &alice); code != http.StatusOK {
+		t.Fatalf("/lease returned %v, but we expected 200", code)
+	}
+	if code := post(t, server, "/lease", &LeaseRequest{Worker: workers["alice"]}, nil); code != http.StatusNoContent {
+		t.Errorf("/lease of the same number to the same worker returned %v, but we expected 204", code)
+	}
+	if code := post(t, server, "/lease", &LeaseRequest{Worker: workers["bob"]}, &bob); code != http.StatusOK {
+		t.Fatalf("/lease to a second worker returned %v, but we expected 200", code)
+	}
+	if code := post(t, server, "/lease", &LeaseRequest{Worker: workers["carol"]}, nil); code != http.StatusNoContent {
+		t.Errorf("/lease with all the runs leased returned %v, but we expected 204", code)
+	}
+
+	// Heartbeats extend the lease of alice, while the one of bob expires
+	now = now.Add(50 * time.Second)
+	if code := post(t, server, "/heartbeat", &Heartbeat{Lease: alice.ID}, nil); code != http.StatusOK {
+		t.Errorf("/heartbeat returned %v, but we expected 200", code)
+	}
+	now = now.Add(50 * time.Second)
+
+	// Once expired, the run is reassigned and the old lease is rejected
+	if code := post(t, server, "/lease", &LeaseRequest{Worker: workers["carol"]}, &carol); code != http.StatusOK {
+		t.Fatalf("/lease after the expiry returned %v, but we expected 200", code)
+	}
+	if carol.ID == bob.ID || carol.H != 15 || carol.N != 5 {
+		t.Errorf("/lease after the expiry returned %+v, but we expected a new lease of 15*2^5-1", carol)
+	}
+
+	sub := &Submission{Lease: bob.ID, Record: ledger.Record{H: 15, N: 5, Verdict: ledger.Prime,
+		Residue: "0000000000000000"}}
+	if code := post(t, server, "/submit", sub, nil); code != http.StatusNotFound {
+		t.Errorf("/submit with an expired lease returned %v, but we expected 404", code)
+	}
+	if code := post(t, server, "/heartbeat", &Heartbeat{Lease: bob.ID}, nil); code != http.StatusNotFound {
+		t.Errorf("/heartbeat with an expired lease returned %v, but we expected 404", code)
+	}
+
+	sub = &Submission{Lease: carol.ID, Record: ledger.Record{H: 15, N: 6, Verdict: ledger.Prime,
+		Residue: "0000000000000000"}}
+	if code := post(t, server, "/submit", sub, nil); code != http.StatusBadRequest {
+		t.Errorf("/submit with a mismatched number returned %v, but we expected 400", code)
+	}
+}
+
+func TestDoubleCheck(t *testing.T) {
+	l, records := newLedger(t)
+	server := NewServer(newBatch(t, [][2]int64{{15, 5}}), time.Minute, l)
+	workers := map[string]string{}
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		workers[name] = register(t, server, name)
+	}
+
+	submit := func(worker string, verdict, residue string) {
+		var l Lease
+		if code := post(t, server, "/lease", &LeaseRequest{Worker: workers[worker]}, &l); code != http.StatusOK {
+			t.Fatalf("/lease to %v returned %v, but we expected 200", worker, code)
+		}
+
+		sub := &Submission{Lease: l.ID, Record: ledger.Record{H: 15, N: 5, Verdict: verdict, Residue: residue}}
+		if code := post(t, server, "/submit", sub, nil); code != http.StatusOK {
+			t.Fatalf("/submit from %v returned %v, but we expected 200", worker, code)
+		}
+	}
+
+	// A result without a valid residue is rejected
+	var l0 Lease
+	if code := post(t, server, "/lease", &LeaseRequest{Worker: workers["alice"]}, &l0); code != http.StatusOK {
+		t.Fatalf("/lease returned %v, but we expected 200", code)
+	}
+	for _, residue := range []string{"", "0", "XYZ"} {
+		sub := &Submission{Lease: l0.ID, Record: ledger.Record{H: 15, N: 5, Verdict: ledger.Prime, Residue: residue}}
+		if code := post(t, server, "/submit", sub, nil); code != http.StatusBadRequest {
+			t.Errorf("/submit with the residue %q returned %v, but we expected 400", residue, code)
+		}
+	}
+	sub := &Submission{Lease: l0.ID, Record: ledger.Record{H: 15, N: 5, Verdict: ledger.Prime,
+		Residue: "0000000000000000"}}
+	if code := post(t, server, "/submit", sub, nil); code != http.StatusOK {
+		t.Fatalf("/submit returned %v, but we expected 200", code)
+	}
+
+	// Two mismatching results require a third run by another worker
+	submit("bob", ledger.Composite, "0123456789ABCDEF")
+	if code := post(t, server, "/lease", &LeaseRequest{Worker: workers["alice"]}, nil); code != http.StatusNoContent {
+		t.Errorf("/lease of a number already tested by the worker returned %v, but we expected 204", code)
+	}
+	if server.Done() {
+		t.Errorf("The number was verified with mismatching results")
+	}
+
+	submit("carol", ledger.Prime, "0000000000000000")
+	if !server.Done() {
+		t.Errorf("The number was not verified with two matching results")
+	}
+	if code := post(t, server, "/lease", &LeaseRequest{Worker: workers["dave"]}, nil); code != http.StatusGone {
+		t.Errorf("/lease with a verified batch returned %v, but we expected 410", code)
+	}
+
+	// Both the matching results are marked as verified
+	var verified []string
+	for _, rec := range records() {
+		if rec.Verified {
+			verified = append(verified, rec.Worker)
+		}
+	}
+
+	if len(verified) != 2 || verified[0] != workers["carol"] || verified[1] != workers["alice"] {
+		t.Errorf("The results of %v were marked as verified, but we expected the ones of carol and alice",
+			verified)
+	}
+
+	// A restarted server does not hand out the verified number
+	server = NewServer(newBatch(t, [][2]int64{{15, 5}}), time.Minute, nil)
+	for _, rec := range records() {
+		server.Restore(rec)
+	}
+	if !server.Done() {
+		t.Errorf("The restored server did not consider the number as verified")
+	}
+}
+
+// stopMonitor closes stop when a test reaches the iteration at.
+type stopMonitor struct {
+	at   int64
+	once sync.Once
+	stop chan struct{}
+}
+
+func (m *stopMonitor) Started(R *rieseltest.RieselNumber) {}
+func (m *stopMonitor) CheckpointSaved(R *rieseltest.RieselNumber, i int64) {}
+func (m *stopMonitor) Error(R *rieseltest.RieselNumber, err error) {}
+func (m *stopMonitor) Done(R *rieseltest.RieselNumber, result *rieseltest.Result, err error) {}
+
+func (m *stopMonitor) Iteration(R *rieseltest.RieselNumber, i int64) {
+	if i >= m.at {
+		m.once.Do(func() { close(m.stop) })
+	}
+}
+
+func TestWorkerStop(t *testing.T) {
+	server := NewServer(newBatch(t, [][2]int64{{3, 4990}}), time.Minute, nil)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	m := &stopMonitor{at: 1000, stop: make(chan struct{})}
+	dir := t.TempDir()
+	w := &Worker{URL: ts.URL, ID: "alice", CheckpointDir: dir, Monitor: m, Stop: m.stop}
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run() }()
+
+	select {
+	case err := <-done:
+		if err != rieseltest.ErrInterrupted {
+			t.Errorf("Run returned %v, but we expected ErrInterrupted", err)
+		}
+	case <-time.After(time.Minute):
+		t.Fatalf("Run did not return after Stop was closed")
+	}
+
+	c, err := rieseltest.LoadCheckpoint(filepath.Join(dir, "3-4990.ckpt"))
+	if err != nil {
+		t.Fatalf("The worker did not save the checkpoint of the interrupted test: %v", err)
+	}
+	if c.Iteration < 1000 || c.Iteration >= 4990 {
+		t.Errorf("The checkpoint is at U(%v), but we expected it between U(1000) and U(4990)", c.Iteration)
+	}
+}
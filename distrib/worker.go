@@ -0,0 +1,330 @@
+package distrib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arcetri/goprime/ledger"
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// Default intervals used by a Worker when they are not set.
+const (
+	DefaultHeartbeatInterval = time.Minute
+	DefaultPollInterval      = 30 * time.Second
+)
+
+// errLeaseLost is returned when the server does not recognize a lease anymore.
+var errLeaseLost = errors.New("The lease is unknown or expired")
+
+// errUnknownWorker is returned when the server does not recognize the identity
+// of the worker anymore.
+var errUnknownWorker = errors.New("The worker is unknown to the server")
+
+// Worker tests the numbers leased by a Server.
+type Worker struct {
+
+	// URL is the base URL of the server, e.g. "http://localhost:8080".
+	URL string
+
+	// ID names the worker in the logs. The server issues the identity under
+	// which the results of the worker are recorded, starting with ID.
+	ID string
+
+	// CheckpointDir is the directory where the checkpoints of the tests are
+	// saved, so that a test can be resumed if the worker is restarted and the
+	// same number is leased to it again. When empty, no checkpoint is written.
+	CheckpointDir string
+
+	// CertificateDir is the directory where the certificates of the primes
+	// found are written. When empty, no certificate is written.
+	CertificateDir string
+
+	// InterimInterval, when positive, records the RES64 of U(i) every
+	// InterimInterval iterations in the submitted results, so that the server
+	// can locate where two mismatching results diverged.
+	InterimInterval int64
+
+	// HeartbeatInterval is the time between two heartbeats during a test.
+	HeartbeatInterval time.Duration
+
+	// PollInterval is the time to wait before asking again for work when
+	// all the remaining numbers are leased to other workers.
+	PollInterval time.Duration
+
+	// Client is the HTTP client used to contact the server. When nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// Monitor, when set, is notified of the progress of the tests.
+	Monitor rieseltest.Monitor
+
+	// Stop, when closed, interrupts the test in progress at its next
+	// iteration, saving its checkpoint, and Run returns
+	// rieseltest.ErrInterrupted.
+	Stop <-chan struct{}
+
+	// identity is the identity issued by the server.
+	identity string
+}
+
+// Run leases and tests numbers until the server reports that all the numbers
+// of the batch have a result, or until Stop is closed.
+func (w *Worker) Run() error {
+	poll := w.PollInterval
+	if poll <= 0 {
+		poll = DefaultPollInterval
+	}
+
+	for _, dir := range []string{w.CheckpointDir, w.CertificateDir} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	if err := w.register(); err != nil {
+		return err
+	}
+
+	for {
+		if w.stopped() {
+			return rieseltest.ErrInterrupted
+		}
+
+		l, done, err := w.lease()
+		if err == errUnknownWorker {
+
+			// The server was restarted, and does not know the identity anymore
+			if err = w.register(); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if done {
+			log.Infof("Worker %v: the batch is complete", w.ID)
+			return nil
+		}
+		if l == nil {
+			select {
+			case <-w.Stop:
+			case <-time.After(poll):
+			}
+			continue
+		}
+
+		if err = w.test(l); err != nil && err != errLeaseLost {
+			return err
+		}
+	}
+}
+
+// stopped returns true if Stop is closed.
+func (w *Worker) stopped() bool {
+	select {
+	case <-w.Stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// register asks the server for the identity of the worker.
+func (w *Worker) register() error {
+	resp, err := w.post("/register", &Registration{Name: w.ID})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("Unexpected response to /register: %v", resp.Status))
+	}
+
+	var reg Registration
+	if err = json.NewDecoder(resp.Body).Decode(&reg); err != nil {
+		return err
+	}
+	w.identity = reg.Worker
+	log.Infof("Worker %v registered as %v", w.ID, w.identity)
+	return nil
+}
+
+// lease asks the server for a number. It returns a nil lease if all the
+// remaining numbers are leased, and done == true if the batch is complete.
+func (w *Worker) lease() (l *Lease, done bool, err error) {
+	resp, err := w.post("/lease", &LeaseRequest{Worker: w.identity})
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		l = new(Lease)
+		err = json.NewDecoder(resp.Body).Decode(l)
+		return l, false, err
+	case http.StatusNoContent:
+		return nil, false, nil
+	case http.StatusGone:
+		return nil, true, nil
+	case http.StatusForbidden:
+		return nil, false, errUnknownWorker
+	default:
+		return nil, false, errors.New(fmt.Sprintf("Unexpected response to /lease: %v", resp.Status))
+	}
+}
+
+// test tests the leased number and submits its result, sending heartbeats
+// in the meantime. If the lease is lost or Stop is closed, the test is
+// interrupted.
+func (w *Worker) test(l *Lease) error {
+	R, err := rieseltest.NewRieselNumber(l.H, l.N)
+	if err != nil {
+		return err
+	}
+
+	interval := w.HeartbeatInterval
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	interrupt := func() { once.Do(func() { close(stop) }) }
+	finished := make(chan struct{})
+	lost := make(chan error, 1)
+
+	go func() {
+		select {
+		case <-finished:
+		case <-w.Stop:
+			log.Infof("Worker %v: interrupting the test of %v", w.ID, R)
+			interrupt()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-finished:
+				return
+			case <-ticker.C:
+				if err := w.heartbeat(l); err != nil {
+					log.Warningf("Worker %v: stopping the test of %v: %v", w.ID, R, err)
+					lost <- err
+					interrupt()
+					return
+				}
+			}
+		}
+	}()
+
+	opts := rieseltest.DefaultOptions()
+	opts.Stop = stop
+	opts.InterimInterval = w.InterimInterval
+	opts.Monitor = w.Monitor
+
+	// The server matches the residues, which a factor found by the precheck
+	// does not have
+	opts.MersennePrecheck = false
+	if w.CheckpointDir != "" {
+		opts.CheckpointFile = filepath.Join(w.CheckpointDir, fmt.Sprintf("%d-%d.ckpt", l.H, l.N))
+	}
+
+	log.Infof("Worker %v is testing %v", w.ID, R)
+	result, err := rieseltest.Test(R, opts)
+	close(finished)
+
+	if err == rieseltest.ErrInterrupted {
+
+		// The lost lease is sent before the test is interrupted
+		select {
+		case err = <-lost:
+		default:
+			if opts.CheckpointFile != "" {
+				log.Infof("Worker %v: the checkpoint of %v was saved to %v", w.ID, R, opts.CheckpointFile)
+			}
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if w.CertificateDir != "" && result.Certificate != nil {
+		path := filepath.Join(w.CertificateDir, result.Certificate.FileName())
+		if err := rieseltest.WriteCertificate(path, result.Certificate); err != nil {
+			log.Warningf("Worker %v: could not write the certificate of %v: %v", w.ID, R, err)
+		}
+	}
+
+	return w.submit(&Submission{Lease: l.ID, Record: *ledger.FromResult(result)})
+}
+
+// heartbeat extends the given lease.
+func (w *Worker) heartbeat(l *Lease) error {
+	resp, err := w.post("/heartbeat", &Heartbeat{Lease: l.ID})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return errLeaseLost
+	default:
+		return errors.New(fmt.Sprintf("Unexpected response to /heartbeat: %v", resp.Status))
+	}
+}
+
+// submit sends a result to the server.
+func (w *Worker) submit(sub *Submission) error {
+	resp, err := w.post("/submit", sub)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		log.Warningf("Worker %v: the result of %v * 2^%v - 1 was rejected: the lease expired", w.ID, sub.H, sub.N)
+		return errLeaseLost
+	default:
+		return errors.New(fmt.Sprintf("Unexpected response to /submit: %v", resp.Status))
+	}
+}
+
+// post sends v as the JSON body of a POST request to the given path of the server.
+func (w *Worker) post(path string, v interface{}) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return client.Post(strings.TrimSuffix(w.URL, "/")+path, "application/json", bytes.NewReader(body))
+}
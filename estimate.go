@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// runEstimate implements the "goprime estimate" command, which estimates how
+// long the test of a number would take.
+func runEstimate(args []string) error {
+	fs := flag.NewFlagSet("estimate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime estimate [-method riesel|rodseth|penne] [-sample N] h n\n")
+		fmt.Print("  goprime estimate [-method riesel|rodseth|penne] [-sample N] expression\n\n")
+		fmt.Print("Generates V(1) and U(2), and times the first iterations of U(i), to estimate how long\n")
+		fmt.Print("the test of h*2^n-1 would take on this machine.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	methodPtr := fs.String("method", "rodseth", "Method used to find V(1) when h is a multiple of 3.")
+	samplePtr := fs.Int64("sample", rieseltest.DefaultEstimateSample, "Number of iterations of U(i) timed.")
+	configureLogger := loggerFlags(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if fs.NArg() != 1 && fs.NArg() != 2 {
+		return usageError(fs, "")
+	}
+	if *samplePtr < 1 {
+		return usageError(fs, "Expected sample >= 1.")
+	}
+
+	method, ok := v1Methods[*methodPtr]
+	if !ok {
+		return errors.New(fmt.Sprintf("Unknown V(1) method %v", *methodPtr))
+	}
+
+	R, err := parseCandidate(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	e, err := rieseltest.EstimateDuration(R, method, *samplePtr)
+	if err != nil {
+		return err
+	}
+
+	_, n := R.Params()
+	fmt.Printf("N = %v (%v digits)\n", R, R.Digits())
+	fmt.Printf("V(1): %v\n", e.V1)
+	fmt.Printf("U(2): %v\n", e.U2)
+	fmt.Printf("U(n): %v iterations of %v (timed over %v): %v\n", n - 2, e.Iteration, e.Sample, e.UN)
+	fmt.Printf("Total: %v\n", e.Total())
+	return nil
+}
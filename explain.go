@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// v1Methods maps the names of the V(1) methods accepted on the command line
+// to their rieseltest constants.
+var v1Methods = map[string]uint8{
+	"riesel":  rieseltest.RIESEL,
+	"rodseth": rieseltest.RODSETH,
+	"penne":   rieseltest.PENNE,
+}
+
+// runExplain implements the "goprime explain" command, which walks through
+// every step of the test of a small number.
+func runExplain(args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime explain [-method riesel|rodseth|penne] [-markdown] h n | expression\n\n")
+		fmt.Printf("Tests h*2^n-1, printing every step with its values. N must have at most %v bits.\n\n",
+			rieseltest.MaxExplainBits)
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	methodPtr := fs.String("method", "rodseth", "Method used to find V(1) when h is a multiple of 3.")
+	markdownPtr := fs.Bool("markdown", false, "Print the steps as Markdown instead of plain text.")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 && fs.NArg() != 2 {
+		return usageError(fs, "")
+	}
+
+	method, ok := v1Methods[*methodPtr]
+	if !ok {
+		return errors.New(fmt.Sprintf("Unknown V(1) method %v", *methodPtr))
+	}
+
+	R, err := parseCandidate(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	_, err = rieseltest.Explain(os.Stdout, R, method, *markdownPtr)
+	return err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// runExportScript implements the "goprime export-script" command, which writes
+// a calc or PARI/GP script performing the same test as goprime.
+func runExportScript(args []string) error {
+	fs := flag.NewFlagSet("export-script", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime export-script [-format calc|gp] [-method riesel|rodseth|penne] h n | expression\n\n")
+		fmt.Print("Tests h*2^n-1, and prints a self-contained script which performs the same test with calc\n")
+		fmt.Print("or PARI/GP, and checks that it agrees with goprime.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	formatPtr := fs.String("format", rieseltest.ScriptCalc, "Language of the script, calc or gp.")
+	methodPtr := fs.String("method", "rodseth", "Method used to find V(1) when h is a multiple of 3.")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 && fs.NArg() != 2 {
+		return usageError(fs, "")
+	}
+
+	method, ok := v1Methods[*methodPtr]
+	if !ok {
+		return errors.New(fmt.Sprintf("Unknown V(1) method %v", *methodPtr))
+	}
+
+	R, err := parseCandidate(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	return rieseltest.WriteScript(os.Stdout, *formatPtr, R, method)
+}
@@ -3,10 +3,11 @@ package main
 import (
 	"github.com/arcetri/goprime/rieseltest"
 	"github.com/op/go-logging"
+	"errors"
 	"fmt"
 	"flag"
 	"os"
-	"strconv"
+	"strings"
 )
 
 var logLevels = map[int]logging.Level{
@@ -15,66 +16,167 @@ var logLevels = map[int]logging.Level{
 	3: logging.DEBUG,
 }
 
-func main() {
-
-	// Define the Usage message
-	flag.Usage = func() {
-		fmt.Print("GoPrime, a software to test the primality of numbers of the form h*2^n-1.\n\n")
-		fmt.Print("Usage:\n")
-		fmt.Print("  goprime [h] [n]\n\n")
-		fmt.Print("Optional flags:\n")
-		flag.PrintDefaults()
-	}
-
-	// Read command line arguments
-	//		'-t level' for outputting logs of the specified level and higher to the terminal
-	//		'-f level' for outputting logs of the specified level and higher to a file
-	terminalLoggerPtr := flag.Int("t", 0, "Level of logs to be written to stdout " +
+// loggerFlags defines the logging flags in the given flag set: '-t' and '-f'
+// for the levels of the terminal and file logs, and the '-log-*' flags for
+// their destination, rotation, format and per-phase levels. The returned
+// function configures the logger according to them, and it must be called
+// once the flag set is parsed.
+func loggerFlags(fs *flag.FlagSet) func() {
+	terminalLoggerPtr := fs.Int("t", 0, "Level of logs to be written to stdout " +
 		"{0 = None (default); 1 = Warning; 2 = Info; 3 = Debug}.")
-	fileLoggerPtr := flag.Int("f", 0, "Level of logs to be written to log files " +
+	fileLoggerPtr := fs.Int("f", 0, "Level of logs to be written to log files " +
 		"{0 = None (default); 1 = Warning; 2 = Info; 3 = Debug}.")
-	flag.Parse()
+	dirPtr := fs.String("log-dir", rieseltest.DefaultLogDir, "Directory of the log files.")
+	maxSizePtr := fs.Int("log-max-size", rieseltest.DefaultLogMaxSize, "Size in megabytes at which a log " +
+		"file is rotated.")
+	maxBackupsPtr := fs.Int("log-max-backups", rieseltest.DefaultLogMaxBackups, "Number of rotated log " +
+		"files kept (-1 to keep them all).")
+	maxAgePtr := fs.Int("log-max-age", rieseltest.DefaultLogMaxAge, "Days after which the rotated log " +
+		"files are removed (-1 to keep them).")
+	compressPtr := fs.Bool("log-compress", false, "Compress the rotated log files with gzip.")
+	formatPtr := fs.String("log-format", rieseltest.LogText, "Format of the logs: text, or json for one " +
+		"object per message with fields such as phase, iteration and residue.")
+	levelsPtr := fs.String("log-levels", "", "Comma separated phase=level pairs overriding the levels of " +
+		"some phases (screening, v1, u2, un, checkpoint) or packages (rieseltest, search, ledger, distrib), " +
+		"such as v1=debug,un=info.")
 
-	// Check for validity of command line arguments
-	if *fileLoggerPtr < 0 || *fileLoggerPtr > 3 || *terminalLoggerPtr < 0 || *terminalLoggerPtr > 3 {
-		fmt.Print("Unexpected command line flags.\n\n")
-		flag.Usage()
-		os.Exit(1)
-	}
+	return func() {
 
-	// Configure logger according to the command line arguments
-	rieseltest.ConfigureLogger(*fileLoggerPtr != 0, logLevels[*fileLoggerPtr],
-		*terminalLoggerPtr != 0, logLevels[*terminalLoggerPtr])
+		// Check for validity of the logging flags
+		if *fileLoggerPtr < 0 || *fileLoggerPtr > 3 || *terminalLoggerPtr < 0 || *terminalLoggerPtr > 3 {
+			fmt.Fprint(os.Stderr, "Expected the logging levels -t and -f between 0 and 3.\n\n")
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
+		levels, err := parseLogLevels(*levelsPtr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n\n", err)
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
 
-	// Read h and n arguments
-	if len(flag.Args()) < 2 {
-		flag.Usage()
-		os.Exit(1)
+		// Configure logger according to the command line arguments
+		err = rieseltest.ConfigureLogging(&rieseltest.LogConfig{
+			Terminal:      *terminalLoggerPtr != 0,
+			TerminalLevel: logLevels[*terminalLoggerPtr],
+			File:          *fileLoggerPtr != 0,
+			FileLevel:     logLevels[*fileLoggerPtr],
+			Dir:           *dirPtr,
+			MaxSize:       *maxSizePtr,
+			MaxBackups:    *maxBackupsPtr,
+			MaxAge:        *maxAgePtr,
+			Compress:      *compressPtr,
+			Format:        *formatPtr,
+			PhaseLevels:   levels,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n\n", err)
+			fs.Usage()
+			os.Exit(exitUsage)
+		}
 	}
+}
 
-	// Try to convert them to int64 values
-	h, err := strconv.ParseInt(flag.Args()[0], 10, 64)
-	if err != nil { panic(err) }
-	n, err := strconv.ParseInt(flag.Args()[1], 10, 64)
-	if err != nil { panic(err) }
+// parseLogLevels parses the phase=level pairs of the '-log-levels' flag.
+func parseLogLevels(s string) (map[string]logging.Level, error) {
+	levels := make(map[string]logging.Level)
+	if s == "" {
+		return levels, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid log level %q: expected phase=level", pair))
+		}
+		level, err := logging.LogLevel(parts[1])
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid log level %q: expected debug, info, notice, warning, " +
+				"error or critical", parts[1]))
+		}
+		levels[parts[0]] = level
+	}
+	return levels, nil
+}
 
-	// Create RieselNumber instance with the specified h and n
-	N, err := rieseltest.NewRieselNumber(int64(h), int64(n))
+// commands are the commands of goprime, in the order of the usage message.
+var commands = []struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}{
+	{"test", "test [-base b] [-checkpoint file] [-output format] h n | expression", runTest},
+	{"test", "test -batch file [-workers N] [-checkpoints dir] [-output format]", runTest},
+	{"resume", "resume [-output format] file.ckpt", runResume},
+	{"prp", "prp [-base b] [-output format] h n | expression", runPRP},
+	{"estimate", "estimate [-method M] [-sample N] h n | expression", runEstimate},
+	{"bench", "bench [-sizes n,n,...] [-benchtime d] [-diff old.out]", runBench},
+	{"sieve", "sieve -h h -nmin n -nmax n [-sieve limit]", runSieve},
+	{"search", "search -h h -nmin n -nmax n [-workers N] [-output format]", runSearch},
+	{"covering", "covering -h h [-modulus M] [-primes limit]", runCovering},
+	{"weight", "weight h [nmin nmax]", runWeight},
+	{"v1table", "v1table -h h [-maxp P] [-o file]", runV1Table},
+	{"explain", "explain [-method M] [-markdown] h n | expression", runExplain},
+	{"export-script", "export-script [-format calc|gp] [-method M] h n | expression", runExportScript},
+	{"lookup", "lookup [-known file] h n | expression", runLookup},
+	{"lookup", "lookup import [-known file] file...", runLookup},
+	{"server", "server -batch file -ledger file [-addr address]", runServer},
+	{"worker", "worker -server URL", runWorker},
+	{"results", "results query -ledger file [-h h] [-nmin n] [-nmax n] [-primes] [-gaps] [-csv]", runResults},
+	{"verify", "verify [-replay=false] file...", runVerifyCert},
+	{"verify-cert", "verify-cert [-replay=false] file...", runVerifyCert},
+}
 
-	// N, err := rieseltest.NewRieselNumber(507, 217588)
-	// N, err := rieseltest.NewRieselNumber(502573, 7181987)	// largest known Riesel prime
+// usage prints the usage message of goprime.
+func usage() {
+	fmt.Print("GoPrime, a software to test the primality of numbers of the form h*2^n-1.\n\n")
+	fmt.Print("Usage:\n")
+	for _, c := range commands {
+		fmt.Printf("  goprime %v\n", c.usage)
+	}
+	fmt.Print("\nThe numbers are given either as h and n, or as an expression such as 3*2^1000-1 or 2^127-1,\n")
+	fmt.Print("or as the decimal digits of N. Without a command, as in goprime 3 1000, the number is tested.\n")
+	fmt.Print("Run goprime <command> -help for the flags of a command.\n\n")
+	fmt.Print("Exit status:\n")
+	fmt.Printf("  %v  the command completed\n", exitOK)
+	fmt.Printf("  %v  the command failed, as reported on stderr\n", exitError)
+	fmt.Printf("  %v  the command was invoked with wrong arguments or flags\n", exitUsage)
+	fmt.Printf("  %v  interrupted by SIGINT or SIGTERM, after saving the checkpoints\n", exitInterrupted)
+	fmt.Printf("  %v  interrupted by a second SIGINT or SIGTERM, without saving anything\n", exitForced)
+}
 
-	if err != nil {
-		fmt.Println(err)
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
 
-	} else {
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	}
 
-		// Test the specified Riesel number for primality
-		result, err := rieseltest.IsPrime(N)
-		if err != nil {
-			fmt.Println(err)
-		} else {
-			fmt.Println(result)
+	for _, c := range commands {
+		if c.name == args[0] {
+			os.Exit(exitStatus(c.run(args[1:])))
 		}
 	}
+
+	if len(args[0]) == 0 {
+		fmt.Fprint(os.Stderr, "Expected a command or a number, but received an empty argument.\n\n")
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	// The numbers start with a digit, anything else is an unknown command
+	if first := args[0][0]; first != '-' && (first < '0' || first > '9') {
+		fmt.Fprintf(os.Stderr, "Unknown command %v.\n\n", args[0])
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	// goprime [flags] h n is goprime test [flags] h n
+	os.Exit(exitStatus(runTest(args)))
 }
@@ -0,0 +1,247 @@
+// Package ledger implements a durable, append-only store for the results of
+// the primality tests.
+//
+// A ledger is a text file with one JSON object per line. Every line wraps a
+// Record together with the CRC-32 checksum of its JSON encoding:
+//
+//		{"crc32":"9c3d4a8f","record":{"h":3,"n":1274,"verdict":"prime",...}}
+//
+// so that corrupted lines can be detected when the ledger is read. Lines are
+// only ever appended, and each append is synced to disk.
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arcetri/goprime/rieseltest"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("ledger")
+
+// Possible verdicts of a Record.
+const (
+	Prime     = "prime"
+	Composite = "composite"
+)
+
+// A Record is the result of a primality test of h*2^n-1.
+type Record struct {
+	H       int64  `json:"h"`
+	N       int64  `json:"n"`
+	Verdict string `json:"verdict"`
+
+	// Residue is the RES64 of U(n) mod N in hexadecimal. It is empty when the
+	// verdict was decided by the screening of small primes, or by a database
+	// of known primes.
+	Residue string `json:"residue,omitempty"`
+	V1      int64  `json:"v1,omitempty"`
+
+	// Time spent generating V(1), U(2) and U(n), in seconds.
+	V1Seconds float64 `json:"v1_seconds"`
+	U2Seconds float64 `json:"u2_seconds"`
+	UNSeconds float64 `json:"un_seconds"`
+
+	Backend string    `json:"backend"`
+	Host    string    `json:"host"`
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+
+	// Worker is the worker which performed the test, when it was distributed
+	// by a work server. Verified is true when the result was matched by the
+	// test of another worker. Since the ledger is append-only, a result which
+	// is matched by a later one is recorded again, with Verified set, and
+	// Select collapses the records of each number to a single one.
+	Worker   string `json:"worker,omitempty"`
+	Verified bool   `json:"verified,omitempty"`
+
+	// Interim are the interim residues recorded during the test, if requested.
+	Interim []Interim `json:"interim,omitempty"`
+
+	// Eliminated is the reason why h*2^n-1 was found composite without being
+	// tested, such as "sieved" or "covering" for the n eliminated by a search.
+	Eliminated string `json:"eliminated,omitempty"`
+}
+
+// An Interim is the RES64 of U(i) mod N in hexadecimal, recorded during a test.
+type Interim struct {
+	Iteration int64  `json:"i"`
+	Residue   string `json:"res64"`
+}
+
+// InterimResidues returns the interim residues of the record. The malformed
+// ones are skipped.
+func (r *Record) InterimResidues() []rieseltest.InterimResidue {
+	var residues []rieseltest.InterimResidue
+	for _, i := range r.Interim {
+		if residue, err := strconv.ParseUint(i.Residue, 16, 64); err == nil {
+			residues = append(residues, rieseltest.InterimResidue{Iteration: i.Iteration, Residue: residue})
+		}
+	}
+
+	return residues
+}
+
+// FromResult returns the Record of the given result, performed on this host.
+func FromResult(result *rieseltest.Result) *Record {
+	h, n := result.R.Params()
+	host, _ := os.Hostname()
+
+	r := &Record{
+		H:         h,
+		N:         n,
+		Verdict:   Composite,
+		V1:        result.V1,
+		V1Seconds: result.V1Time.Seconds(),
+		U2Seconds: result.U2Time.Seconds(),
+		UNSeconds: result.UNTime.Seconds(),
+		Backend:   rieseltest.Backend(),
+		Host:      host,
+		Version:   rieseltest.Version,
+		Time:      time.Now().UTC(),
+	}
+
+	if result.Prime {
+		r.Verdict = Prime
+	}
+	if !result.Screened && !result.Known {
+		r.Residue = result.ResidueString()
+	}
+	for _, i := range result.Interim {
+		r.Interim = append(r.Interim, Interim{Iteration: i.Iteration, Residue: i.ResidueString()})
+	}
+
+	return r
+}
+
+// FromElimination returns the Record of the composite h*2^n-1, eliminated on
+// this host without being tested for the given reason.
+func FromElimination(h, n int64, reason string) *Record {
+	host, _ := os.Hostname()
+
+	return &Record{
+		H:          h,
+		N:          n,
+		Verdict:    Composite,
+		Backend:    rieseltest.Backend(),
+		Host:       host,
+		Version:    rieseltest.Version,
+		Time:       time.Now().UTC(),
+		Eliminated: reason,
+	}
+}
+
+// line is the format of a line of a ledger.
+type line struct {
+	CRC32  string          `json:"crc32"`
+	Record json.RawMessage `json:"record"`
+}
+
+// Ledger is a ledger file opened for appending.
+type Ledger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens the ledger at the given path for appending, creating it if it does not exist.
+func Open(path string) (*Ledger, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ledger{file: file}, nil
+}
+
+// Append writes the given record at the end of the ledger. It is safe to call
+// Append from multiple goroutines.
+func (l *Ledger) Append(r *Record) error {
+	record, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(&line{CRC32: checksum(record), Record: record})
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err = l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	return l.file.Sync()
+}
+
+// Close closes the ledger.
+func (l *Ledger) Close() error {
+	return l.file.Close()
+}
+
+// Read calls fn for every record of the ledger at the given path, in order.
+// If fn returns an error, the reading stops and that error is returned.
+//
+// A line which is not valid, or whose checksum does not match its record,
+// is reported as an error, except for a truncated last line, which might be
+// left by a crash during an append and is ignored.
+func Read(path string, fn func(*Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return read(file, fn)
+}
+
+// read calls fn for every record read from r.
+func read(r io.Reader, fn func(*Record) error) error {
+	br := bufio.NewReader(r)
+
+	for number := 1; ; number++ {
+		data, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				log.Warningf("Ignoring the truncated line %v of the ledger", number)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var l line
+		if err := json.Unmarshal(data, &l); err != nil {
+			return errors.New(fmt.Sprintf("Line %v of the ledger is not valid: %v", number, err))
+		}
+		if checksum(l.Record) != l.CRC32 {
+			return errors.New(fmt.Sprintf("Line %v of the ledger is corrupted: wrong checksum", number))
+		}
+
+		record := new(Record)
+		if err := json.Unmarshal(l.Record, record); err != nil {
+			return errors.New(fmt.Sprintf("Line %v of the ledger is not valid: %v", number, err))
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// checksum returns the CRC-32 of data in hexadecimal.
+func checksum(data []byte) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+}
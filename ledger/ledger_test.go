@@ -0,0 +1,87 @@
+package ledger
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.ledger")
+	expected := []*Record{
+		{H: 3, N: 1274, Verdict: Prime, Residue: "0000000000000000", V1: 5, Time: time.Unix(1000, 0).UTC()},
+		{H: 507, N: 2005, Verdict: Composite, Residue: "B888A8E4F7CA0445", V1: 3, Worker: "bob", Verified: true,
+			Interim: []Interim{{Iteration: 1000, Residue: "0123456789ABCDEF"}}},
+	}
+
+	for i := range expected {
+		l, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open returned an error: %v", err)
+		}
+		if err = l.Append(expected[i]); err != nil {
+			t.Fatalf("Append returned an error: %v", err)
+		}
+		l.Close()
+	}
+
+	var actual []*Record
+	if err := Read(path, func(r *Record) error { actual = append(actual, r); return nil }); err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("Read returned %v records, but we expected %v", len(actual), len(expected))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(actual[i], expected[i]) {
+			t.Errorf("Read returned %+v, but we expected %+v", actual[i], expected[i])
+		}
+	}
+}
+
+func TestReadCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.ledger")
+	l, _ := Open(path)
+	l.Append(&Record{H: 3, N: 1274, Verdict: Prime})
+	l.Append(&Record{H: 3, N: 1275, Verdict: Composite})
+	l.Close()
+
+	data, _ := os.ReadFile(path)
+
+	// A truncated last line is ignored
+	truncated := data[:len(data)-10]
+	os.WriteFile(path, truncated, 0644)
+
+	count := 0
+	if err := Read(path, func(r *Record) error { count++; return nil }); err != nil || count != 1 {
+		t.Errorf("Read of a truncated ledger returned %v records and %v, but we expected 1 record", count, err)
+	}
+
+	// A modified record is detected
+	corrupted := strings.Replace(string(data), `"n":1274`, `"n":1276`, 1)
+	os.WriteFile(path, []byte(corrupted), 0644)
+
+	if err := Read(path, func(r *Record) error { return nil }); err == nil {
+		t.Errorf("Read of a corrupted ledger should return an error, but it didn't")
+	}
+}
+
+func TestFromResult(t *testing.T) {
+	R, _ := rieseltest.NewRieselNumber(507, 2005)
+	result, err := rieseltest.Test(R, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := FromResult(result)
+	if r.H != 507 || r.N != 2005 || r.Verdict != Composite || r.Residue != "B888A8E4F7CA0445" || r.V1 != 3 ||
+		r.Backend != rieseltest.Backend() || r.Version != rieseltest.Version {
+		t.Errorf("FromResult returned %+v, which does not match the result %+v", r, result)
+	}
+}
@@ -0,0 +1,165 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// Query selects the records of a ledger. The zero value of every field
+// matches all the records.
+//
+// Since the numbers are recorded with an odd h, an even H selects the records
+// of its odd part, with NMin and NMax shifted by the powers of two moved over
+// 2^n, as NewRieselNumber does.
+type Query struct {
+	H          int64 // if not 0, only the records of this h
+	NMin, NMax int64 // if not 0, only the records with NMin <= n <= NMax
+	Primes     bool  // only the records with the Prime verdict
+	Verified   bool  // only the verified records
+}
+
+// Match returns true if the given record is selected by the query.
+func (q *Query) Match(r *Record) bool {
+	h, shift := normalize(q.H)
+	nmin := q.NMin
+	if shift > 0 && nmin < 2 {
+		nmin = 2
+	}
+
+	switch {
+	case q.H != 0 && r.H != h:
+		return false
+	case nmin != 0 && r.N < nmin + shift:
+		return false
+	case q.NMax != 0 && r.N > q.NMax + shift:
+		return false
+	case q.Primes && r.Verdict != Prime:
+		return false
+	case q.Verified && !r.Verified:
+		return false
+	}
+
+	return true
+}
+
+// Select returns the records of the ledger at the given path selected by the
+// query, collapsed to one record per number as Collapse does.
+func Select(path string, q *Query) ([]*Record, error) {
+	var records []*Record
+
+	err := Read(path, func(r *Record) error {
+		if q.Match(r) {
+			records = append(records, r)
+		}
+		return nil
+	})
+
+	return Collapse(records), err
+}
+
+// Collapse returns one record for every h and n of the given records: the
+// last verified one if any, otherwise the last one. The numbers are returned
+// in the order in which they first appear.
+//
+// A number can have several records, since it can be tested more than once,
+// and a work server records again the results matched by a later one, marked
+// as verified.
+func Collapse(records []*Record) []*Record {
+	index := make(map[[2]int64]int)
+	var collapsed []*Record
+
+	for _, r := range records {
+		key := [2]int64{r.H, r.N}
+		i, ok := index[key]
+		switch {
+		case !ok:
+			index[key] = len(collapsed)
+			collapsed = append(collapsed, r)
+		case r.Verified || !collapsed[i].Verified:
+			collapsed[i] = r
+		}
+	}
+
+	return collapsed
+}
+
+// Gaps returns the ranges [from, to] of the n in [nmin, nmax] for which
+// none of the given records tests or eliminates h*2^n-1. An even h is looked
+// up as its odd part, as Query does, but the gaps are still ranges of the n
+// of h*2^n-1.
+func Gaps(records []*Record, h, nmin, nmax int64) [][2]int64 {
+	odd, shift := normalize(h)
+
+	var tested []int64
+	for _, r := range records {
+		if r.H == odd && r.N >= nmin + shift && r.N <= nmax + shift {
+			tested = append(tested, r.N - shift)
+		}
+	}
+	sort.Slice(tested, func(i, j int) bool { return tested[i] < tested[j] })
+
+	var gaps [][2]int64
+	next := nmin
+	for _, n := range tested {
+		if n > next {
+			gaps = append(gaps, [2]int64{next, n - 1})
+		}
+		if n >= next {
+			next = n + 1
+		}
+	}
+	if next <= nmax {
+		gaps = append(gaps, [2]int64{next, nmax})
+	}
+
+	return gaps
+}
+
+// normalize returns the odd h of the numbers h*2^n-1, as they are recorded,
+// and the shift of their n, using rieseltest.RieselParams. An h which is not
+// positive is returned unchanged, without shift.
+func normalize(h int64) (int64, int64) {
+	odd, n, err := rieseltest.RieselParams(h, 2)
+	if err != nil {
+		return h, 0
+	}
+	return odd, n - 2
+}
+
+// csvHeader is the header of the CSV written by WriteCSV.
+var csvHeader = []string{"h", "n", "verdict", "residue", "v1", "v1_seconds", "u2_seconds", "un_seconds",
+	"backend", "host", "version", "time", "worker", "verified", "eliminated"}
+
+// WriteCSV writes the given records to w in CSV format, with a header line.
+func WriteCSV(w io.Writer, records []*Record) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+
+	for _, r := range records {
+		cw.Write([]string{
+			strconv.FormatInt(r.H, 10),
+			strconv.FormatInt(r.N, 10),
+			r.Verdict,
+			r.Residue,
+			strconv.FormatInt(r.V1, 10),
+			strconv.FormatFloat(r.V1Seconds, 'f', -1, 64),
+			strconv.FormatFloat(r.U2Seconds, 'f', -1, 64),
+			strconv.FormatFloat(r.UNSeconds, 'f', -1, 64),
+			r.Backend,
+			r.Host,
+			r.Version,
+			r.Time.Format(time.RFC3339),
+			r.Worker,
+			strconv.FormatBool(r.Verified),
+			r.Eliminated,
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,86 @@
+package ledger
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryMatch(t *testing.T) {
+	r := &Record{H: 3, N: 1274, Verdict: Prime}
+
+	var testCases = []struct {
+		q        Query
+		expected bool
+	}{
+		{Query{}, true},
+		{Query{H: 3}, true},
+		{Query{H: 5}, false},
+		{Query{NMin: 1000, NMax: 1274}, true},
+		{Query{NMin: 1275}, false},
+		{Query{NMax: 1000}, false},
+		{Query{Primes: true}, true},
+		{Query{Verified: true}, false},
+		{Query{H: 6, NMin: 1273, NMax: 1273}, true},
+		{Query{H: 12, NMax: 1272}, true},
+		{Query{H: 6, NMin: 1274}, false},
+		{Query{H: 6, NMax: 1272}, false},
+	}
+
+	for _, c := range testCases {
+		if actual := c.q.Match(r); actual != c.expected {
+			t.Errorf("%+v.Match(%+v) == %v, but we expected %v", c.q, r, actual, c.expected)
+		}
+	}
+}
+
+func TestGaps(t *testing.T) {
+	records := []*Record{
+		{H: 3, N: 12}, {H: 3, N: 10}, {H: 5, N: 13}, {H: 3, N: 11}, {H: 3, N: 15}, {H: 3, N: 15}, {H: 3, N: 30},
+	}
+
+	var testCases = []struct {
+		h, nmin, nmax int64
+		expected      [][2]int64
+	}{
+		{3, 10, 20, [][2]int64{{13, 14}, {16, 20}}},
+		{3, 5, 15, [][2]int64{{5, 9}, {13, 14}}},
+		{3, 10, 12, nil},
+		{7, 1, 3, [][2]int64{{1, 3}}},
+		{6, 9, 14, [][2]int64{{12, 13}}},
+		{12, 8, 13, [][2]int64{{11, 12}}},
+	}
+
+	for _, c := range testCases {
+		if actual := Gaps(records, c.h, c.nmin, c.nmax); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("Gaps(%v, %v, %v) == %v, but we expected %v", c.h, c.nmin, c.nmax, actual, c.expected)
+		}
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	records := []*Record{
+		{H: 3, N: 12, Worker: "a"}, {H: 3, N: 10, Worker: "a"}, {H: 3, N: 12, Worker: "b", Verified: true},
+		{H: 3, N: 12, Worker: "a", Verified: true}, {H: 3, N: 10, Worker: "b"}, {H: 5, N: 12, Worker: "b"},
+		{H: 3, N: 12, Worker: "c"},
+	}
+	expected := []*Record{
+		{H: 3, N: 12, Worker: "a", Verified: true}, {H: 3, N: 10, Worker: "b"}, {H: 5, N: 12, Worker: "b"},
+	}
+
+	if actual := Collapse(records); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Collapse returned %+v, but we expected %+v", actual, expected)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	WriteCSV(&buf, []*Record{{H: 3, N: 1274, Verdict: Prime, Residue: "0000000000000000", V1: 5, Host: "a,b"}})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "h,n,verdict,residue,v1,") ||
+		!strings.HasPrefix(lines[1], `3,1274,prime,0000000000000000,5,0,0,0,,"a,b",`) {
+		t.Errorf("WriteCSV wrote %q, which is not the expected CSV", buf.String())
+	}
+}
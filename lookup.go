@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/arcetri/goprime/rieseltest/knownprimes"
+)
+
+// defaultKnownPrimes is the database of the known primes updated by
+// "goprime lookup import". While it does not exist, the database embedded in
+// goprime is used.
+const defaultKnownPrimes = "goprime.known"
+
+// embeddedKnownPrimes is the value of the -known flag of the test and search
+// commands which selects the database embedded in goprime. These commands
+// only look up the known primes when -known is given, since a number found in
+// the database is reported as prime without being tested, and the imported
+// primes are not verified.
+const embeddedKnownPrimes = "embedded"
+
+// openKnownPrimes returns the database of the known primes at path, the
+// embedded database if path is embeddedKnownPrimes or the file does not exist,
+// or nil if path is empty.
+func openKnownPrimes(path string) (*knownprimes.DB, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if path == embeddedKnownPrimes {
+		return knownprimes.Embedded(), nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return knownprimes.Embedded(), nil
+	}
+	return knownprimes.LoadFile(path)
+}
+
+// runLookup implements the "goprime lookup" command, which reports whether a
+// number is a known prime, or imports new primes in the database.
+func runLookup(args []string) error {
+	if len(args) > 0 && args[0] == "import" {
+		return runLookupImport(args[1:])
+	}
+
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime lookup [-known file] h n | expression\n")
+		fmt.Print("  goprime lookup import [-known file] file...\n\n")
+		fmt.Print("Reports whether h*2^n-1 is in the database of the known primes, or imports in the\n")
+		fmt.Print("database the primes listed in the files, one \"h n\" pair per line.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	knownPtr := fs.String("known", defaultKnownPrimes, "Database of the known primes. The embedded " +
+		"database is used while the file does not exist.")
+	fs.Parse(args)
+
+	if (fs.NArg() != 1 && fs.NArg() != 2) || *knownPtr == "" {
+		return usageError(fs, "")
+	}
+
+	// The number is looked up by h and n, since N is not needed
+	h, n, err := parseParams(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	db, err := openKnownPrimes(*knownPtr)
+	if err != nil {
+		return err
+	}
+
+	if db.Contains(h, n) {
+		fmt.Printf("%v * 2^%v - 1 is a known prime (database revision %v)\n", h, n, db.Revision())
+	} else {
+		fmt.Printf("%v * 2^%v - 1 is not a known prime (database revision %v)\n", h, n, db.Revision())
+	}
+	return nil
+}
+
+// runLookupImport implements "goprime lookup import", which adds the primes
+// listed in files to the database of the known primes.
+func runLookupImport(args []string) error {
+	fs := flag.NewFlagSet("lookup import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime lookup import [-known file] file...\n\n")
+		fmt.Print("Adds the primes listed in the files, one \"h n\" pair per line, to the database of the\n")
+		fmt.Print("known primes. The database starts from the embedded one if the file does not exist.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	knownPtr := fs.String("known", defaultKnownPrimes, "Database of the known primes to update.")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 || *knownPtr == "" {
+		return usageError(fs, "")
+	}
+
+	db, err := knownprimes.Open(*knownPtr)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range fs.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		added, err := db.Import(file)
+		file.Close()
+		if err != nil {
+			return errors.New(fmt.Sprintf("%v: %v", path, err))
+		}
+		fmt.Printf("%v: %v new primes\n", path, added)
+	}
+
+	if err = db.WriteFile(*knownPtr); err != nil {
+		return err
+	}
+	fmt.Printf("%v: %v known primes, revision %v\n", *knownPtr, db.Len(), db.Revision())
+	return nil
+}
@@ -0,0 +1,209 @@
+// Package metrics exposes the progress of the tests run by goprime in the
+// Prometheus text format, so that long tests and searches can be followed
+// from a monitoring system:
+//
+//		# TYPE goprime_candidate_iteration gauge
+//		goprime_candidate_iteration{h="3",n="1274"} 812
+//		# TYPE goprime_primes_found_total counter
+//		goprime_primes_found_total 1
+//
+// A Collector is fed through the rieseltest.Monitor hooks of the tests (see
+// rieseltest.Options.Monitor), and serves its metrics over HTTP.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// Path is the path of the metrics on the HTTP server started by Serve.
+const Path = "/metrics"
+
+// RateWindow is the minimum duration over which the iterations per second of
+// a test are measured.
+const RateWindow = time.Second
+
+// A Collector keeps the metrics of the tests it monitors. It is a
+// rieseltest.Monitor, safe for concurrent use by the workers of a Pool, and an
+// http.Handler serving the metrics.
+type Collector struct {
+	mu      sync.Mutex
+	running map[*rieseltest.RieselNumber]*candidate
+
+	errors      uint64 // errors detected during the tests
+	checkpoints uint64 // checkpoints written
+	completed   uint64 // tests which completed with a result
+	primes      uint64 // primes found by the completed tests
+
+	// now returns the current time. It is replaced by the tests.
+	now func() time.Time
+}
+
+// A candidate is a number being tested.
+type candidate struct {
+	h, n      int64
+	iteration int64   // last U(i) computed
+	rate      float64 // iterations per second over the last RateWindow
+
+	// Beginning of the current RateWindow
+	sampleIteration int64
+	sampleTime      time.Time
+}
+
+// NewCollector returns a new Collector, with all its metrics at 0.
+func NewCollector() *Collector {
+	return &Collector{
+		running: make(map[*rieseltest.RieselNumber]*candidate),
+		now:     time.Now,
+	}
+}
+
+// Started adds R to the candidates being tested.
+func (c *Collector) Started(R *rieseltest.RieselNumber) {
+	h, n := R.Params()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running[R] = &candidate{h: h, n: n}
+}
+
+// Iteration records that U(i) of R has been computed, and updates the
+// iterations per second of its test once every RateWindow.
+func (c *Collector) Iteration(R *rieseltest.RieselNumber, i int64) {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.running[R]
+	if t == nil {
+		return
+	}
+
+	t.iteration = i
+	if t.sampleTime.IsZero() {
+		t.sampleIteration, t.sampleTime = i, now
+	} else if elapsed := now.Sub(t.sampleTime); elapsed >= RateWindow {
+		t.rate = float64(i - t.sampleIteration) / elapsed.Seconds()
+		t.sampleIteration, t.sampleTime = i, now
+	}
+}
+
+// CheckpointSaved counts the checkpoints written.
+func (c *Collector) CheckpointSaved(R *rieseltest.RieselNumber, i int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkpoints++
+}
+
+// Error counts the errors detected.
+func (c *Collector) Error(R *rieseltest.RieselNumber, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors++
+}
+
+// Done removes R from the candidates being tested, and counts it as completed
+// if its test gave a result.
+func (c *Collector) Done(R *rieseltest.RieselNumber, result *rieseltest.Result, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.running, R)
+
+	if err == nil && result != nil {
+		c.completed++
+		if result.Prime {
+			c.primes++
+		}
+	}
+}
+
+// WriteMetrics writes the current metrics to w in the Prometheus text format.
+func (c *Collector) WriteMetrics(w io.Writer) error {
+	c.mu.Lock()
+	candidates := make([]candidate, 0, len(c.running))
+	for _, t := range c.running {
+		candidates = append(candidates, *t)
+	}
+	counters := []struct {
+		name, help string
+		value      uint64
+	}{
+		{"goprime_errors_total", "Errors detected during the tests.", c.errors},
+		{"goprime_checkpoints_written_total", "Checkpoints written.", c.checkpoints},
+		{"goprime_candidates_completed_total", "Numbers whose test completed.", c.completed},
+		{"goprime_primes_found_total", "Primes found by the completed tests.", c.primes},
+	}
+	c.mu.Unlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].n != candidates[j].n {
+			return candidates[i].n < candidates[j].n
+		}
+		return candidates[i].h < candidates[j].h
+	})
+
+	b := bufio.NewWriter(w)
+	writeHeader(b, "goprime_candidates_running", "gauge", "Numbers being tested.")
+	fmt.Fprintf(b, "goprime_candidates_running %d\n", len(candidates))
+
+	gauges := []struct {
+		name, help string
+		value      func(t *candidate) string
+	}{
+		{"goprime_candidate_iteration", "Last iteration i of U(i) computed by the test of h*2^n-1.",
+			func(t *candidate) string { return fmt.Sprint(t.iteration) }},
+		{"goprime_candidate_n", "Number n of iterations of the test of h*2^n-1.",
+			func(t *candidate) string { return fmt.Sprint(t.n) }},
+		{"goprime_candidate_iterations_per_second", "Iterations per second of the test of h*2^n-1.",
+			func(t *candidate) string { return fmt.Sprint(t.rate) }},
+	}
+	for _, g := range gauges {
+		writeHeader(b, g.name, "gauge", g.help)
+		for i := range candidates {
+			t := &candidates[i]
+			fmt.Fprintf(b, "%v{h=\"%d\",n=\"%d\"} %v\n", g.name, t.h, t.n, g.value(t))
+		}
+	}
+
+	for _, m := range counters {
+		writeHeader(b, m.name, "counter", m.help)
+		fmt.Fprintf(b, "%v %d\n", m.name, m.value)
+	}
+
+	return b.Flush()
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric.
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %v %v\n", name, help)
+	fmt.Fprintf(w, "# TYPE %v %v\n", name, kind)
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteMetrics(w)
+}
+
+// Serve listens on the TCP address addr, such as "localhost:9100", and serves
+// the metrics of c on Path in the background. An error is returned if the
+// address cannot be listened on.
+func (c *Collector) Serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, c)
+	go http.Serve(ln, mux)
+	return nil
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// metrics returns the metrics written by c.
+func metrics(t *testing.T, c *Collector) string {
+	var b bytes.Buffer
+	if err := c.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// expectLines checks that every line is in the metrics.
+func expectLines(t *testing.T, metrics string, lines ...string) {
+	for _, line := range lines {
+		if !strings.Contains(metrics, line + "\n") {
+			t.Errorf("Expected the line %q in the metrics:\n%v", line, metrics)
+		}
+	}
+}
+
+func TestCollectorTests(t *testing.T) {
+	c := NewCollector()
+	opts := rieseltest.DefaultOptions()
+	opts.Monitor = c
+	opts.CheckpointFile = filepath.Join(t.TempDir(), "test.ckpt")
+	opts.CheckpointInterval = 500
+
+	var testCases = []struct {
+		h, n           int64
+		expected_prime bool
+	}{
+		{3, 1274, true},
+		{507, 2005, false},
+		{5, 148, true},
+	}
+
+	for _, tc := range testCases {
+		R, _ := rieseltest.NewRieselNumber(tc.h, tc.n)
+		result, err := rieseltest.Test(R, opts)
+		if err != nil {
+			t.Fatalf("Test(%v) returned an error: %v", R, err)
+		}
+		if result.Prime != tc.expected_prime {
+			t.Errorf("Expected prime = %v for %v, but received %v", tc.expected_prime, R, result.Prime)
+		}
+	}
+
+	// Checkpoints at U(500), U(1000), U(1500) and U(2000)
+	expectLines(t, metrics(t, c),
+		"goprime_candidates_running 0",
+		"goprime_errors_total 0",
+		"goprime_checkpoints_written_total 6",
+		"goprime_candidates_completed_total 3",
+		"goprime_primes_found_total 2")
+}
+
+func TestCollectorProgress(t *testing.T) {
+	c := NewCollector()
+	now := time.Unix(1000000, 0)
+	c.now = func() time.Time { return now }
+
+	R, _ := rieseltest.NewRieselNumber(3, 1274)
+	c.Started(R)
+	c.Iteration(R, 3)
+	c.Iteration(R, 100)
+	now = now.Add(2 * time.Second)
+	c.Iteration(R, 403)
+
+	expectLines(t, metrics(t, c),
+		"goprime_candidates_running 1",
+		"# TYPE goprime_candidate_iteration gauge",
+		`goprime_candidate_iteration{h="3",n="1274"} 403`,
+		`goprime_candidate_n{h="3",n="1274"} 1274`,
+		`goprime_candidate_iterations_per_second{h="3",n="1274"} 200`)
+
+	// An interrupted test is neither running nor completed
+	c.Error(R, errors.New("bad checkpoint"))
+	c.Done(R, nil, rieseltest.ErrInterrupted)
+	expectLines(t, metrics(t, c),
+		"goprime_candidates_running 0",
+		"goprime_errors_total 1",
+		"goprime_candidates_completed_total 0")
+}
+
+func TestCollectorHTTP(t *testing.T) {
+	c := NewCollector()
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the Prometheus text format, but received the content type %q", ct)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectLines(t, string(body), "# TYPE goprime_primes_found_total counter", "goprime_primes_found_total 0")
+}
@@ -0,0 +1,194 @@
+// Package output writes the results of the primality tests as records meant
+// to be read by other programs, rather than by humans.
+//
+// Every tested number gives one Record, written on its own line as soon as
+// its test completes, either as a JSON object:
+//
+//		{"h":3,"n":1274,"digits":384,"verdict":"prime","v1":5,"method":"llr",...}
+//
+// or as a row of tab separated values, after a header line naming the
+// columns. Thus, the results of a batch or a search can be consumed while they
+// are running.
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// Formats of the records.
+const (
+	Text = "text" // human-readable output of goprime, not handled by this package
+	JSON = "json" // one JSON object per line
+	TSV  = "tsv"  // tab separated values, after a header line
+)
+
+// Possible verdicts of a Record.
+const (
+	Prime         = "prime"
+	ProbablePrime = "probable-prime"
+	Composite     = "composite"
+	Error         = "error"
+)
+
+// Methods by which the verdict of a Record was reached.
+const (
+	MethodLLR            = "llr"             // Lucas-Lehmer-Riesel test
+	MethodLucasLehmer    = "lucas-lehmer"    // Lucas-Lehmer test of a Mersenne number
+	MethodScreening      = "screening"       // prime < 257, or factor < 257
+	MethodTrialFactoring = "trial-factoring" // factor of a Mersenne number
+	MethodKnown          = "known"           // database of the known primes
+	MethodFermat         = "fermat"          // Fermat probable prime test
+)
+
+// A Record describes the test of a number h*2^n-1.
+type Record struct {
+	H       int64  `json:"h"`
+	N       int64  `json:"n"`
+	Digits  int64  `json:"digits"`
+	Verdict string `json:"verdict"`
+
+	// Residue is the RES64 of the test in hexadecimal. It is empty when the
+	// verdict was not reached by a test, or when the test failed.
+	Residue string `json:"residue,omitempty"`
+	V1      int64  `json:"v1,omitempty"`
+	Method  string `json:"method,omitempty"`
+
+	// Time spent generating V(1), U(2) and U(n), and their sum, in seconds.
+	V1Seconds    float64 `json:"v1_seconds"`
+	U2Seconds    float64 `json:"u2_seconds"`
+	UNSeconds    float64 `json:"un_seconds"`
+	TotalSeconds float64 `json:"total_seconds"`
+
+	// Error describes why the test failed, when Verdict is Error.
+	Error string `json:"error,omitempty"`
+}
+
+// FromResult returns the record of a Lucas-Lehmer-Riesel test.
+func FromResult(result *rieseltest.Result) *Record {
+	r := newRecord(result.R)
+	r.Verdict = Composite
+	if result.Prime {
+		r.Verdict = Prime
+	}
+	r.V1 = result.V1
+	r.V1Seconds = result.V1Time.Seconds()
+	r.U2Seconds = result.U2Time.Seconds()
+	r.UNSeconds = result.UNTime.Seconds()
+	r.TotalSeconds = (result.V1Time + result.U2Time + result.UNTime).Seconds()
+
+	h, _ := result.R.Params()
+	switch {
+	case result.Known:
+		r.Method = MethodKnown
+	case result.Factor != nil:
+		r.Method = MethodTrialFactoring
+	case result.Screened:
+		r.Method = MethodScreening
+	case h == 1:
+		r.Method = MethodLucasLehmer
+		r.Residue = result.ResidueString()
+	default:
+		r.Method = MethodLLR
+		r.Residue = result.ResidueString()
+	}
+
+	return r
+}
+
+// FromPRP returns the record of a Fermat probable prime test.
+func FromPRP(R *rieseltest.RieselNumber, prp bool, residue uint64) *Record {
+	r := newRecord(R)
+	r.Verdict = Composite
+	if prp {
+		r.Verdict = ProbablePrime
+	}
+	r.Method = MethodFermat
+	r.Residue = fmt.Sprintf("%016X", residue)
+	return r
+}
+
+// FromError returns the record of a test of R which failed with err.
+func FromError(R *rieseltest.RieselNumber, err error) *Record {
+	r := newRecord(R)
+	r.Verdict = Error
+	r.Error = err.Error()
+	return r
+}
+
+// newRecord returns a record with the h, n and digits of R.
+func newRecord(R *rieseltest.RieselNumber) *Record {
+	h, n := R.Params()
+	return &Record{H: h, N: n, Digits: R.Digits()}
+}
+
+// columns are the names of the TSV columns, in the order of tsvRow.
+var columns = []string{"h", "n", "digits", "verdict", "residue", "v1", "method", "v1_seconds", "u2_seconds",
+	"un_seconds", "total_seconds", "error"}
+
+// tsvRow returns the values of the TSV columns of the record.
+func (r *Record) tsvRow() []string {
+	seconds := func(s float64) string {
+		return strconv.FormatFloat(s, 'f', 6, 64)
+	}
+
+	// Keep the error on a single field
+	e := strings.Join(strings.Fields(r.Error), " ")
+
+	return []string{strconv.FormatInt(r.H, 10), strconv.FormatInt(r.N, 10), strconv.FormatInt(r.Digits, 10),
+		r.Verdict, r.Residue, strconv.FormatInt(r.V1, 10), r.Method, seconds(r.V1Seconds), seconds(r.U2Seconds),
+		seconds(r.UNSeconds), seconds(r.TotalSeconds), e}
+}
+
+// A Writer writes records in a given format. It can be used by several
+// goroutines at once.
+type Writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+	header bool
+}
+
+// NewWriter returns a Writer writing to w in the given format, JSON or TSV.
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	if format != JSON && format != TSV {
+		return nil, errors.New(fmt.Sprintf("Unknown output format %v, expected %v or %v", format, JSON, TSV))
+	}
+
+	return &Writer{w: w, format: format}, nil
+}
+
+// Write writes the record on its own line. With the TSV format, the first
+// call also writes the header line.
+func (w *Writer) Write(r *Record) error {
+	var line []byte
+	if w.format == JSON {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		line = append(data, '\n')
+	} else {
+		line = []byte(strings.Join(r.tsvRow(), "\t") + "\n")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.format == TSV && !w.header {
+		if _, err := io.WriteString(w.w, strings.Join(columns, "\t") + "\n"); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	_, err := w.w.Write(line)
+	return err
+}
@@ -0,0 +1,120 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/arcetri/goprime/rieseltest"
+	"github.com/arcetri/goprime/rieseltest/knownprimes"
+)
+
+func TestFromResult(t *testing.T) {
+	db := knownprimes.New()
+	db.Add(3, 1274)
+
+	var testCases = []struct {
+		h, n             int64
+		known            *knownprimes.DB
+		expected_verdict string
+		expected_method  string
+		expected_residue string
+		expected_v1      int64
+		expected_digits  int64
+	}{
+		{3, 1274, nil, Prime, MethodLLR, "0000000000000000", 5, 384},
+		{3, 1274, db, Prime, MethodKnown, "", 0, 384},
+		{507, 2005, nil, Composite, MethodLLR, "B888A8E4F7CA0445", 0, 607},
+		{1, 127, nil, Prime, MethodLucasLehmer, "0000000000000000", 4, 39},
+		{9, 10, nil, Composite, MethodScreening, "", 0, 4},
+	}
+
+	for _, c := range testCases {
+		R, _ := rieseltest.NewRieselNumber(c.h, c.n)
+		opts := rieseltest.DefaultOptions()
+		opts.KnownPrimes = c.known
+		result, err := rieseltest.Test(R, opts)
+		if err != nil {
+			t.Fatalf("Test(%v) returned an error: %v", R, err)
+		}
+
+		r := FromResult(result)
+		if r.H != c.h || r.N != c.n || r.Digits != c.expected_digits || r.Verdict != c.expected_verdict ||
+			r.Method != c.expected_method || r.Residue != c.expected_residue ||
+			(c.expected_v1 != 0 && r.V1 != c.expected_v1) {
+			t.Errorf("FromResult of %v == %+v, but we expected the verdict %v by %v, residue %q, V(1) = %v and " +
+				"%v digits", R, r, c.expected_verdict, c.expected_method, c.expected_residue, c.expected_v1,
+				c.expected_digits)
+		}
+	}
+}
+
+func TestFromPRPAndError(t *testing.T) {
+	R, _ := rieseltest.NewRieselNumber(3, 1274)
+
+	r := FromPRP(R, true, 1)
+	if r.Verdict != ProbablePrime || r.Method != MethodFermat || r.Residue != "0000000000000001" {
+		t.Errorf("FromPRP(%v, true, 1) == %+v", R, r)
+	}
+
+	r = FromError(R, errors.New("failed"))
+	if r.Verdict != Error || r.Error != "failed" || r.Residue != "" || r.H != 3 || r.N != 1274 {
+		t.Errorf("FromError(%v, failed) == %+v", R, r)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	R, _ := rieseltest.NewRieselNumber(3, 1274)
+	records := []*Record{FromPRP(R, true, 1), FromError(R, errors.New("a\tmulti-line\nerror"))}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, JSON)
+	if err != nil {
+		t.Fatalf("NewWriter(%v) returned an error: %v", JSON, err)
+	}
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write returned an error: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(records) {
+		t.Fatalf("The JSON writer wrote %v lines, but we expected %v", len(lines), len(records))
+	}
+	for i, line := range lines {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("Could not decode the JSON record %q: %v", line, err)
+		} else if r != *records[i] {
+			t.Errorf("The JSON record %q was decoded as %+v, but we expected %+v", line, r, records[i])
+		}
+	}
+
+	buf.Reset()
+	if w, err = NewWriter(&buf, TSV); err != nil {
+		t.Fatalf("NewWriter(%v) returned an error: %v", TSV, err)
+	}
+	for _, r := range records {
+		w.Write(r)
+	}
+
+	lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(records) + 1 || lines[0] != strings.Join(columns, "\t") {
+		t.Fatalf("The TSV writer wrote %q, but we expected a header and %v rows", buf.String(), len(records))
+	}
+	for _, line := range lines[1:] {
+		if fields := strings.Split(line, "\t"); len(fields) != len(columns) {
+			t.Errorf("The TSV row %q has %v fields, but we expected %v", line, len(fields), len(columns))
+		}
+	}
+	if !strings.HasSuffix(lines[2], "\ta multi-line error") {
+		t.Errorf("The TSV row %q does not end with the error on a single field", lines[2])
+	}
+
+	if _, err := NewWriter(&buf, Text); err == nil {
+		t.Errorf("NewWriter(%v) did not return an error", Text)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/arcetri/goprime/output"
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// runPRP implements the "goprime prp" command, which performs a Fermat
+// probable prime test of a number.
+func runPRP(args []string) error {
+	fs := flag.NewFlagSet("prp", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime prp [-base b] [-output text|json|tsv] h n\n")
+		fmt.Print("  goprime prp [-base b] [-output text|json|tsv] expression\n\n")
+		fmt.Print("Performs a Fermat probable prime test of h*2^n-1 in base b, which cross-checks the\n")
+		fmt.Print("Lucas-Lehmer-Riesel test with an independent algorithm, but cannot prove that N is prime.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	basePtr := fs.Int64("base", rieseltest.DefaultPRPBase, "Base of the Fermat test, 2 <= b <= 256.")
+	configureLogger := loggerFlags(fs)
+	openOutput := outputFlag(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if fs.NArg() != 1 && fs.NArg() != 2 {
+		return usageError(fs, "")
+	}
+	if *basePtr < 2 || *basePtr > 256 {
+		return usageError(fs, "Expected 2 <= base <= 256.")
+	}
+	out, err := openOutput()
+	if err != nil {
+		return usageError(fs, err.Error())
+	}
+
+	R, err := parseCandidate(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	prp, residue, err := rieseltest.FermatPRP(R, *basePtr, handleSignals())
+	if err != nil {
+		if out != nil && err != rieseltest.ErrInterrupted {
+			out.Write(output.FromError(R, err))
+		}
+		return err
+	}
+
+	if out != nil {
+		return out.Write(output.FromPRP(R, prp, residue))
+	}
+	if prp {
+		fmt.Printf("%v is a probable prime (base %v)\n", R, *basePtr)
+	} else {
+		fmt.Printf("%v is composite (base %v RES64 %016X)\n", R, *basePtr, residue)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/arcetri/goprime/ledger"
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// appendResult appends the given result to the ledger, if it is not nil.
+func appendResult(l *ledger.Ledger, result *rieseltest.Result) error {
+	if l == nil {
+		return nil
+	}
+
+	return l.Append(ledger.FromResult(result))
+}
+
+// runResults implements the "goprime results" command, which queries the ledger.
+func runResults(args []string) error {
+	if len(args) == 0 || args[0] != "query" {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime results query -ledger file [flags]\n")
+		return errUsage
+	}
+
+	fs := flag.NewFlagSet("results query", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime results query -ledger file [-h h] [-nmin n] [-nmax n] [-primes] [-verified] [-gaps] [-csv]\n\n")
+		fmt.Print("Lists the results of the ledger selected by the flags.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	ledgerPtr := fs.String("ledger", "", "Ledger to query.")
+	hPtr := fs.Int64("h", 0, "Only list the results of this h.")
+	nminPtr := fs.Int64("nmin", 0, "Only list the results with n >= nmin.")
+	nmaxPtr := fs.Int64("nmax", 0, "Only list the results with n <= nmax.")
+	primesPtr := fs.Bool("primes", false, "Only list the primes found.")
+	verifiedPtr := fs.Bool("verified", false, "Only list the results verified by a double check.")
+	gapsPtr := fs.Bool("gaps", false, "List the ranges of n neither tested nor eliminated by a search yet " +
+		"for h between nmin and nmax, instead of the results.")
+	csvPtr := fs.Bool("csv", false, "Export the results in CSV format.")
+	fs.Parse(args[1:])
+
+	if *ledgerPtr == "" {
+		return usageError(fs, "Expected the ledger to query.")
+	}
+
+	q := &ledger.Query{H: *hPtr, NMin: *nminPtr, NMax: *nmaxPtr, Primes: *primesPtr, Verified: *verifiedPtr}
+	records, err := ledger.Select(*ledgerPtr, q)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *gapsPtr:
+		if q.H == 0 || q.NMin == 0 || q.NMax == 0 {
+			return errors.New("-gaps requires -h, -nmin and -nmax")
+		}
+
+		for _, gap := range ledger.Gaps(records, q.H, q.NMin, q.NMax) {
+			fmt.Printf("%v %v\n", gap[0], gap[1])
+		}
+
+	case *csvPtr:
+		return ledger.WriteCSV(os.Stdout, records)
+
+	default:
+		for _, r := range records {
+			fmt.Printf("%v * 2^%v - 1: %v %v\n", r.H, r.N, r.Verdict, r.Residue)
+		}
+	}
+
+	return nil
+}
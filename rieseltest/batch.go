@@ -0,0 +1,49 @@
+package rieseltest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ScanBatch reads a batch of Riesel numbers from r, calling fn for each of
+// them in order. If fn returns an error, the scan stops and that error is returned.
+//
+// Each non-empty line of a batch must contain the h and n of a number, separated
+// by spaces. Lines starting with '#' are ignored.
+func ScanBatch(r io.Reader, fn func(R *RieselNumber) error) error {
+	s := bufio.NewScanner(r)
+
+	for line := 1; s.Scan(); line++ {
+		words := strings.Fields(s.Text())
+		if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+			continue
+		}
+		if len(words) != 2 {
+			return errors.New(fmt.Sprintf("Line %v: expected \"h n\", but found %q", line, s.Text()))
+		}
+
+		h, err := strconv.ParseInt(words[0], 10, 64)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Line %v: %v", line, err))
+		}
+		n, err := strconv.ParseInt(words[1], 10, 64)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Line %v: %v", line, err))
+		}
+
+		R, err := NewRieselNumber(h, n)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Line %v: %v", line, err))
+		}
+
+		if err = fn(R); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
@@ -0,0 +1,48 @@
+package rieseltest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScanBatch(t *testing.T) {
+	batch := "# comment\n15 5\n\n  6 152 \n# 7 8\n507 2005\n"
+	expected := [][2]int64{{15, 5}, {3, 153}, {507, 2005}}
+
+	var actual [][2]int64
+	err := ScanBatch(strings.NewReader(batch), func(R *RieselNumber) error {
+		actual = append(actual, [2]int64{R.h, R.n})
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("ScanBatch returned an error: %v", err)
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("ScanBatch read %v, but we expected %v", actual, expected)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("ScanBatch read %v, but we expected %v", actual, expected)
+			break
+		}
+	}
+}
+
+func TestScanBatchErrors(t *testing.T) {
+	var testCases = []string{
+		"15\n",
+		"15 5 3\n",
+		"a 5\n",
+		"15 b\n",
+		"0 5\n",
+		"15 1\n",
+	}
+
+	for _, c := range testCases {
+		err := ScanBatch(strings.NewReader(c), func(R *RieselNumber) error { return nil })
+		if err == nil {
+			t.Errorf("ScanBatch(%q) should return an error, but it didn't", c)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package rieseltest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// certificateHeader is the first line of every certificate file. The trailing
+// number is the version of the format.
+const certificateHeader = "goprime certificate 1"
+
+// An InterimHash is the SHA-256 hash of U(Iteration) mod N, taken while the
+// Lucas-Lehmer-Riesel test was running.
+type InterimHash struct {
+	Iteration int64
+	Hash      string // hex encoded SHA-256 of the big-endian bytes of U(i) mod N
+}
+
+// A Certificate records the proof that the Riesel number H*2^N-1 is prime.
+//
+// The V(1) used by the test is justified by the Jacobi symbols of the Rödseth
+// criterion, Jacobi(V(1)-2, N) == 1 and Jacobi(V(1)+2, N) == -1, or by the
+// Riesel criterion when h mod 3 != 0 and V(1) == 4. The interim hashes allow
+// a replay of the test to be compared with the original run.
+type Certificate struct {
+	H           int64
+	N           int64
+	V1          int64
+	JacobiMinus int // Jacobi(V(1)-2, N)
+	JacobiPlus  int // Jacobi(V(1)+2, N)
+	Hashes      []InterimHash
+}
+
+// FileName returns the conventional name of the certificate file, "h-n.cert".
+func (c *Certificate) FileName() string {
+	return fmt.Sprintf("%d-%d.cert", c.H, c.N)
+}
+
+// isHashedIteration returns true if U(i) is hashed in the certificate of a
+// Riesel number with the given n: U(i) is hashed when i is a power of two,
+// and for the last term U(n-1) before the final U(n) == 0.
+func isHashedIteration(i, n int64) bool {
+	return i&(i-1) == 0 || i == n-1
+}
+
+// hashResidue returns the InterimHash of U(i) mod N.
+func hashResidue(i int64, u *big.Int) InterimHash {
+	sum := sha256.Sum256(u.Bytes())
+	return InterimHash{Iteration: i, Hash: hex.EncodeToString(sum[:])}
+}
+
+// newCertificate builds the certificate of the prime R from the given V(1)
+// and interim hashes.
+//
+// If the V(1) can not be justified by either criterion, an error is returned.
+func newCertificate(R *RieselNumber, v1 int64, hashes []InterimHash) (*Certificate, error) {
+	proof, err := NewV1Proof(R, v1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Certificate{H: R.h, N: R.n, V1: v1, JacobiMinus: proof.JacobiMinus, JacobiPlus: proof.JacobiPlus,
+		Hashes: hashes}, nil
+}
+
+// checkV1 verifies that the V(1) of the certificate is justified, through
+// its V1Proof.
+func (c *Certificate) checkV1() error {
+	proof := &V1Proof{H: c.H, N: c.N, V1: c.V1, JacobiMinus: c.JacobiMinus, JacobiPlus: c.JacobiPlus}
+	return proof.Verify()
+}
+
+// Verify checks the certificate.
+//
+// The V(1) conditions are always checked, which is cheap. If replay is true,
+// the test is also performed again from V(1), checking that every interim
+// hash matches and that U(n) == 0 (mod N), which costs as much as the
+// original test. Only a replay proves that N is prime: without it, a nil error
+// only means that V(1) satisfies the conditions of the test.
+func (c *Certificate) Verify(replay bool) error {
+	if c.H < 1 || c.H%2 == 0 || c.N < 2 {
+		return errors.New(fmt.Sprintf("Expected odd h >= 1 and n >= 2, but the certificate has h = %v and n = %v",
+			c.H, c.N))
+	}
+
+	if err := c.checkV1(); err != nil {
+		return err
+	}
+	if !replay {
+		return nil
+	}
+
+	R, err := NewRieselNumber(c.H, c.N)
+	if err != nil {
+		return err
+	}
+
+	expected := make(map[int64]string)
+	for _, h := range c.Hashes {
+		expected[h.Iteration] = h.Hash
+	}
+
+	u, err := GenU2(R, c.V1)
+	if err != nil {
+		return err
+	}
+
+	step := func(i int64, u *big.Int) error {
+		hash, ok := expected[i]
+		if !ok {
+			return nil
+		}
+		if actual := hashResidue(i, u); actual.Hash != hash {
+			return errors.New(fmt.Sprintf("The hash of U(%v) is %v, but the certificate has %v", i, actual.Hash, hash))
+		}
+		return nil
+	}
+
+	uN, err := genUNFrom(R, u, 3, step)
+	if err != nil {
+		return err
+	}
+	if uN.Sign() != 0 {
+		return errors.New(fmt.Sprintf("U(n) != 0 (mod N): %v is not prime", R))
+	}
+
+	return nil
+}
+
+// WriteCertificate writes the given certificate to the file at path.
+//
+// As for checkpoints, the certificate is first written to a temporary file
+// which then replaces the old one.
+//
+// The file has the following plain text format:
+//
+//		goprime certificate 1
+//		h <h>
+//		n <n>
+//		v1 <V(1)>
+//		jacobi-minus <Jacobi(V(1)-2, N)>
+//		jacobi-plus <Jacobi(V(1)+2, N)>
+//		hash <i> <SHA-256 of U(i) mod N>
+//		...
+func WriteCertificate(path string, c *Certificate) error {
+
+	// Check preconditions
+	if c == nil {
+		return errors.New("Received an empty certificate")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, certificateHeader)
+	fmt.Fprintln(&buf, "h", c.H)
+	fmt.Fprintln(&buf, "n", c.N)
+	fmt.Fprintln(&buf, "v1", c.V1)
+	fmt.Fprintln(&buf, "jacobi-minus", c.JacobiMinus)
+	fmt.Fprintln(&buf, "jacobi-plus", c.JacobiPlus)
+	for _, h := range c.Hashes {
+		fmt.Fprintln(&buf, "hash", h.Iteration, h.Hash)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(buf.Bytes())
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// LoadCertificate reads the certificate saved in the file at path.
+//
+// Only the format of the file is checked: use Verify to check the certificate.
+func LoadCertificate(path string) (*Certificate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	invalid := func(reason string) error {
+		return errors.New(fmt.Sprintf("%v is not a valid certificate file: %v", path, reason))
+	}
+
+	s := bufio.NewScanner(file)
+	if !s.Scan() || strings.TrimSpace(s.Text()) != certificateHeader {
+		if err = s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, invalid("missing header")
+	}
+
+	c := new(Certificate)
+	seen := make(map[string]bool)
+	for s.Scan() {
+		words := strings.Fields(s.Text())
+		if len(words) == 0 {
+			continue
+		}
+
+		key := words[0]
+		if key != "hash" && seen[key] {
+			return nil, invalid(fmt.Sprintf("duplicate %q", key))
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "h":
+			_, err = fmt.Sscan(strings.Join(words[1:], " "), &c.H)
+		case "n":
+			_, err = fmt.Sscan(strings.Join(words[1:], " "), &c.N)
+		case "v1":
+			_, err = fmt.Sscan(strings.Join(words[1:], " "), &c.V1)
+		case "jacobi-minus":
+			_, err = fmt.Sscan(strings.Join(words[1:], " "), &c.JacobiMinus)
+		case "jacobi-plus":
+			_, err = fmt.Sscan(strings.Join(words[1:], " "), &c.JacobiPlus)
+		case "hash":
+			var h InterimHash
+			if len(words) != 3 {
+				return nil, invalid(fmt.Sprintf("bad line %q", s.Text()))
+			}
+			if _, err = fmt.Sscan(words[1], &h.Iteration); err == nil {
+				_, err = hex.DecodeString(words[2])
+			}
+			h.Hash = strings.ToLower(words[2])
+			c.Hashes = append(c.Hashes, h)
+		default:
+			return nil, invalid(fmt.Sprintf("unknown key %q", key))
+		}
+		if err != nil {
+			return nil, invalid(fmt.Sprintf("bad line %q", s.Text()))
+		}
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"h", "n", "v1", "jacobi-minus", "jacobi-plus"} {
+		if !seen[key] {
+			return nil, invalid(fmt.Sprintf("missing %q", key))
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,159 @@
+package rieseltest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+func TestCertificate(t *testing.T) {
+	var testCases = []struct {
+		h, n int64
+		v1   int64
+	}{
+		{8565, 15, 9},
+		{5, 148, 4},
+		{3, 1274, 5},
+	}
+
+	dir := t.TempDir()
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		result, err := Test(R, nil)
+		if err != nil || result.Certificate == nil {
+			t.Fatalf("Test(%v) should return a certificate, but we got %+v, %v", R, result, err)
+		}
+
+		cert := result.Certificate
+		if cert.H != c.h || cert.N != c.n || cert.V1 != c.v1 {
+			t.Errorf("The certificate of %v has h = %v, n = %v, V(1) = %v, but we expected %v, %v, %v",
+				R, cert.H, cert.N, cert.V1, c.h, c.n, c.v1)
+		}
+
+		path := filepath.Join(dir, "test.cert")
+		if err := WriteCertificate(path, cert); err != nil {
+			t.Fatalf("WriteCertificate returned an error: %v", err)
+		}
+
+		loaded, err := LoadCertificate(path)
+		if err != nil {
+			t.Fatalf("LoadCertificate returned an error: %v", err)
+		}
+		if !reflect.DeepEqual(loaded, cert) {
+			t.Errorf("LoadCertificate returned %+v, but we expected %+v", loaded, cert)
+		}
+
+		if err := loaded.Verify(true); err != nil {
+			t.Errorf("The certificate of %v should be valid, but Verify returned: %v", R, err)
+		}
+	}
+}
+
+func TestCertificateOfComposite(t *testing.T) {
+	R, _ := NewRieselNumber(507, 2005)
+	if result, err := Test(R, nil); err != nil || result.Certificate != nil {
+		t.Errorf("Test(%v) should not return a certificate, but we got %+v, %v", R, result, err)
+	}
+}
+
+func TestCertificateAfterResume(t *testing.T) {
+	R, _ := NewRieselNumber(3, 1274)
+	reference, _ := Test(R, nil)
+
+	path := filepath.Join(t.TempDir(), "cert.ckpt")
+
+	// Save a checkpoint at U(1000) with the hashes computed so far
+	var hashes []InterimHash
+	u, _ := GenU2(R, reference.V1)
+	genUNFrom(R, u, 3, func(i int64, u *big.Int) error {
+		if isHashedIteration(i, R.n) {
+			hashes = append(hashes, hashResidue(i, u))
+		}
+		if i == 1000 {
+			SaveCheckpoint(path, &Checkpoint{H: R.h, N: R.n, V1: reference.V1, Iteration: i, U: u, Hashes: hashes})
+			return ErrInterrupted
+		}
+		return nil
+	})
+
+	opts := DefaultOptions()
+	opts.CheckpointFile = path
+	result, err := Test(R, opts)
+	if err != nil || result.ResumedFrom == 0 {
+		t.Fatalf("Test(%v) should resume from a checkpoint, but we got %+v, %v", R, result, err)
+	}
+
+	if !reflect.DeepEqual(result.Certificate, reference.Certificate) {
+		t.Errorf("The certificate of the resumed test is %+v, but we expected %+v",
+			result.Certificate, reference.Certificate)
+	}
+}
+
+func TestVerifyCertificateErrors(t *testing.T) {
+	R, _ := NewRieselNumber(3, 1274)
+	result, _ := Test(R, nil)
+
+	var testCases = []struct {
+		name   string
+		tamper func(c *Certificate)
+		replay bool
+	}{
+		{"even h", func(c *Certificate) { c.H = 4 }, false},
+		{"wrong V(1)", func(c *Certificate) { c.V1 = 3 }, false},
+		{"wrong Jacobi symbol", func(c *Certificate) { c.JacobiPlus = 1 }, false},
+		{"wrong n", func(c *Certificate) { c.N = 1275 }, true},
+		{"wrong hash", func(c *Certificate) { c.Hashes[3].Hash = c.Hashes[4].Hash }, true},
+	}
+
+	for _, c := range testCases {
+		cert := *result.Certificate
+		cert.Hashes = append([]InterimHash(nil), result.Certificate.Hashes...)
+		c.tamper(&cert)
+
+		if err := cert.Verify(c.replay); err == nil {
+			t.Errorf("Verify should fail for a certificate with %v, but it didn't", c.name)
+		}
+	}
+}
+
+func TestVerifyCertificateOfComposite(t *testing.T) {
+
+	// The V(1) of the certificate satisfies the conditions of the test of the
+	// composite 5*2^6-1 = 319, so only the replay can reject it
+	cert := &Certificate{H: 5, N: 6, V1: 4, JacobiMinus: 1, JacobiPlus: -1}
+	if err := cert.Verify(false); err != nil {
+		t.Errorf("Verify(false) should only check V(1) = 4, but it returned: %v", err)
+	}
+	if err := cert.Verify(true); err == nil {
+		t.Errorf("Verify(true) should fail for the composite %v * 2^%v - 1, but it didn't", cert.H, cert.N)
+	}
+}
+
+func TestLoadCertificateErrors(t *testing.T) {
+	var testCases = []string{
+		"",
+		"goprime certificate 2\nh 3\nn 10\nv1 3\njacobi-minus 1\njacobi-plus -1\n",
+		"goprime certificate 1\nh 3\nn 10\nv1 3\njacobi-minus 1\n",
+		"goprime certificate 1\nh 3\nh 3\nn 10\nv1 3\njacobi-minus 1\njacobi-plus -1\n",
+		"goprime certificate 1\nh 3\nn 10\nv1 x\njacobi-minus 1\njacobi-plus -1\n",
+		"goprime certificate 1\nh 3\nn 10\nv1 3\njacobi-minus 1\njacobi-plus -1\nhash 4 zz\n",
+		"goprime certificate 1\nh 3\nn 10\nv1 3\njacobi-minus 1\njacobi-plus -1\nfoo 1\n",
+	}
+
+	dir := t.TempDir()
+	for i, c := range testCases {
+		path := filepath.Join(dir, "bad.cert")
+		if err := os.WriteFile(path, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadCertificate(path); err == nil {
+			t.Errorf("LoadCertificate should return an error for test case %v, but it didn't", i)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package rieseltest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// checkpointHeader is the first line of every checkpoint file. The trailing
+// number is the version of the format.
+const checkpointHeader = "goprime checkpoint 1"
+
+// A Checkpoint represents the state of GenUN for the Riesel number H*2^N-1
+// after the term U(Iteration) has been computed.
+type Checkpoint struct {
+	H         int64
+	N         int64
+	V1        int64    // V(1) used to generate U(2)
+	Iteration int64    // index i of the last computed U(i)
+	U         *big.Int // U(i) mod N
+
+	// Hashes of the interim residues computed so far, for the certificate.
+	Hashes []InterimHash
+
+	// Interim residues recorded so far, for the Result.
+	Interim []InterimResidue
+}
+
+// Residue returns the RES64 of the U(i) of the checkpoint.
+func (c *Checkpoint) Residue() InterimResidue {
+	return InterimResidue{Iteration: c.Iteration, Residue: residue64(c.U)}
+}
+
+// SaveCheckpoint writes the given checkpoint to the file at path.
+//
+// The checkpoint is first written to a temporary file which then replaces
+// the old one, so that an interruption never leaves a truncated checkpoint.
+//
+// The file has the following plain text format:
+//
+//		goprime checkpoint 1
+//		h n v1 i
+//		U(i) mod N in hexadecimal
+//		hash j SHA-256 of U(j) mod N, for each interim hash
+//		res64 j RES64 of U(j) mod N, for each interim residue
+func SaveCheckpoint(path string, c *Checkpoint) error {
+
+	// Check preconditions
+	if c == nil || c.U == nil {
+		return errors.New("Received an empty checkpoint")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	fmt.Fprintln(w, checkpointHeader)
+	fmt.Fprintln(w, c.H, c.N, c.V1, c.Iteration)
+	fmt.Fprintln(w, c.U.Text(16))
+	for _, h := range c.Hashes {
+		fmt.Fprintln(w, "hash", h.Iteration, h.Hash)
+	}
+	for _, r := range c.Interim {
+		fmt.Fprintln(w, "res64", r.Iteration, r.ResidueString())
+	}
+
+	if err = w.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	logCheckpoint.Debugf("Saved checkpoint of %v * 2^%v - 1 at U(%v) to %v", c.H, c.N, iterationField(c.Iteration), path)
+	return nil
+}
+
+// LoadCheckpoint reads the checkpoint saved in the file at path.
+//
+// If the file does not exist, the returned error satisfies os.IsNotExist.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	s := bufio.NewScanner(file)
+	s.Buffer(nil, 1<<30)
+
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, strings.TrimSpace(s.Text()))
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) < 3 || lines[0] != checkpointHeader {
+		return nil, errors.New(fmt.Sprintf("%v is not a valid checkpoint file", path))
+	}
+
+	c := new(Checkpoint)
+	if _, err = fmt.Sscan(lines[1], &c.H, &c.N, &c.V1, &c.Iteration); err != nil {
+		return nil, errors.New(fmt.Sprintf("%v is not a valid checkpoint file: %v", path, err))
+	}
+
+	u, ok := new(big.Int).SetString(lines[2], 16)
+	if !ok || u.Sign() < 0 {
+		return nil, errors.New(fmt.Sprintf("%v is not a valid checkpoint file: bad U(%v)", path, c.Iteration))
+	}
+	c.U = u
+
+	for _, line := range lines[3:] {
+		var key, value string
+		var i int64
+		_, err = fmt.Sscan(line, &key, &i, &value)
+
+		switch {
+		case err != nil || i > c.Iteration:
+			err = errors.New("bad line")
+		case key == "hash":
+			c.Hashes = append(c.Hashes, InterimHash{Iteration: i, Hash: value})
+		case key == "res64":
+			var r uint64
+			if r, err = strconv.ParseUint(value, 16, 64); err == nil {
+				c.Interim = append(c.Interim, InterimResidue{Iteration: i, Residue: r})
+			}
+		default:
+			err = errors.New("bad line")
+		}
+
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("%v is not a valid checkpoint file: bad line %q", path, line))
+		}
+	}
+
+	if c.H < 1 || c.H%2 == 0 || c.N < 2 || c.Iteration < 2 || c.Iteration > c.N {
+		return nil, errors.New(fmt.Sprintf("%v is not a valid checkpoint file: inconsistent values", path))
+	}
+
+	return c, nil
+}
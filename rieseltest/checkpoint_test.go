@@ -0,0 +1,133 @@
+package rieseltest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.ckpt")
+	expected := &Checkpoint{H: 507, N: 2005, V1: 5, Iteration: 1000, U: big.NewInt(0).Lsh(big.NewInt(12345), 200),
+		Hashes: []InterimHash{{Iteration: 512, Hash: "ab01"}}, Interim: []InterimResidue{{Iteration: 500, Residue: 42}}}
+
+	if err := SaveCheckpoint(path, expected); err != nil {
+		t.Fatalf("SaveCheckpoint returned an error: %v", err)
+	}
+
+	actual, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint returned an error: %v", err)
+	}
+
+	if actual.H != expected.H || actual.N != expected.N || actual.V1 != expected.V1 ||
+		actual.Iteration != expected.Iteration || actual.U.Cmp(expected.U) != 0 ||
+		!reflect.DeepEqual(actual.Hashes, expected.Hashes) || !reflect.DeepEqual(actual.Interim, expected.Interim) {
+		t.Errorf("LoadCheckpoint returned %+v, but we expected %+v", actual, expected)
+	}
+}
+
+func TestLoadCheckpointErrors(t *testing.T) {
+	var testCases = []string{
+		"",
+		"goprime checkpoint 1\n",
+		"goprime checkpoint 2\n3 10 5 4\nff\n",
+		"goprime checkpoint 1\n3 10 5\nff\n",
+		"goprime checkpoint 1\n3 10 5 4\nzz\n",
+		"goprime checkpoint 1\n4 10 5 4\nff\n",
+		"goprime checkpoint 1\n3 10 5 11\nff\n",
+		"goprime checkpoint 1\n3 10 5 4\nff\nres64 4 xyz\n",
+		"goprime checkpoint 1\n3 10 5 4\nff\nhash 8 ab01\n",
+		"goprime checkpoint 1\n3 10 5 4\nff\nfoo 4 ab01\n",
+	}
+
+	dir := t.TempDir()
+	for i, c := range testCases {
+		path := filepath.Join(dir, "bad.ckpt")
+		if err := os.WriteFile(path, []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadCheckpoint(path); err == nil {
+			t.Errorf("LoadCheckpoint should return an error for test case %v, but it didn't", i)
+		}
+	}
+
+	if _, err := LoadCheckpoint(filepath.Join(dir, "missing.ckpt")); !os.IsNotExist(err) {
+		t.Errorf("LoadCheckpoint of a missing file returned %v, but we expected a not exist error", err)
+	}
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	var testCases = []struct {
+		h, n     int64
+		resumeAt int64
+		expected bool
+	}{
+		{8565, 15, 10, true},
+		{507, 2005, 1000, false},
+		{3, 1274, 1000, true},
+	}
+
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		path := filepath.Join(t.TempDir(), "resume.ckpt")
+
+		// Compute U(resumeAt) and save it as a checkpoint
+		v1, _ := GenV1(R, RODSETH)
+		u, _ := GenU2(R, v1)
+		u, _ = genUNFrom(R, u, 3, func(i int64, u *big.Int) error {
+			if i == c.resumeAt {
+				return SaveCheckpoint(path, &Checkpoint{H: R.h, N: R.n, V1: v1, Iteration: i, U: u})
+			}
+			return nil
+		})
+
+		reference, _ := Test(R, nil)
+
+		opts := DefaultOptions()
+		opts.CheckpointFile = path
+		actual, err := Test(R, opts)
+
+		if err != nil {
+			t.Errorf("Test(%v) returned an error: %v", R, err)
+		} else if actual.Prime != c.expected || actual.Residue != reference.Residue || actual.ResumedFrom != c.resumeAt {
+			t.Errorf("Test(%v) resumed from U(%v) returned prime = %v with residue %v, but we expected "+
+				"prime = %v with residue %v resumed from U(%v)", R, actual.ResumedFrom, actual.Prime,
+				actual.ResidueString(), c.expected, reference.ResidueString(), c.resumeAt)
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Test(%v) should remove the checkpoint once complete, but it didn't", R)
+		}
+	}
+}
+
+func TestStopSavesCheckpoint(t *testing.T) {
+	R, _ := NewRieselNumber(3, 1274)
+	stop := make(chan struct{})
+	close(stop)
+
+	opts := DefaultOptions()
+	opts.CheckpointFile = filepath.Join(t.TempDir(), "stop.ckpt")
+	opts.Stop = stop
+
+	if _, err := Test(R, opts); err != ErrInterrupted {
+		t.Fatalf("Test(%v) with a closed Stop returned %v, but we expected ErrInterrupted", R, err)
+	}
+
+	c, err := LoadCheckpoint(opts.CheckpointFile)
+	if err != nil || c.Iteration != 3 {
+		t.Fatalf("The interrupted Test(%v) should save a checkpoint at U(3), but we got %+v, %v", R, c, err)
+	}
+
+	opts.Stop = nil
+	if result, err := Test(R, opts); err != nil || !result.Prime || result.ResumedFrom != 3 {
+		t.Errorf("Test(%v) resumed from U(3) returned %+v, %v, but we expected a prime", R, result, err)
+	}
+}
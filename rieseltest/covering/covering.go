@@ -0,0 +1,244 @@
+// Package covering finds the residue classes of n for which h*2^n-1 is always
+// composite, and the covering sets proving that h is a Riesel number.
+//
+// For an odd prime p not dividing h, let d = ord_p(2). Then p divides h*2^n-1
+// exactly when n belongs to a residue class r mod d (if any), since
+// h*2^n == 1 (mod p) only depends on n mod d. A set of such classes covering
+// every integer is a covering set: every h*2^n-1 has a factor in the set and,
+// unless it is equal to that factor, it is composite. Such an h is a Riesel
+// number, e.g. 509203 with the covering set {3, 5, 7, 13, 17, 241} mod 24.
+package covering
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// DefaultMaxModulus is the largest ord_p(2) considered by Analyze by default.
+const DefaultMaxModulus = 720
+
+// DefaultPrimeLimit is the largest prime considered by Analyze by default.
+const DefaultPrimeLimit = 1 << 22
+
+// A Class is a residue class of n for which Prime divides h*2^n-1.
+type Class struct {
+	Prime   uint64
+	Modulus int64 // ord_p(2)
+	Residue int64 // 0 <= Residue < Modulus
+}
+
+// Contains returns true if n belongs to the class.
+func (c Class) Contains(n int64) bool {
+	return n%c.Modulus == c.Residue
+}
+
+func (c Class) String() string {
+	return fmt.Sprintf("%v | n == %v (mod %v)", c.Prime, c.Residue, c.Modulus)
+}
+
+// Analysis is the result of the covering-set analysis of an h.
+type Analysis struct {
+	H int64
+
+	// Classes are the classes of all the primes p considered with a small
+	// ord_p(2), sorted by modulus and then by prime.
+	Classes []Class
+
+	// Riesel is true if h is proven to be a Riesel number. In that case,
+	// Cover is a covering set of classes whose moduli divide Modulus.
+	Riesel  bool
+	Modulus int64
+	Cover   []Class
+}
+
+// Analyze looks for a covering set of h among the primes p <= primeLimit
+// with ord_p(2) <= maxModulus.
+//
+// This function requires:
+//		a) h >= 1
+//		b) maxModulus >= 1
+//		c) 3 <= primeLimit < 2^32
+//
+// Not finding a covering set does not prove that h is not a Riesel number,
+// since a larger bound might find one.
+func Analyze(h, maxModulus int64, primeLimit uint64) (*Analysis, error) {
+
+	// Check preconditions
+	if h < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected h >= 1, but received h = %v", h))
+	}
+	if maxModulus < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected maxModulus >= 1, but received maxModulus = %v", maxModulus))
+	}
+	if primeLimit < 3 || primeLimit >= 1<<32 {
+		return nil, errors.New(fmt.Sprintf("Expected 3 <= primeLimit < 2^32, but received primeLimit = %v",
+			primeLimit))
+	}
+
+	a := &Analysis{H: h}
+	for _, p := range Primes(primeLimit) {
+		if c, ok := classOf(h, p, maxModulus); ok {
+			a.Classes = append(a.Classes, c)
+		}
+	}
+
+	sort.Slice(a.Classes, func(i, j int) bool {
+		if a.Classes[i].Modulus != a.Classes[j].Modulus {
+			return a.Classes[i].Modulus < a.Classes[j].Modulus
+		}
+		return a.Classes[i].Prime < a.Classes[j].Prime
+	})
+
+	// Look for the smallest modulus covered by the classes whose moduli divide it
+	for m := int64(1); m <= maxModulus; m++ {
+		var classes []Class
+		for _, c := range a.Classes {
+			if m%c.Modulus == 0 {
+				classes = append(classes, c)
+			}
+		}
+
+		if cover := findCover(classes, m); cover != nil {
+			a.Modulus = m
+			a.Cover = cover
+			a.Riesel = true
+			break
+		}
+	}
+
+	// h*2^n-1 equal to a prime of the cover is not composite
+	for _, c := range a.Cover {
+		if n, ok := exponentOf(h, c.Prime); ok && c.Contains(n) {
+			a.Riesel = false
+		}
+	}
+
+	return a, nil
+}
+
+// Composite returns a class proving that h*2^n-1 is composite, if the
+// analysis has one. The caller must check the second return value.
+func (a *Analysis) Composite(n int64) (Class, bool) {
+	for _, c := range a.Classes {
+		if c.Contains(n) {
+			if m, ok := exponentOf(a.H, c.Prime); !ok || m != n {
+				return c, true
+			}
+		}
+	}
+	return Class{}, false
+}
+
+// classOf returns the class of n for which p divides h*2^n-1, if p does not
+// divide h and ord_p(2) <= maxModulus.
+func classOf(h int64, p uint64, maxModulus int64) (Class, bool) {
+
+	// Find d = ord_p(2), if it is small enough
+	var d int64
+	for k, w := int64(1), uint64(2)%p; k <= maxModulus; k++ {
+		if w == 1 {
+			d = k
+			break
+		}
+		w = w * 2 % p
+	}
+	if d == 0 {
+		return Class{}, false
+	}
+
+	// Find r such that h*2^r == 1 (mod p)
+	v := uint64(h) % p
+	for r := int64(0); r < d; r++ {
+		if v == 1 {
+			return Class{Prime: p, Modulus: d, Residue: r}, true
+		}
+		v = v * 2 % p
+	}
+
+	// p divides h, or h is not a power of 2 modulo p
+	return Class{}, false
+}
+
+// findCover returns a subset of the given classes covering every residue
+// modulo m, chosen greedily, or nil if the classes do not cover all of them.
+func findCover(classes []Class, m int64) []Class {
+	covered := make([]bool, m)
+	count := int64(0)
+	for _, c := range classes {
+		for r := c.Residue; r < m; r += c.Modulus {
+			if !covered[r] {
+				covered[r] = true
+				count++
+			}
+		}
+	}
+	if count < m {
+		return nil
+	}
+
+	// Pick the class covering the most uncovered residues, until all are covered
+	var cover []Class
+	for i := range covered {
+		covered[i] = false
+	}
+	for count = 0; count < m; {
+		best, bestGain := -1, int64(0)
+		for i, c := range classes {
+			gain := int64(0)
+			for r := c.Residue; r < m; r += c.Modulus {
+				if !covered[r] {
+					gain++
+				}
+			}
+			if gain > bestGain {
+				best, bestGain = i, gain
+			}
+		}
+
+		c := classes[best]
+		for r := c.Residue; r < m; r += c.Modulus {
+			covered[r] = true
+		}
+		count += bestGain
+		cover = append(cover, c)
+	}
+
+	sort.Slice(cover, func(i, j int) bool { return cover[i].Prime < cover[j].Prime })
+	return cover
+}
+
+// exponentOf returns the n >= 1 such that h*2^n-1 == p, if there is one.
+func exponentOf(h int64, p uint64) (int64, bool) {
+	q := p + 1
+	if q%uint64(h) != 0 {
+		return 0, false
+	}
+	q /= uint64(h)
+
+	n := int64(0)
+	for q > 1 && q%2 == 0 {
+		q /= 2
+		n++
+	}
+	return n, q == 1 && n >= 1
+}
+
+// Primes returns the odd primes <= limit, computed with the sieve of Eratosthenes.
+func Primes(limit uint64) []uint64 {
+	composite := make([]bool, limit+1)
+	var result []uint64
+
+	for p := uint64(3); p <= limit; p += 2 {
+		if composite[p] {
+			continue
+		}
+		result = append(result, p)
+
+		for q := p * p; q <= limit; q += 2 * p {
+			composite[q] = true
+		}
+	}
+
+	return result
+}
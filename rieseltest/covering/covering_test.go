@@ -0,0 +1,97 @@
+package covering
+
+import (
+	"reflect"
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+func TestAnalyze(t *testing.T) {
+	var testCases = []struct {
+		h       int64
+		riesel  bool
+		modulus int64
+		primes  []uint64
+	}{
+		{509203, true, 24, []uint64{3, 5, 7, 13, 17, 241}},
+		{762701, true, 24, []uint64{3, 5, 7, 13, 17, 241}},
+		{777149, true, 36, []uint64{3, 5, 7, 13, 19, 37, 73}},
+		{3, false, 0, nil},
+		{507, false, 0, nil},
+		{8565, false, 0, nil},
+	}
+
+	for _, c := range testCases {
+		a, err := Analyze(c.h, DefaultMaxModulus, 1000)
+		if err != nil {
+			t.Fatalf("Analyze(%v) returned an error: %v", c.h, err)
+		}
+
+		var primes []uint64
+		for _, class := range a.Cover {
+			primes = append(primes, class.Prime)
+		}
+
+		if a.Riesel != c.riesel || a.Modulus != c.modulus || !reflect.DeepEqual(primes, c.primes) {
+			t.Errorf("Analyze(%v) returned Riesel = %v with the cover %v mod %v, but we expected %v with %v mod %v",
+				c.h, a.Riesel, primes, a.Modulus, c.riesel, c.primes, c.modulus)
+		}
+	}
+}
+
+func TestAnalyzeError(t *testing.T) {
+	var testCases = []struct {
+		h          int64
+		maxModulus int64
+		primeLimit uint64
+	}{
+		{0, 10, 100},
+		{3, 0, 100},
+		{3, 10, 2},
+		{3, 10, 1 << 32},
+	}
+
+	for _, c := range testCases {
+		if _, err := Analyze(c.h, c.maxModulus, c.primeLimit); err == nil {
+			t.Errorf("Analyze(%v, %v, %v) should return an error, but it didn't", c.h, c.maxModulus, c.primeLimit)
+		}
+	}
+}
+
+func TestComposite(t *testing.T) {
+	var testCases = []int64{3, 5, 507, 8565, 509203}
+
+	for _, h := range testCases {
+		a, _ := Analyze(h, 100, 10000)
+
+		for n := int64(1); n <= 300; n++ {
+			N := new(big.Int).Lsh(big.NewInt(h), uint(n))
+			N.Sub(N, big.NewInt(1))
+
+			c, ok := a.Composite(n)
+			if !ok {
+				continue
+			}
+
+			p := new(big.Int).SetUint64(c.Prime)
+			if new(big.Int).Mod(N, p).Sign() != 0 || N.Cmp(p) == 0 {
+				t.Errorf("Composite(%v) for h = %v returned %v, which does not prove %v * 2^%v - 1 composite",
+					n, h, c, h, n)
+			}
+		}
+	}
+}
+
+func TestNotRieselWhenEqualToCoverPrime(t *testing.T) {
+	// 1*2^n-1 is divisible by 3 for every even n, and 3 = 1*2^2-1 itself
+	a, _ := Analyze(1, 10, 100)
+	if _, ok := a.Composite(2); ok {
+		t.Errorf("Composite(2) for h = 1 should be false, since 1 * 2^2 - 1 = 3 is prime")
+	}
+	if _, ok := a.Composite(4); !ok {
+		t.Errorf("Composite(4) for h = 1 should be true, since 3 divides 1 * 2^4 - 1 = 15")
+	}
+}
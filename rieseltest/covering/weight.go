@@ -0,0 +1,87 @@
+package covering
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The Nash weight of h is the number of n in [NashMin, NashMax] such that
+// h*2^n-1 has no prime factor p < NashPrimeLimit.
+const (
+	NashMin        = 100001
+	NashMax        = 110000
+	NashPrimeLimit = 256
+)
+
+// eulerGamma is the Euler-Mascheroni constant.
+const eulerGamma = 0.57721566490153286061
+
+// NashWeight returns the Nash weight of h, a measure of how "prime-rich" the
+// numbers h*2^n-1 are. The average weight of an odd h is about 1751, and
+// a Riesel number has weight 0.
+//
+// This function requires:
+//		a) h >= 1
+func NashWeight(h int64) (int64, error) {
+
+	// Check preconditions
+	if h < 1 {
+		return 0, errors.New(fmt.Sprintf("Expected h >= 1, but received h = %v", h))
+	}
+
+	// The order of 2 modulo p < 256 is at most p - 1, so every class is found
+	var classes []Class
+	for _, p := range Primes(NashPrimeLimit - 1) {
+		if c, ok := classOf(h, p, int64(p)); ok {
+			classes = append(classes, c)
+		}
+	}
+
+	weight := int64(0)
+	for n := int64(NashMin); n <= NashMax; n++ {
+		survives := true
+		for _, c := range classes {
+			if c.Contains(n) {
+				survives = false
+				break
+			}
+		}
+		if survives {
+			weight++
+		}
+	}
+
+	return weight, nil
+}
+
+// ExpectedPrimes estimates the number of primes h*2^n-1 with nmin <= n <= nmax,
+// for an h of the given Nash weight.
+//
+// This function requires:
+//		a) h >= 1
+//		b) 1 <= nmin <= nmax
+//
+// The fraction f = weight / (NashMax - NashMin + 1) of the n survive the
+// sieve by the primes p < 256, and by Mertens' theorem a survivor N is prime
+// with probability about e^gamma * ln(256) / ln(N). Since ln(N) is close to
+// (n + log2(h)) * ln(2), summing over n gives:
+//
+//		f * e^gamma * log2(256) * ln((nmax + 1/2 + log2(h)) / (nmin - 1/2 + log2(h)))
+func ExpectedPrimes(h, weight, nmin, nmax int64) (float64, error) {
+
+	// Check preconditions
+	if h < 1 {
+		return 0, errors.New(fmt.Sprintf("Expected h >= 1, but received h = %v", h))
+	}
+	if nmin < 1 || nmax < nmin {
+		return 0, errors.New(fmt.Sprintf("Expected 1 <= nmin <= nmax, but received nmin = %v, nmax = %v",
+			nmin, nmax))
+	}
+
+	f := float64(weight) / float64(NashMax - NashMin + 1)
+	l := math.Log2(float64(h))
+	sum := math.Log((float64(nmax) + 0.5 + l) / (float64(nmin) - 0.5 + l))
+
+	return f * math.Exp(eulerGamma) * math.Log2(NashPrimeLimit) * sum, nil
+}
@@ -0,0 +1,82 @@
+package covering
+
+import (
+	"math"
+	"testing"
+)
+
+// bruteForceWeight is a slow reference implementation of NashWeight.
+func bruteForceWeight(h int64) int64 {
+	weight := int64(0)
+	for n := int64(NashMin); n <= NashMax; n++ {
+		survives := true
+		for _, p := range Primes(NashPrimeLimit - 1) {
+			// h*2^n mod p
+			v, base := uint64(h)%p, uint64(2)
+			for e := n; e > 0; e >>= 1 {
+				if e&1 == 1 {
+					v = v * base % p
+				}
+				base = base * base % p
+			}
+			if v == 1 {
+				survives = false
+				break
+			}
+		}
+		if survives {
+			weight++
+		}
+	}
+	return weight
+}
+
+func TestNashWeight(t *testing.T) {
+	var testCases = []int64{1, 3, 5, 15, 507, 8565, 502573}
+
+	for _, h := range testCases {
+		actual, err := NashWeight(h)
+		if err != nil {
+			t.Fatalf("NashWeight(%v) returned an error: %v", h, err)
+		}
+		if expected := bruteForceWeight(h); actual != expected {
+			t.Errorf("NashWeight(%v) returned %v, but we expected %v", h, actual, expected)
+		}
+	}
+
+	// A Riesel number whose covering set only has primes < 256
+	if w, _ := NashWeight(509203); w != 0 {
+		t.Errorf("NashWeight(509203) returned %v, but we expected 0", w)
+	}
+
+	if _, err := NashWeight(0); err == nil {
+		t.Errorf("NashWeight(0) should return an error, but it didn't")
+	}
+}
+
+func TestExpectedPrimes(t *testing.T) {
+	var testCases = []struct {
+		h, weight, nmin, nmax int64
+		expected              float64
+	}{
+		{3, 1751, 1000, 2000, 1.728},
+		{3, 1751, 1000000, 2000000, 1.728},
+		{3, 3502, 1000, 2000, 3.456},
+		{509203, 0, 1, 1000000, 0},
+	}
+
+	for _, c := range testCases {
+		actual, err := ExpectedPrimes(c.h, c.weight, c.nmin, c.nmax)
+		if err != nil {
+			t.Fatalf("ExpectedPrimes returned an error: %v", err)
+		}
+		if math.Abs(actual-c.expected) > 0.01 {
+			t.Errorf("ExpectedPrimes(%v, %v, %v, %v) returned %v, but we expected about %v",
+				c.h, c.weight, c.nmin, c.nmax, actual, c.expected)
+		}
+	}
+
+	if _, err := ExpectedPrimes(3, 1751, 10, 9); err == nil {
+		t.Errorf("ExpectedPrimes with nmin > nmax should return an error, but it didn't")
+	}
+}
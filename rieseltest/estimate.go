@@ -0,0 +1,127 @@
+package rieseltest
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// DefaultEstimateSample is the number of U(i) iterations timed by EstimateDuration
+// when no sample size is given.
+const DefaultEstimateSample = 1000
+
+// errSampleDone stops genUNFrom once the sample has been timed.
+var errSampleDone = errors.New("the sample of iterations is complete")
+
+// An Estimate is the expected duration of the test of a number, by step.
+type Estimate struct {
+	V1        time.Duration	// Time to generate V(1)
+	U2        time.Duration	// Time to generate U(2)
+	Iteration time.Duration	// Average time of one U(i) iteration
+	UN        time.Duration	// Expected time of the n-2 iterations up to U(n)
+
+	// Sample is the number of U(i) iterations actually timed.
+	Sample int64
+}
+
+// Total returns the expected duration of the whole test.
+func (e *Estimate) Total() time.Duration {
+	return e.V1 + e.U2 + e.UN
+}
+
+// EstimateDuration estimates how long the Lucas-Lehmer-Riesel test of R would
+// take on this machine, by generating V(1) and U(2) with the given method, and
+// timing the first sample iterations of U(i). Since every iteration squares a
+// number of the size of N, the time of U(n) is extrapolated linearly.
+//
+// This function requires:
+//		a) sample >= 1
+func EstimateDuration(R *RieselNumber, method uint8, sample int64) (*Estimate, error) {
+
+	// Check preconditions
+	if R == nil {
+		return nil, errors.New("Received R == nil")
+	}
+	if sample < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected sample >= 1, but received sample = %v", sample))
+	}
+
+	e := new(Estimate)
+
+	begin := time.Now()
+	v1, err := GenV1(R, method)
+	if err != nil {
+		return nil, err
+	}
+	e.V1 = time.Since(begin)
+
+	begin = time.Now()
+	u, err := GenU2(R, v1)
+	if err != nil {
+		return nil, err
+	}
+	e.U2 = time.Since(begin)
+
+	begin = time.Now()
+	_, err = genUNFrom(R, u, 3, func(i int64, u *big.Int) error {
+		e.Sample++
+		if e.Sample >= sample {
+			return errSampleDone
+		}
+		return nil
+	})
+	if err != nil && err != errSampleDone {
+		return nil, err
+	}
+	elapsed := time.Since(begin)
+
+	if e.Sample > 0 {
+		e.Iteration = elapsed / time.Duration(e.Sample)
+		e.UN = time.Duration(float64(elapsed) * float64(R.n - 2) / float64(e.Sample))
+	}
+
+	return e, nil
+}
+
+// TimeIterations returns the time taken by count iterations of
+// U(i+1) = U(i)^2 - 2 mod N, starting from u = U(2), which is not modified.
+// If count > n - 2, the iterations start again from u once U(n) is reached,
+// so that the cost of an iteration can be measured over any duration.
+//
+// This function requires:
+//		a) n >= 3
+//		b) count >= 1
+func TimeIterations(R *RieselNumber, u *big.Int, count int64) (time.Duration, error) {
+
+	// Check preconditions
+	if R == nil || u == nil {
+		return 0, errors.New("Received R == nil or u == nil")
+	}
+	if R.n < 3 {
+		return 0, errors.New(fmt.Sprintf("Expected n >= 3, but received n = %v", R.n))
+	}
+	if count < 1 {
+		return 0, errors.New(fmt.Sprintf("Expected count >= 1, but received count = %v", count))
+	}
+
+	done := int64(0)
+	begin := time.Now()
+	for done < count {
+		_, err := genUNFrom(R, new(big.Int).Set(u), 3, func(i int64, u *big.Int) error {
+			done++
+			if done >= count {
+				return errSampleDone
+			}
+			return nil
+		})
+		if err != nil && err != errSampleDone {
+			return 0, err
+		}
+	}
+
+	return time.Since(begin), nil
+}
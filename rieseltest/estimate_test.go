@@ -0,0 +1,76 @@
+package rieseltest
+
+import (
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+func TestEstimateDuration(t *testing.T) {
+	var testCases = []struct {
+		h, n            int64
+		sample          int64
+		expected_sample int64
+	}{
+		{3, 1274, 100, 100},
+		{8565, 15, 1000, 13},
+		{375, 9, 1, 1},
+	}
+
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		e, err := EstimateDuration(R, RODSETH, c.sample)
+		if err != nil {
+			t.Errorf("EstimateDuration(%v, %v) returned an error: %v", R, c.sample, err)
+			continue
+		}
+		if e.Sample != c.expected_sample {
+			t.Errorf("EstimateDuration(%v, %v) timed %v iterations, but we expected %v", R, c.sample, e.Sample,
+				c.expected_sample)
+		}
+		if e.UN < e.Iteration || e.Total() < e.UN {
+			t.Errorf("EstimateDuration(%v, %v) == %+v, but U(n) cannot take less than one iteration", R, c.sample, e)
+		}
+	}
+
+	R, _ := NewRieselNumber(3, 1274)
+	if _, err := EstimateDuration(R, RODSETH, 0); err == nil {
+		t.Errorf("EstimateDuration(%v, 0) did not return an error", R)
+	}
+}
+
+func TestTimeIterations(t *testing.T) {
+	var testCases = []struct {
+		h, n  int64
+		count int64
+	}{
+		{3, 1274, 100},
+		{8565, 15, 100},
+		{1, 127, 1000},
+	}
+
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		v1, _ := GenV1(R, RODSETH)
+		u, _ := GenU2(R, v1)
+		expected := new(big.Int).Set(u)
+
+		if _, err := TimeIterations(R, u, c.count); err != nil {
+			t.Errorf("TimeIterations(%v, %v) returned an error: %v", R, c.count, err)
+		}
+		if u.Cmp(expected) != 0 {
+			t.Errorf("TimeIterations(%v, %v) modified U(2)", R, c.count)
+		}
+	}
+
+	R, _ := NewRieselNumber(3, 1274)
+	if _, err := TimeIterations(R, big.NewInt(5), 0); err == nil {
+		t.Errorf("TimeIterations(%v, 5, 0) did not return an error", R)
+	}
+	R, _ = NewRieselNumber(3, 2)
+	if _, err := TimeIterations(R, big.NewInt(5), 1); err == nil {
+		t.Errorf("TimeIterations(%v, 5, 1) did not return an error", R)
+	}
+}
@@ -0,0 +1,312 @@
+package rieseltest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// MaxExplainBits is the largest size of N, in bits, accepted by Explain, so
+// that the values it prints remain readable.
+const MaxExplainBits = 512
+
+// explainer renders the steps of Explain as plain text or Markdown.
+type explainer struct {
+	w        io.Writer
+	markdown bool
+	err      error
+}
+
+// printf writes a formatted line, remembering the first write error.
+func (e *explainer) printf(format string, args ...interface{}) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format + "\n", args...)
+	}
+}
+
+// heading starts a new section.
+func (e *explainer) heading(format string, args ...interface{}) {
+	title := fmt.Sprintf(format, args...)
+	if e.markdown {
+		e.printf("\n## %v\n", title)
+	} else {
+		e.printf("\n%v\n%v", title, strings.Repeat("-", len(title)))
+	}
+}
+
+// item writes an entry of a list.
+func (e *explainer) item(format string, args ...interface{}) {
+	if e.markdown {
+		e.printf("- " + format, args...)
+	} else {
+		e.printf("  " + format, args...)
+	}
+}
+
+// value formats a number, as code in Markdown.
+func (e *explainer) value(v interface{}) string {
+	if e.markdown {
+		return fmt.Sprintf("`%v`", v)
+	}
+	return fmt.Sprint(v)
+}
+
+// Explain performs the Lucas-Lehmer-Riesel test of R like Test, writing every
+// step to w with its full values, so that the algorithm can be followed on
+// concrete numbers: the screening of small factors, every Jacobi symbol
+// computed while looking for V(1) with the given method, every step of the
+// ladder computing U(2) = V(h), and every U(i) up to U(n).
+//
+// The steps are written as plain text, or as Markdown if markdown is true.
+// Explain returns the same Result as Test, and an error if N has more than
+// MaxExplainBits bits.
+func Explain(w io.Writer, R *RieselNumber, method uint8, markdown bool) (*Result, error) {
+
+	// Check preconditions
+	if R == nil {
+		return nil, errors.New("Received R == nil")
+	}
+	if bits := R.N.BitLen(); bits > MaxExplainBits {
+		return nil, errors.New(fmt.Sprintf("N = %v has %v bits, but Explain accepts at most %v bits", R, bits,
+			MaxExplainBits))
+	}
+	if method != RIESEL && method != RODSETH && method != PENNE {
+		return nil, errors.New("The specified method to generate v1 is not valid")
+	}
+
+	e := &explainer{w: w, markdown: markdown}
+	result := &Result{R: R}
+
+	if markdown {
+		e.printf("# Lucas-Lehmer-Riesel test of N = %v", R)
+	} else {
+		e.printf("Lucas-Lehmer-Riesel test of N = %v", R)
+	}
+	e.printf("\nN = %v (%v bits)", e.value(R.N), R.N.BitLen())
+
+	// Step 0: screening
+	e.heading("Step 0: screening of the small primes")
+	check, err := screenEasyPrimes(R)
+	if err != nil {
+		return nil, err
+	}
+	switch check {
+	case 1:
+		e.item("N is a known prime < 257, so there is nothing to test.")
+		result.Screened = true
+		result.Prime = true
+		return result, e.err
+	case -1:
+		p := big.NewInt(0)
+		for _, q := range screenedPrimes {
+			if new(big.Int).Mod(R.N, big.NewInt(q)).Sign() == 0 {
+				p.SetInt64(q)
+				break
+			}
+		}
+		e.item("N mod %v = 0: N is composite.", p)
+		result.Screened = true
+		return result, e.err
+	}
+	e.item("N has no prime factor < 257, and is not one of them: the test is needed.")
+
+	// Step 1: V(1)
+	e.heading("Step 1: V(1)")
+	v1, err := e.explainV1(R, method)
+	if err != nil {
+		e.item("The search for V(1) stopped: %v", err)
+		return nil, err
+	}
+	if expected, err := GenV1(R, method); err != nil || expected != v1 {
+		return nil, errors.New(fmt.Sprintf("Explain found V(1) = %v, but GenV1 returned %v, %v", v1, expected,
+			err))
+	}
+	result.V1 = v1
+	e.item("V(1) = %v", e.value(v1))
+
+	// Step 2: U(2) = V(h)
+	e.heading("Step 2: U(2) = V(h) = V(%v)", R.h)
+	u := e.explainU2(R, v1)
+	if expected, err := GenU2(R, v1); err != nil || expected.Cmp(u) != 0 {
+		return nil, errors.New(fmt.Sprintf("Explain found U(2) = %v, but GenU2 returned %v, %v", u, expected,
+			err))
+	}
+	e.item("U(2) = %v", e.value(u))
+
+	// Step 3: U(n)
+	e.heading("Step 3: U(i) = U(i-1)^2 - 2 mod N, for 3 <= i <= n")
+	uN, err := genUNFrom(R, new(big.Int).Set(u), 3, func(i int64, u *big.Int) error {
+		e.item("U(%v) = %v", i, e.value(u))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 4: verdict
+	e.heading("Step 4: verdict")
+	result.Residue = residue64(uN)
+	if uN.Sign() == 0 {
+		result.Prime = true
+		e.item("U(n) = U(%v) == 0 (mod N): N is prime.", R.n)
+	} else {
+		e.item("U(n) = U(%v) != 0 (mod N): N is composite, with RES64 %v.", R.n, e.value(result.ResidueString()))
+	}
+
+	return result, e.err
+}
+
+// screenedPrimes are the primes screened by screenEasyPrimes.
+var screenedPrimes = []int64{3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37, 41, 43, 47, 53, 59, 61, 67, 71, 73, 79,
+	83, 89, 97, 101, 103, 107, 109, 113, 127, 131, 137, 139, 149, 151, 157, 163, 167, 173, 179, 181, 191, 193,
+	197, 199, 211, 223, 227, 229, 233, 239, 241, 251}
+
+// explainV1 looks for V(1) with the given method, writing every Jacobi symbol
+// it computes. The symbols are computed without the caches of GenV1, so that
+// each one appears in the trace.
+func (e *explainer) explainV1(R *RieselNumber, method uint8) (int64, error) {
+	jacobi := func(x int64) (int, error) {
+		return efficientJacobi(x, R.h, R.n, nil)
+	}
+
+	if hmod3 := R.h % 3; hmod3 != 0 {
+		e.item("h mod 3 = %v != 0, and N mod 3 = %v != 0, so V(1) = 4 (Riesel, case 1).", hmod3,
+			new(big.Int).Mod(R.N, big.NewInt(3)))
+		return 4, nil
+	}
+
+	switch method {
+	case RODSETH:
+		e.item("h mod 3 = 0: looking for the first P with Jacobi(P-2, N) = 1 and Jacobi(P+2, N) = -1 (Rödseth).")
+		for P := int64(3); P < math.MaxInt64; P++ {
+			jMinus, err := jacobi(P - 2)
+			if err != nil {
+				return -1, err
+			}
+			if jMinus != 1 {
+				e.item("P = %v: Jacobi(%v, N) = %v", P, P - 2, jMinus)
+				continue
+			}
+
+			jPlus, err := jacobi(P + 2)
+			if err != nil {
+				return -1, err
+			}
+			e.item("P = %v: Jacobi(%v, N) = %v, Jacobi(%v, N) = %v", P, P - 2, jMinus, P + 2, jPlus)
+			if jPlus == -1 {
+				return P, nil
+			}
+		}
+
+	case RIESEL:
+		e.item("h mod 3 = 0: looking for the first v with Jacobi(v^2-4, N) = -1 and v-2 a square or " +
+			"Jacobi(v-2, N) = 1 (Riesel).")
+		for v := int64(3); v < math.MaxInt64; v++ {
+			_, D := reduce(v * v - 4)
+			jD, err := jacobi(D)
+			if err != nil {
+				return -1, err
+			}
+			if jD != -1 {
+				e.item("v = %v: Jacobi(%v, N) = %v, where %v is the square free part of %v", v, D, jD, D, v * v - 4)
+				continue
+			}
+
+			if square, _ := isPerfectSquare(v - 2); square {
+				e.item("v = %v: Jacobi(%v, N) = %v, and %v is a square", v, D, jD, v - 2)
+				return v, nil
+			}
+
+			jA, err := jacobi(v - 2)
+			if err != nil {
+				return -1, err
+			}
+			e.item("v = %v: Jacobi(%v, N) = %v, Jacobi(%v, N) = %v", v, D, jD, v - 2, jA)
+			if jA == 1 {
+				return v, nil
+			}
+		}
+
+	case PENNE:
+		e.item("h mod 3 = 0: looking for the first x with Jacobi(x^2+4, N) = -1, and V(1) = x^2+2 (Penné).")
+		for x := int64(1); x < math.MaxInt64; x++ {
+			_, D := reduce(x * x + 4)
+			jD, err := jacobi(D)
+			if err != nil {
+				return -1, err
+			}
+			e.item("x = %v: Jacobi(%v, N) = %v, where %v is the square free part of %v", x, D, jD, D, x * x + 4)
+			if jD == -1 {
+				return x * x + 2, nil
+			}
+		}
+	}
+
+	return -1, errors.New("It was not possible to find a valid v1 for the given n and h")
+}
+
+// explainU2 computes U(2) = V(h) with the same ladder as GenU2, one bit of h
+// at a time, writing r = V(x) and s = V(x+1) after every step.
+func (e *explainer) explainU2(R *RieselNumber, v1 int64) *big.Int {
+	v := big.NewInt(v1)
+	r := new(big.Int).Set(v)
+	rieselMod(r, R)
+	if R.h == 1 {
+		e.item("h = 1, so U(2) = V(1) mod N.")
+		return r
+	}
+
+	e.item("h = %v = %v in binary. Starting from r = V(1), s = V(2), for every following bit of h:", R.h,
+		e.value(fmt.Sprintf("%b", R.h)))
+	e.item("bit 0: r = V(2x) = V(x)^2 - 2, s = V(2x+1) = V(x+1) V(x) - V(1)")
+	e.item("bit 1: r = V(2x+1) = V(x+1) V(x) - V(1), s = V(2x+2) = V(x+1)^2 - 2")
+
+	s := new(big.Int).Mul(r, r)
+	s.Sub(s, two)
+	rieselMod(s, R)
+
+	x := int64(1)
+	e.item("x = 1: r = V(1) = %v, s = V(2) = %v", e.value(r), e.value(s))
+
+	bits, _ := bitLen(R.h)
+	for i := int(bits) - 2; i >= 0; i-- {
+		t := new(big.Int).Mul(r, s)
+		t.Sub(t, v)
+		if t.Sign() < 0 {
+			t.Add(t, R.N)
+		}
+		rieselMod(t, R)
+
+		if bit(R.h, uint(i)) {
+			s.Mul(s, s)
+			s.Sub(s, two)
+			rieselMod(s, R)
+			r = t
+			x = 2 * x + 1
+		} else {
+			r.Mul(r, r)
+			r.Sub(r, two)
+			rieselMod(r, R)
+			s = t
+			x = 2 * x
+		}
+		e.item("bit %v = %v, x = %v: r = V(%v) = %v, s = V(%v) = %v", i, btoi(bit(R.h, uint(i))), x, x,
+			e.value(r), x + 1, e.value(s))
+	}
+
+	return r
+}
+
+// btoi returns 1 for true and 0 for false.
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package rieseltest
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+
+	// Explain must reach the same verdict as Test, with every method
+	for h := int64(1); h <= 99; h += 2 {
+		for n := int64(2); n <= 40; n++ {
+			R, _ := NewRieselNumber(h, n)
+			expected, expectedErr := Test(R, &Options{V1Method: RODSETH})
+
+			for _, method := range []uint8{RIESEL, RODSETH, PENNE} {
+				result, err := Explain(io.Discard, R, method, false)
+				if (err != nil) != (expectedErr != nil) {
+					t.Errorf("Explain(%v, %v) returned the error %v, but Test returned %v", R, method, err,
+						expectedErr)
+					continue
+				}
+				if err == nil && result.Prime != expected.Prime {
+					t.Errorf("Explain(%v, %v) returned %v, but Test returned %v", R, method, result.Prime,
+						expected.Prime)
+				}
+			}
+		}
+	}
+}
+
+func TestExplainOutput(t *testing.T) {
+	R, _ := NewRieselNumber(8565, 15)
+
+	var text, markdown bytes.Buffer
+	if _, err := Explain(&text, R, RODSETH, false); err != nil {
+		t.Fatalf("Explain(%v) returned an error: %v", R, err)
+	}
+	if _, err := Explain(&markdown, R, RODSETH, true); err != nil {
+		t.Fatalf("Explain(%v) returned an error: %v", R, err)
+	}
+
+	for _, line := range []string{
+		"N = 280657919 (29 bits)",
+		"P = 9: Jacobi(7, N) = 1, Jacobi(11, N) = -1",
+		"V(1) = 9",
+		"U(2) = 244410061",
+		"U(15) = 0",
+		"U(n) = U(15) == 0 (mod N): N is prime.",
+	} {
+		if !strings.Contains(text.String(), line) {
+			t.Errorf("The explanation of %v doesn't contain %q:\n%v", R, line, text.String())
+		}
+	}
+
+	for _, line := range []string{"# Lucas-Lehmer-Riesel test of N = 8565 * 2^15 - 1", "## Step 1: V(1)",
+		"- V(1) = `9`", "- U(15) = `0`"} {
+		if !strings.Contains(markdown.String(), line) {
+			t.Errorf("The Markdown explanation of %v doesn't contain %q:\n%v", R, line, markdown.String())
+		}
+	}
+}
+
+func TestExplainTooLarge(t *testing.T) {
+	R, _ := NewRieselNumber(3, MaxExplainBits)
+	if _, err := Explain(io.Discard, R, RODSETH, false); err == nil {
+		t.Errorf("Explain(%v) didn't return an error", R)
+	}
+}
@@ -0,0 +1,216 @@
+// Command fixturegen generates the fixtures of the rieseltest tests.
+//
+// For every odd h and every n of the given sets such that h < 2^n and N = h*2^n-1
+// has no factor < 257, it writes a line
+//
+//	h n V(1) U(2) verdict
+//
+// where V(1) is the one selected by the RODSETH method, U(2) is given by its
+// last rieseltest.FixtureDigits decimal digits, and verdict is "prime" or
+// "composite".
+//
+// The values are computed by a slow reference implementation which shares no
+// code with rieseltest, apart from the FixtureDigits constant: the Jacobi
+// symbols are computed on N by big.Jacobi, V(h) by the linear recurrence
+// V(k+1) = V(1) V(k) - V(k-1), and the verdict of the Lucas-Lehmer-Riesel test
+// is checked against big.ProbablyPrime. The fixtures can thus be extended and
+// audited independently of the code they test. Run it through go generate in
+// the rieseltest directory:
+//
+//	go generate github.com/arcetri/goprime/rieseltest
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/arcetri/goprime/rieseltest"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// smallPrimes are the primes < 257 screened by rieseltest.
+var smallPrimes []int64
+
+func init() {
+	for p := int64(3); p < 257; p += 2 {
+		if big.NewInt(p).ProbablyPrime(0) {
+			smallPrimes = append(smallPrimes, p)
+		}
+	}
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  fixturegen -h set -n set [-o file]\n\n")
+		fmt.Print("A set is a comma separated list of numbers and ranges, as in 3,5,7-99 or 2-64.\n\n")
+		fmt.Print("Optional flags:\n")
+		flag.PrintDefaults()
+	}
+	hPtr := flag.String("h", "", "Set of the h to generate, of which only the odd ones are used.")
+	nPtr := flag.String("n", "", "Set of the n to generate.")
+	outPtr := flag.String("o", "", "File to write the fixtures to, instead of the standard output.")
+	flag.Parse()
+
+	if *hPtr == "" || *nPtr == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(*hPtr, *nPtr, *outPtr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run generates the fixtures of the given sets of h and n.
+func run(hSet, nSet, out string) error {
+	hs, err := parseSet(hSet)
+	if err != nil {
+		return err
+	}
+	ns, err := parseSet(nSet)
+	if err != nil {
+		return err
+	}
+
+	file := os.Stdout
+	if out != "" {
+		if file, err = os.Create(out); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintf(w, "# Generated by fixturegen -h %v -n %v, do not edit.\n", hSet, nSet)
+	fmt.Fprintf(w, "# h n V(1) U(2)-mod-10^%v verdict\n", rieseltest.FixtureDigits)
+
+	for _, h := range hs {
+		if h % 2 == 0 {
+			continue
+		}
+		for _, n := range ns {
+			if n < 2 || n < 63 && h >= int64(1) << uint(n) {
+				continue
+			}
+
+			line, err := fixture(h, n)
+			if err != nil {
+				return err
+			}
+			if line != "" {
+				fmt.Fprintln(w, line)
+			}
+		}
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if out != "" {
+		return file.Close()
+	}
+	return nil
+}
+
+// fixture computes the fixture line of h*2^n-1, or "" if N is screened.
+func fixture(h, n int64) (string, error) {
+	N := new(big.Int).Lsh(big.NewInt(h), uint(n))
+	N.Sub(N, big.NewInt(1))
+
+	for _, p := range smallPrimes {
+		if new(big.Int).Mod(N, big.NewInt(p)).Sign() == 0 {
+			return "", nil
+		}
+	}
+
+	v1, err := referenceV1(h, N)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("%v * 2^%v - 1: %v", h, n, err))
+	}
+
+	// U(2) = V(h)
+	u := referenceV(big.NewInt(v1), h, N)
+	last := new(big.Int).Mod(u, new(big.Int).Exp(big.NewInt(10), big.NewInt(rieseltest.FixtureDigits), nil))
+
+	// U(i) = U(i-1)^2 - 2 mod N
+	for i := int64(3); i <= n; i++ {
+		u.Mul(u, u)
+		u.Sub(u, big.NewInt(2))
+		u.Mod(u, N)
+	}
+
+	prime := u.Sign() == 0
+	if prime != N.ProbablyPrime(20) {
+		return "", errors.New(fmt.Sprintf("%v * 2^%v - 1: the reference test and ProbablyPrime disagree", h, n))
+	}
+
+	verdict := "composite"
+	if prime {
+		verdict = "prime"
+	}
+	return fmt.Sprintf("%v %v %v %v %v", h, n, v1, last, verdict), nil
+}
+
+// referenceV1 returns V(1) = 4 when h mod 3 != 0, and otherwise the first
+// P >= 3 with Jacobi(P-2, N) == 1 and Jacobi(P+2, N) == -1 (Rödseth).
+func referenceV1(h int64, N *big.Int) (int64, error) {
+	if h % 3 != 0 {
+		return 4, nil
+	}
+
+	for P := int64(3); P < 1 << 20; P++ {
+		if big.Jacobi(big.NewInt(P - 2), N) == 1 && big.Jacobi(big.NewInt(P + 2), N) == -1 {
+			return P, nil
+		}
+	}
+	return -1, errors.New("no V(1) was found")
+}
+
+// referenceV returns V(h) mod N for the Lucas sequence V(0) = 2, V(1) = v1,
+// V(k+1) = v1 V(k) - V(k-1), iterating the recurrence h times.
+func referenceV(v1 *big.Int, h int64, N *big.Int) *big.Int {
+	prev := big.NewInt(2)
+	cur := new(big.Int).Mod(v1, N)
+	for k := int64(1); k < h; k++ {
+		next := new(big.Int).Mul(v1, cur)
+		next.Sub(next, prev)
+		next.Mod(next, N)
+		prev, cur = cur, next
+	}
+	return cur
+}
+
+// parseSet parses a comma separated list of numbers and ranges a-b.
+func parseSet(s string) ([]int64, error) {
+	var set []int64
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		lo, err := strconv.ParseInt(bounds[0], 10, 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid set %v: %v", s, err))
+		}
+		hi := lo
+		if len(bounds) == 2 {
+			if hi, err = strconv.ParseInt(bounds[1], 10, 64); err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid set %v: %v", s, err))
+			}
+		}
+		if lo < 1 || hi < lo {
+			return nil, errors.New(fmt.Sprintf("Invalid set %v: expected 1 <= a <= b in a-b", s))
+		}
+		for x := lo; x <= hi; x++ {
+			set = append(set, x)
+		}
+	}
+	return set, nil
+}
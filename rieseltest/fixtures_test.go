@@ -0,0 +1,69 @@
+package rieseltest
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+func TestFixtures(t *testing.T) {
+
+	// The fixtures are generated by fixturegen, see generate.go
+	paths, err := filepath.Glob("testfiles/fixtures_*.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("No fixtures found in testfiles, run go generate")
+	}
+
+	digits := new(big.Int).Exp(big.NewInt(10), big.NewInt(FixtureDigits), nil)
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := bufio.NewScanner(file)
+		for s.Scan() {
+			if strings.HasPrefix(s.Text(), "#") {
+				continue
+			}
+
+			words := strings.Split(s.Text(), " ")
+			h, _ := strconv.ParseInt(words[0], 10, 64)
+			n, _ := strconv.ParseInt(words[1], 10, 64)
+			v1, _ := strconv.ParseInt(words[2], 10, 64)
+			u2, _ := new(big.Int).SetString(words[3], 10)
+			prime := words[4] == "prime"
+
+			R, _ := NewRieselNumber(h, n)
+			if actual, err := GenV1(R, RODSETH); err != nil || actual != v1 {
+				t.Errorf("%v: GenV1(%v) returned %v, %v, but we expected %v", path, R, actual, err, v1)
+				continue
+			}
+
+			if actual, err := GenU2(R, v1); err != nil || new(big.Int).Mod(actual, digits).Cmp(u2) != 0 {
+				t.Errorf("%v: GenU2(%v, %v) returned %v, %v, but we expected ...%v", path, R, v1, actual,
+					err, u2)
+			}
+
+			if result, err := Test(R, nil); err != nil || result.Prime != prime {
+				t.Errorf("%v: Test(%v) returned %v, %v, but we expected prime = %v", path, R, result, err,
+					prime)
+			}
+		}
+		if err := s.Err(); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+}
@@ -0,0 +1,304 @@
+package rieseltest
+
+import (
+	"errors"
+	"fmt"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// maxLucasP is the largest Lucas parameter P tried by TestGeneralized.
+const maxLucasP = 1000
+
+// A GeneralizedNumber represents a number in the form k*b^n-1
+type GeneralizedNumber struct {
+	k    int64
+	kBig *big.Int // multi-precision k
+	b    int64
+	n    int64
+	bn   *big.Int // b^n
+	N    *big.Int // k*b^n-1
+}
+
+// NewGeneralizedNumber constructs a new GeneralizedNumber instance with the
+// given k, b and n
+//
+// This function requires:
+//		a) k >= 1
+//		b) b >= 2
+//		c) n >= 1
+//
+// When k is a multiple of b, we will divide it by b and add the number of
+// times we had to divide it to n.
+func NewGeneralizedNumber(k, b, n int64) (*GeneralizedNumber, error) {
+
+	// Check preconditions
+	if k < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected k > 0, but received k = %v", k))
+	}
+	if b < 2 {
+		return nil, errors.New(fmt.Sprintf("Expected b > 1, but received b = %v", b))
+	}
+	if n < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected n > 0, but received n = %v", n))
+	}
+
+	// b^n has at most n * bitlen(b) bits, which must not exceed the bits of 2^MaxN
+	bits, _ := bitLen(b)
+	maxN := MaxN / int64(bits)
+	if n > maxN {
+		return nil, errors.New(fmt.Sprintf("Expected n <= %v for b = %v, but received n = %v", maxN, b, n))
+	}
+
+	G := &GeneralizedNumber{k: k, b: b, n: n}
+
+	// Move powers of b over b^n
+	for G.k % b == 0 {
+		G.k /= b
+		G.n++
+	}
+	if G.n > maxN {
+		return nil, errors.New(fmt.Sprintf("Expected n <= %v for b = %v once k is not a multiple of b, " +
+			"but received n = %v", maxN, b, G.n))
+	}
+
+	G.kBig = big.NewInt(G.k)
+	G.bn = new(big.Int).Exp(big.NewInt(b), big.NewInt(G.n), nil)
+	G.N = new(big.Int).Mul(G.kBig, G.bn)
+	G.N.Sub(G.N, one)
+
+	return G, nil
+}
+
+// Params returns the k, b and n of the number k*b^n-1.
+func (G *GeneralizedNumber) Params() (k, b, n int64) {
+	return G.k, G.b, G.n
+}
+
+// Custom "toString" functionality to print instances of GeneralizedNumber as k*b^n-1
+func (G *GeneralizedNumber) String() string {
+	return fmt.Sprintf("%v * %v^%v - 1", G.k, G.b, G.n)
+}
+
+// Riesel returns the RieselNumber equal to G, if b is a power of 2.
+func (G *GeneralizedNumber) Riesel() (*RieselNumber, bool) {
+	if G.b & (G.b - 1) != 0 {
+		return nil, false
+	}
+
+	shift, _ := lowerNonZeroBit(G.b)
+	R, err := NewRieselNumber(G.k, G.n * int64(shift))
+	return R, err == nil
+}
+
+// generalizedMod computes (a mod N), where N = (k * b^n - 1).
+//
+// Unlike rieselMod, it has no shift and add method: when b is not a power of
+// 2, the high part of a cannot be split off without a division by b^n, which
+// costs as much as the division by N. A Barrett reduction, which replaces the
+// division by two multiplications, is no faster with math/big either (see
+// BenchmarkGeneralizedMod), so a is simply reduced by a.Mod(a, N).
+func generalizedMod(a *big.Int, G *GeneralizedNumber) {
+	a.Mod(a, G.N)
+}
+
+// lucasV computes V(m) mod N of the Lucas sequence with V(0) = 2, V(1) = x,
+// using the same ladder as GenU2:
+//
+//		V(2*x) = V(x)^2 - 2
+//		V(2*x+1) = V(x+1) * V(x) - V(1)
+//
+// This function requires m >= 1.
+func lucasV(x *big.Int, m int64, G *GeneralizedNumber) *big.Int {
+	r := new(big.Int).Set(x)
+	if m == 1 {
+		return r
+	}
+
+	s := new(big.Int).Mul(x, x)
+	s.Sub(s, two)
+	generalizedMod(s, G)
+
+	bitLen, _ := bitLen(m)
+	for i := int(bitLen) - 2; i >= 0; i-- {
+		if bit(m, uint(i)) {
+			r.Mul(r, s)
+			r.Sub(r, x)
+			s.Mul(s, s)
+			s.Sub(s, two)
+		} else {
+			s.Mul(s, r)
+			s.Sub(s, x)
+			r.Mul(r, r)
+			r.Sub(r, two)
+		}
+		generalizedMod(r, G)
+		generalizedMod(s, G)
+	}
+
+	return r
+}
+
+// primeFactors returns the distinct prime factors of n >= 2.
+func primeFactors(n int64) []int64 {
+	var factors []int64
+	for q := int64(2); q * q <= n; q++ {
+		if n % q == 0 {
+			factors = append(factors, q)
+			for n % q == 0 {
+				n /= q
+			}
+		}
+	}
+	if n > 1 {
+		factors = append(factors, n)
+	}
+	return factors
+}
+
+// GeneralizedResult is the result of a primality test of k*b^n-1.
+type GeneralizedResult struct {
+	G *GeneralizedNumber
+
+	// Prime is true if N is prime. When Proven is false, N is only a
+	// Lucas probable prime.
+	Prime  bool
+	Proven bool
+
+	// P is the Lucas parameter used by the test, and Residue is the RES64
+	// of V(N+1) mod N. They are 0 if the test was decided without them.
+	P       int64
+	Residue uint64
+}
+
+// TestGeneralized tests the primality of G = k*b^n-1 with an N+1 Lucas test.
+//
+// When b is a power of 2, G is a Riesel number, and the faster
+// Lucas-Lehmer-Riesel test is performed through Test instead, with the given
+// options. For the other bases, only opts.Stop is used: when it is closed, the
+// test is interrupted and ErrInterrupted is returned. If opts is nil,
+// DefaultOptions() are used.
+//
+// Otherwise, we look for a P such that Jacobi(P^2-4, N) == -1 and compute
+// the Lucas sequence V(m) of parameter P. Since N+1 = k*b^n, and V(x*y) is
+// V(x) of the sequence of parameter V(y), we get W = V((N+1)/b) by applying
+// V(k) once and then V(b) n-1 times. Then:
+//
+//		a) if V(b) of parameter W, that is V(N+1), is not 2 (mod N), N is composite
+//		b) if gcd(V(b/q) - 2, N) == 1 for every prime q dividing b, and
+//		   (b^n - 1)^2 > N, then N is proven prime (Morrison's theorem)
+//
+// When the last condition on b^n does not hold, N is only a probable prime.
+// If gcd(V(b/q) - 2, N) == N, the test is inconclusive for this P, and we
+// try the next one.
+func TestGeneralized(G *GeneralizedNumber, opts *Options) (*GeneralizedResult, error) {
+
+	// Check preconditions
+	if G == nil {
+		return nil, errors.New("Received G == nil")
+	}
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	result := &GeneralizedResult{G: G}
+
+	// Fast specialization for base 2
+	if R, ok := G.Riesel(); ok {
+		r, err := Test(R, opts)
+		if err != nil {
+			return nil, err
+		}
+		result.Prime = r.Prime
+		result.Proven = r.Prime
+		result.Residue = r.Residue
+		return result, nil
+	}
+
+	// Small numbers are decided by the Baillie-PSW test, which is exact below 2^64
+	if G.N.BitLen() <= 64 {
+		result.Prime = G.N.ProbablyPrime(0)
+		result.Proven = result.Prime
+		return result, nil
+	}
+
+	// When k and b are odd, N is even
+	if G.N.Bit(0) == 0 {
+		logScreening.Infof("N = %v is even", G)
+		return result, nil
+	}
+
+	// (b^n - 1)^2 > N, so that every prime factor of N is > sqrt(N)
+	F := new(big.Int).Sub(G.bn, one)
+	provable := F.Mul(F, F).Cmp(G.N) == 1
+
+	factors := primeFactors(G.b)
+	d := new(big.Int)
+	g := new(big.Int)
+
+	for P := int64(3); P <= maxLucasP; P++ {
+		d.SetInt64(P * P - 4)
+
+		switch big.Jacobi(d, G.N) {
+		case 1:
+			continue
+		case 0:
+			if g.GCD(nil, nil, d, G.N).Cmp(G.N) != 0 {
+				logV1.Infof("N = %v has the factor %v", G, g)
+				return result, nil
+			}
+			continue
+		}
+
+		// W = V((N+1)/b)
+		x := big.NewInt(P)
+		w := lucasV(x, G.k, G)
+		for i := int64(1); i < G.n; i++ {
+			select {
+			case <-opts.Stop:
+				return nil, ErrInterrupted
+			default:
+			}
+			w = lucasV(w, G.b, G)
+		}
+
+		// V(N+1) must be 2 (mod N)
+		v := lucasV(w, G.b, G)
+		result.P = P
+		result.Residue = residue64(v)
+		if v.Cmp(two) != 0 {
+			logUN.Infof("N = %v is composite! RES64: %016X", G, result.Residue)
+			return result, nil
+		}
+
+		inconclusive := false
+		for _, q := range factors {
+			y := lucasV(w, G.b / q, G)
+			y.Sub(y, two)
+
+			switch g.GCD(nil, nil, y.Mod(y, G.N), G.N); {
+			case g.Cmp(one) == 0:
+			case g.Cmp(G.N) == 0:
+				inconclusive = true
+			default:
+				logUN.Infof("N = %v has the factor %v", G, g)
+				return result, nil
+			}
+		}
+
+		if !inconclusive {
+			result.Prime = true
+			result.Proven = provable
+			logUN.Infof("N = %v is prime! (proven: %v)", G, provable)
+			return result, nil
+		}
+		logV1.Debugf("The test of N = %v is inconclusive with P = %v", G, P)
+	}
+
+	// V(N+1) == 2 for every P tried
+	result.Prime = true
+	logUN.Infof("N = %v is a probable prime", G)
+	return result, nil
+}
@@ -0,0 +1,228 @@
+package rieseltest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+func TestTestGeneralized(t *testing.T) {
+	var testCases = []struct {
+		k, b int64
+		nmax int64
+	}{
+		{2, 3, 200},
+		{4, 3, 200},
+		{2, 5, 150},
+		{6, 7, 120},
+		{1, 6, 150},
+		{10, 3, 200},
+		{3, 10, 120},
+		{1, 4, 150},
+		{5, 8, 150},
+	}
+
+	for _, c := range testCases {
+		for n := int64(1); n <= c.nmax; n++ {
+			G, err := NewGeneralizedNumber(c.k, c.b, n)
+			if err != nil {
+				t.Fatalf("NewGeneralizedNumber(%v, %v, %v) returned an error: %v", c.k, c.b, n, err)
+			}
+			if G.N.Cmp(two) <= 0 {
+				continue
+			}
+
+			result, err := TestGeneralized(G, nil)
+			if err != nil {
+				t.Errorf("TestGeneralized(%v) returned an error: %v", G, err)
+				continue
+			}
+
+			if expected := G.N.ProbablyPrime(20); result.Prime != expected {
+				t.Errorf("TestGeneralized(%v) returned %v, but we expected %v", G, result.Prime, expected)
+			}
+			if result.Prime && !result.Proven && G.k < G.bn.Int64() {
+				t.Errorf("TestGeneralized(%v) should prove the primality of N, but it didn't", G)
+			}
+		}
+	}
+}
+
+func TestTestGeneralizedNotProvable(t *testing.T) {
+	// k > b^n for most of these k, so that b^n is not large enough for Morrison's theorem
+	primes := 0
+	for k := int64(1) << 40; k < 1<<40+200; k++ {
+		G, _ := NewGeneralizedNumber(k, 3, 20)
+		result, err := TestGeneralized(G, nil)
+		if err != nil {
+			t.Fatalf("TestGeneralized(%v) returned an error: %v", G, err)
+		}
+
+		F := new(big.Int).Sub(G.bn, one)
+		provable := F.Mul(F, F).Cmp(G.N) == 1
+
+		expected := G.N.ProbablyPrime(20)
+		if result.Prime != expected || result.Proven != (expected && provable) {
+			t.Errorf("TestGeneralized(%v) returned prime = %v and proven = %v, but we expected %v and %v",
+				G, result.Prime, result.Proven, expected, expected && provable)
+		}
+		if expected && !provable {
+			primes++
+		}
+	}
+
+	if primes == 0 {
+		t.Errorf("No probable prime was found among the test cases")
+	}
+}
+
+func TestNewGeneralizedNumber(t *testing.T) {
+	var testCases = []struct {
+		k, b, n    int64
+		ek, eb, en int64
+	}{
+		{2, 3, 10, 2, 3, 10},
+		{18, 3, 10, 2, 3, 12},
+		{100, 10, 1, 1, 10, 3},
+	}
+
+	for _, c := range testCases {
+		G, err := NewGeneralizedNumber(c.k, c.b, c.n)
+		if err != nil {
+			t.Fatalf("NewGeneralizedNumber(%v, %v, %v) returned an error: %v", c.k, c.b, c.n, err)
+		}
+		if k, b, n := G.Params(); k != c.ek || b != c.eb || n != c.en {
+			t.Errorf("NewGeneralizedNumber(%v, %v, %v) returned %v, but we expected %v * %v^%v - 1",
+				c.k, c.b, c.n, G, c.ek, c.eb, c.en)
+		}
+	}
+
+	var errorCases = [][3]int64{{0, 3, 10}, {2, 1, 10}, {2, 3, 0}, {2, 3, MaxN}, {9, 3, MaxN / 2 - 1},
+		{2, 3, math.MaxInt64}}
+	for _, c := range errorCases {
+		if _, err := NewGeneralizedNumber(c[0], c[1], c[2]); err == nil {
+			t.Errorf("NewGeneralizedNumber(%v, %v, %v) should return an error, but it didn't", c[0], c[1], c[2])
+		}
+	}
+}
+
+func TestGeneralizedMod(t *testing.T) {
+	G, _ := NewGeneralizedNumber(7, 3, 100)
+	a := new(big.Int).Lsh(G.N, 300)
+	a.Add(a, big.NewInt(12345))
+
+	var testCases = []*big.Int{
+		big.NewInt(0),
+		new(big.Int).Set(G.N),
+		new(big.Int).Add(G.N, one),
+		new(big.Int).Mul(G.N, G.N),
+		a,
+		big.NewInt(-5),
+	}
+
+	// Products of two residues, as reduced by lucasV
+	rnd := rand.New(rand.NewSource(1))
+	for _, G2 := range []*GeneralizedNumber{G, mustGeneralized(2, 3, 2000), mustGeneralized(1, 10, 500)} {
+		for i := 0; i < 100; i++ {
+			x := new(big.Int).Rand(rnd, G2.N)
+			y := new(big.Int).Rand(rnd, G2.N)
+			testCases = append(testCases, x.Mul(x, y))
+		}
+		testCases = append(testCases, new(big.Int).Sub(new(big.Int).Mul(G2.N, G2.N), one))
+	}
+
+	for _, c := range testCases {
+		for _, G2 := range []*GeneralizedNumber{G, mustGeneralized(2, 3, 2000), mustGeneralized(1, 10, 500)} {
+			expected := new(big.Int).Mod(c, G2.N)
+			actual := new(big.Int).Set(c)
+			generalizedMod(actual, G2)
+			if actual.Cmp(expected) != 0 {
+				t.Errorf("generalizedMod(%v) for N = %v returned %v, but we expected %v", c, G2, actual, expected)
+			}
+
+			// The alternative timed by BenchmarkGeneralizedMod
+			actual.Set(c)
+			barrettMod(actual, G2, barrettMu(G2))
+			if actual.Cmp(expected) != 0 {
+				t.Errorf("barrettMod(%v) for N = %v returned %v, but we expected %v", c, G2, actual, expected)
+			}
+		}
+	}
+}
+
+// barrettMu returns 2^(2L) / N, where L is the bit length of N.
+func barrettMu(G *GeneralizedNumber) *big.Int {
+	mu := new(big.Int).Lsh(one, uint(2 * G.N.BitLen()))
+	return mu.Quo(mu, G.N)
+}
+
+// barrettMod computes (a mod N) with Barrett's method ([Ref7] 14.42), where
+// mu = barrettMu(G): with L the bit length of N, q = ((a >> (L-1)) * mu) >> (L+1)
+// is at most 2 below a / N, so that a - q * N is reduced by at most two
+// subtractions of N. It replaces the division of a.Mod by two multiplications.
+func barrettMod(a *big.Int, G *GeneralizedNumber, mu *big.Int) {
+	L := G.N.BitLen()
+	if a.Sign() < 0 || a.BitLen() > 2 * L {
+		a.Mod(a, G.N)
+		return
+	}
+
+	q := new(big.Int).Rsh(a, uint(L - 1))
+	q.Mul(q, mu)
+	q.Rsh(q, uint(L + 1))
+	a.Sub(a, q.Mul(q, G.N))
+
+	for a.Cmp(G.N) >= 0 {
+		a.Sub(a, G.N)
+	}
+}
+
+// mustGeneralized returns the GeneralizedNumber k*b^n-1, which must be valid.
+func mustGeneralized(k, b, n int64) *GeneralizedNumber {
+	G, err := NewGeneralizedNumber(k, b, n)
+	if err != nil {
+		panic(err)
+	}
+	return G
+}
+
+// benchmarkGeneralizedMod times the reduction of the square of a residue of
+// 2*3^n-1, as in lucasV, by the given function.
+func benchmarkGeneralizedMod(b *testing.B, n int64, mod func(a *big.Int, G *GeneralizedNumber)) {
+	G := mustGeneralized(2, 3, n)
+	x := new(big.Int).Rand(rand.New(rand.NewSource(1)), G.N)
+	square := new(big.Int).Mul(x, x)
+	a := new(big.Int)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mod(a.Set(square), G)
+	}
+}
+
+// BenchmarkGeneralizedMod compares generalizedMod, which is a plain a.Mod,
+// with a Barrett reduction.
+func BenchmarkGeneralizedMod(b *testing.B) {
+	for _, n := range []int64{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("Mod/n=%v", n), func(b *testing.B) { benchmarkGeneralizedMod(b, n, generalizedMod) })
+		b.Run(fmt.Sprintf("Barrett/n=%v", n), func(b *testing.B) {
+			mu := barrettMu(mustGeneralized(2, 3, n))
+			benchmarkGeneralizedMod(b, n, func(a *big.Int, G *GeneralizedNumber) { barrettMod(a, G, mu) })
+		})
+	}
+}
+
+func TestTestGeneralizedStop(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+
+	G, _ := NewGeneralizedNumber(2, 3, 1000)
+	if _, err := TestGeneralized(G, &Options{Stop: stop}); err != ErrInterrupted {
+		t.Errorf("TestGeneralized(%v) with a closed Stop returned %v, but we expected ErrInterrupted", G, err)
+	}
+}
@@ -0,0 +1,16 @@
+package rieseltest
+
+// The fixtures of TestFixtures are generated by fixturegen, with a reference
+// implementation independent of this package. New sets of h and n can be
+// added here, and all the fixtures regenerated with go generate.
+//
+// The older files of testfiles, such as v1_with_h_NOT_multiple_of_3.out and
+// h_n_large_primes.out, are not generated by fixturegen.
+
+// FixtureDigits is the number of the last decimal digits of U(2) written by
+// fixturegen in the fixtures, and checked by TestFixtures.
+const FixtureDigits = 10
+
+//go:generate go run ./fixturegen -h 1-255 -n 2-64 -o testfiles/fixtures_small.out
+//go:generate go run ./fixturegen -h 3,375,507,1095,8565 -n 65-600 -o testfiles/fixtures_h_multiple_of_3.out
+//go:generate go run ./fixturegen -h 1,5,7,1003,5035 -n 65-600 -o testfiles/fixtures_h_NOT_multiple_of_3.out
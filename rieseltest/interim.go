@@ -0,0 +1,55 @@
+package rieseltest
+
+import (
+	"fmt"
+)
+
+// An InterimResidue is the RES64 of U(Iteration) mod N, recorded while the
+// Lucas-Lehmer-Riesel test was running.
+//
+// Comparing the interim residues of two independent runs of the same test
+// locates where they diverged, without waiting for both to complete.
+type InterimResidue struct {
+	Iteration int64
+	Residue   uint64
+}
+
+// ResidueString returns the RES64 in the usual 16 hex digits format.
+func (r InterimResidue) ResidueString() string {
+	return fmt.Sprintf("%016X", r.Residue)
+}
+
+// isInterimIteration returns true if the RES64 of U(i) must be recorded
+// according to the given options.
+func isInterimIteration(i int64, opts *Options) bool {
+	if opts.InterimPowersOfTwo && i&(i-1) == 0 {
+		return true
+	}
+	return opts.InterimInterval > 0 && i%opts.InterimInterval == 0
+}
+
+// LocateDivergence compares the interim residues of two runs of the same
+// test, which must be sorted by iteration.
+//
+// It returns the last iteration recorded by both runs with the same RES64
+// (0 if there is none), and the first one recorded by both with a different
+// RES64 (0 if the runs never diverge). The runs diverged somewhere between
+// these two iterations.
+func LocateDivergence(a, b []InterimResidue) (agree int64, differ int64) {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].Iteration < b[j].Iteration:
+			i++
+		case a[i].Iteration > b[j].Iteration:
+			j++
+		case a[i].Residue != b[j].Residue:
+			return agree, a[i].Iteration
+		default:
+			agree = a[i].Iteration
+			i++
+			j++
+		}
+	}
+
+	return agree, 0
+}
@@ -0,0 +1,97 @@
+package rieseltest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+func TestInterimResidues(t *testing.T) {
+	var testCases = []struct {
+		interval    int64
+		powersOfTwo bool
+		expected    []int64
+	}{
+		{0, false, nil},
+		{500, false, []int64{500, 1000, 1500, 2000}},
+		{0, true, []int64{4, 8, 16, 32, 64, 128, 256, 512, 1024}},
+		{1000, true, []int64{4, 8, 16, 32, 64, 128, 256, 512, 1000, 1024, 2000}},
+	}
+
+	R, _ := NewRieselNumber(507, 2005)
+	for _, c := range testCases {
+		opts := DefaultOptions()
+		opts.InterimInterval = c.interval
+		opts.InterimPowersOfTwo = c.powersOfTwo
+
+		result, err := Test(R, opts)
+		if err != nil {
+			t.Fatalf("Test(%v) returned an error: %v", R, err)
+		}
+
+		var actual []int64
+		for _, r := range result.Interim {
+			actual = append(actual, r.Iteration)
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("Test(%v) with interval %v and powers of two %v recorded U(i) for i in %v, but we expected %v",
+				R, c.interval, c.powersOfTwo, actual, c.expected)
+		}
+	}
+}
+
+func TestInterimResiduesAfterResume(t *testing.T) {
+	R, _ := NewRieselNumber(507, 2005)
+	opts := DefaultOptions()
+	opts.InterimInterval = 250
+	reference, _ := Test(R, opts)
+
+	// Save a checkpoint at U(1000) with the interim residues recorded so far
+	path := filepath.Join(t.TempDir(), "interim.ckpt")
+	var interim []InterimResidue
+	u, _ := GenU2(R, reference.V1)
+	genUNFrom(R, u, 3, func(i int64, u *big.Int) error {
+		if i%250 == 0 {
+			interim = append(interim, InterimResidue{Iteration: i, Residue: residue64(u)})
+		}
+		if i == 1000 {
+			SaveCheckpoint(path, &Checkpoint{H: R.h, N: R.n, V1: reference.V1, Iteration: i, U: u, Interim: interim})
+			return ErrInterrupted
+		}
+		return nil
+	})
+
+	opts.CheckpointFile = path
+	result, err := Test(R, opts)
+	if err != nil || result.ResumedFrom != 1000 {
+		t.Fatalf("Test(%v) should resume from U(1000), but we got %+v, %v", R, result, err)
+	}
+	if !reflect.DeepEqual(result.Interim, reference.Interim) {
+		t.Errorf("The resumed Test(%v) recorded %v, but we expected %v", R, result.Interim, reference.Interim)
+	}
+}
+
+func TestLocateDivergence(t *testing.T) {
+	var testCases = []struct {
+		a, b          []InterimResidue
+		agree, differ int64
+	}{
+		{nil, nil, 0, 0},
+		{[]InterimResidue{{10, 1}, {20, 2}}, []InterimResidue{{10, 1}, {20, 2}}, 20, 0},
+		{[]InterimResidue{{10, 1}, {20, 2}, {30, 3}}, []InterimResidue{{10, 1}, {20, 5}, {30, 6}}, 10, 20},
+		{[]InterimResidue{{10, 7}, {20, 2}}, []InterimResidue{{10, 1}, {20, 2}}, 0, 10},
+		{[]InterimResidue{{8, 1}, {10, 2}, {16, 3}}, []InterimResidue{{10, 2}, {15, 4}, {16, 5}}, 10, 16},
+	}
+
+	for _, c := range testCases {
+		agree, differ := LocateDivergence(c.a, c.b)
+		if agree != c.agree || differ != c.differ {
+			t.Errorf("LocateDivergence(%v, %v) returned %v, %v, but we expected %v, %v",
+				c.a, c.b, agree, differ, c.agree, c.differ)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// Package knownprimes holds a database of the known primes of the form h*2^n-1,
+// so that they can be reported without being tested again.
+//
+// A copy of the database is embedded in goprime. It can be extended by
+// importing lists of primes, and the result saved to a file which is then used
+// instead of the embedded copy. A database file is a plain text file:
+//
+//	goprime known primes 1
+//	revision R
+//	h n
+//	...
+//
+// where the first line gives the version of the format, and R is the revision
+// of the data, incremented by every import that adds primes. Every following
+// line gives the h and n of a prime, with h odd.
+package knownprimes
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// header is the first line of every database file.
+const header = "goprime known primes 1"
+
+//go:embed known_primes.txt
+var embedded string
+
+// A DB is a database of known primes h*2^n-1. It can be used by several
+// goroutines at once.
+type DB struct {
+	mu       sync.RWMutex
+	revision int64
+	primes   map[int64][]int64 // sorted n of the primes, by h
+	size     int
+}
+
+// New returns an empty database.
+func New() *DB {
+	return &DB{primes: make(map[int64][]int64)}
+}
+
+var (
+	embeddedOnce sync.Once
+	embeddedDB   *DB
+)
+
+// Embedded returns the database embedded in goprime. It is parsed on first use,
+// and shared by all its users: Open must be used to get a copy to modify.
+func Embedded() *DB {
+	embeddedOnce.Do(func() {
+		var err error
+		if embeddedDB, err = Load(strings.NewReader(embedded)); err != nil {
+			panic(fmt.Sprintf("The embedded known primes database is invalid: %v", err))
+		}
+	})
+	return embeddedDB
+}
+
+// Load reads a database file.
+func Load(r io.Reader) (*DB, error) {
+	s := bufio.NewScanner(r)
+	if !s.Scan() || s.Text() != header {
+		return nil, errors.New("Not a known primes database")
+	}
+
+	db := New()
+	if !s.Scan() {
+		return nil, errors.New("Missing revision in the known primes database")
+	}
+	if _, err := fmt.Sscanf(s.Text(), "revision %d", &db.revision); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid revision in the known primes database: %v", err))
+	}
+
+	if _, err := db.read(s, 2); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// LoadFile reads the database file at path.
+func LoadFile(path string) (*DB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	db, err := Load(file)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%v: %v", path, err))
+	}
+	return db, nil
+}
+
+// Open reads the database file at path or, if it does not exist, returns a
+// copy of the embedded database.
+func Open(path string) (*DB, error) {
+	db, err := LoadFile(path)
+	if os.IsNotExist(err) {
+		return Load(strings.NewReader(embedded))
+	}
+	return db, err
+}
+
+// Import adds the primes listed in r, one "h n" pair per line, skipping the
+// blank lines and the lines starting with '#'. The h need not be odd. If some
+// primes were new, the revision of the database is incremented.
+//
+// Import returns the number of new primes.
+func (db *DB) Import(r io.Reader) (int, error) {
+	added, err := db.read(bufio.NewScanner(r), 0)
+	if added > 0 {
+		db.mu.Lock()
+		db.revision++
+		db.mu.Unlock()
+	}
+	return added, err
+}
+
+// read adds the "h n" pairs read by s, whose first line is the given line.
+func (db *DB) read(s *bufio.Scanner, line int) (int, error) {
+	added := 0
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return added, errors.New(fmt.Sprintf("Line %v: expected \"h n\", but found %q", line, text))
+		}
+		h, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return added, errors.New(fmt.Sprintf("Line %v: %v", line, err))
+		}
+		n, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return added, errors.New(fmt.Sprintf("Line %v: %v", line, err))
+		}
+
+		ok, err := db.Add(h, n)
+		if err != nil {
+			return added, errors.New(fmt.Sprintf("Line %v: %v", line, err))
+		}
+		if ok {
+			added++
+		}
+	}
+	return added, s.Err()
+}
+
+// normalize makes h odd by moving its powers of two over 2^n, like
+// rieseltest.NewRieselNumber.
+func normalize(h, n int64) (int64, int64) {
+	for h > 0 && h%2 == 0 {
+		h /= 2
+		n++
+	}
+	return h, n
+}
+
+// Add records that h*2^n-1 is prime, and returns true if it was not known.
+//
+// This function requires:
+//		a) h >= 1
+//		b) n >= 2
+func (db *DB) Add(h, n int64) (bool, error) {
+	if h < 1 || n < 2 {
+		return false, errors.New(fmt.Sprintf("Expected h >= 1 and n >= 2, but received h = %v and n = %v", h, n))
+	}
+	h, n = normalize(h, n)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	ns := db.primes[h]
+	i := sort.Search(len(ns), func(i int) bool { return ns[i] >= n })
+	if i < len(ns) && ns[i] == n {
+		return false, nil
+	}
+
+	ns = append(ns, 0)
+	copy(ns[i+1:], ns[i:])
+	ns[i] = n
+	db.primes[h] = ns
+	db.size++
+	return true, nil
+}
+
+// Contains returns true if h*2^n-1 is a known prime.
+func (db *DB) Contains(h, n int64) bool {
+	h, n = normalize(h, n)
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ns := db.primes[h]
+	i := sort.Search(len(ns), func(i int) bool { return ns[i] >= n })
+	return i < len(ns) && ns[i] == n
+}
+
+// Primes returns the n of the known primes h*2^n-1 with nmin <= n <= nmax, in
+// increasing order. h must be odd.
+func (db *DB) Primes(h, nmin, nmax int64) []int64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var found []int64
+	for _, n := range db.primes[h] {
+		if n >= nmin && n <= nmax {
+			found = append(found, n)
+		}
+	}
+	return found
+}
+
+// Len returns the number of primes in the database.
+func (db *DB) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.size
+}
+
+// Revision returns the revision of the data of the database.
+func (db *DB) Revision() int64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.revision
+}
+
+// Write writes the database file, with the primes sorted by n and then by h.
+func (db *DB) Write(w io.Writer) error {
+	db.mu.RLock()
+	type prime struct{ h, n int64 }
+	primes := make([]prime, 0, db.size)
+	for h, ns := range db.primes {
+		for _, n := range ns {
+			primes = append(primes, prime{h, n})
+		}
+	}
+	revision := db.revision
+	db.mu.RUnlock()
+
+	sort.Slice(primes, func(i, j int) bool {
+		if primes[i].n != primes[j].n {
+			return primes[i].n < primes[j].n
+		}
+		return primes[i].h < primes[j].h
+	})
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, header)
+	fmt.Fprintln(b, "revision", revision)
+	for _, p := range primes {
+		fmt.Fprintln(b, p.h, p.n)
+	}
+	return b.Flush()
+}
+
+// WriteFile writes the database file at path, atomically.
+func (db *DB) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	err = db.Write(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
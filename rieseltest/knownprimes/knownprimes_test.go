@@ -0,0 +1,91 @@
+package knownprimes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEmbedded(t *testing.T) {
+	db := Embedded()
+	if db.Len() == 0 || db.Revision() < 1 {
+		t.Fatalf("The embedded database has %v primes and revision %v", db.Len(), db.Revision())
+	}
+
+	var testCases = []struct {
+		h, n     int64
+		expected bool
+	}{
+		{3, 6090515, true},
+		{353159, 4331116, true},
+		{2165, 7030, true},
+		{4330, 7029, true}, // 2165 * 2^7030 - 1 with an even h
+		{3, 6090514, false},
+		{2165, 7031, false},
+	}
+
+	for _, c := range testCases {
+		if actual := db.Contains(c.h, c.n); actual != c.expected {
+			t.Errorf("Contains(%v, %v) == %v, but we expected %v", c.h, c.n, actual, c.expected)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	db := New()
+	added, err := db.Import(strings.NewReader("# some primes\n3 1274\n\n8565 15\n6 1273\n"))
+	if err != nil {
+		t.Fatalf("Import returned an error: %v", err)
+	}
+	if added != 2 || db.Len() != 2 || db.Revision() != 1 {
+		t.Errorf("Import added %v primes, the database has %v primes and revision %v, but we expected 2, 2 "+
+			"and 1", added, db.Len(), db.Revision())
+	}
+
+	// Importing known primes does not change the revision
+	if added, _ = db.Import(strings.NewReader("8565 15\n")); added != 0 || db.Revision() != 1 {
+		t.Errorf("Importing a known prime added %v primes and set revision %v", added, db.Revision())
+	}
+
+	for _, text := range []string{"3\n", "3 x\n", "0 5\n", "3 1\n"} {
+		if _, err := db.Import(strings.NewReader(text)); err == nil {
+			t.Errorf("Import accepted %q", text)
+		}
+	}
+}
+
+func TestWriteLoad(t *testing.T) {
+	db := New()
+	db.Import(strings.NewReader("8565 15\n3 1274\n375 9\n"))
+
+	var b bytes.Buffer
+	if err := db.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := "goprime known primes 1\nrevision 1\n375 9\n8565 15\n3 1274\n"
+	if b.String() != expected {
+		t.Errorf("Write wrote %q, but we expected %q", b.String(), expected)
+	}
+
+	loaded, err := Load(&b)
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if loaded.Len() != 3 || loaded.Revision() != 1 || !loaded.Contains(375, 9) {
+		t.Errorf("Load read %v primes and revision %v", loaded.Len(), loaded.Revision())
+	}
+
+	if _, err := Load(strings.NewReader("375 9\n")); err == nil {
+		t.Errorf("Load accepted a file without header")
+	}
+}
+
+func TestPrimes(t *testing.T) {
+	db := New()
+	db.Import(strings.NewReader("3 1274\n3 2\n3 3318\n5 148\n"))
+
+	actual := db.Primes(3, 3, 5000)
+	if len(actual) != 2 || actual[0] != 1274 || actual[1] != 3318 {
+		t.Errorf("Primes(3, 3, 5000) == %v, but we expected [1274 3318]", actual)
+	}
+}
@@ -3,8 +3,15 @@ package rieseltest
 import (
 	"github.com/op/go-logging"
 	"gopkg.in/natefinch/lumberjack.v2"
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,52 +19,258 @@ import (
 var log = logging.MustGetLogger("rieseltest")
 var loggingEnabled = false
 
-// ConfigureLogger allows to enable file and terminal outputs for the log messages
+// Loggers of the phases of the test, so that each phase can be logged with
+// its own level (see LogConfig.PhaseLevels).
+var (
+	logScreening  = logging.MustGetLogger(PhaseScreening)
+	logV1         = logging.MustGetLogger(PhaseV1)
+	logU2         = logging.MustGetLogger(PhaseU2)
+	logUN         = logging.MustGetLogger(PhaseUN)
+	logCheckpoint = logging.MustGetLogger(PhaseCheckpoint)
+)
+
+// Phases of the test, which are the modules of their loggers. The other
+// modules are "rieseltest" and the names of the other packages of goprime.
+const (
+	PhaseScreening  = "screening"	// screening of the small factors, known primes
+	PhaseV1         = "v1"		// generation of V(1)
+	PhaseU2         = "u2"		// generation of U(2) = V(h)
+	PhaseUN         = "un"		// iterations up to U(n), and verdict
+	PhaseCheckpoint = "checkpoint"	// checkpoints saved and resumed
+)
+
+// Formats of the logs.
+const (
+	LogText = "text"	// one line of text per message
+	LogJSON = "json"	// one JSON object per message, with its fields
+)
+
+// Default values of a LogConfig.
+const (
+	DefaultLogDir        = ".logs"
+	DefaultLogMaxSize    = 100	// megabytes
+	DefaultLogMaxBackups = 2
+	DefaultLogMaxAge     = 3	// days
+)
+
+// LogConfig describes where and how the log messages are written.
+//
 // The available logging levels are:
 //
 // 		DEBUG < INFO < NOTICE < WARNING < ERROR < CRITICAL
 //
 // It is not recommended to set the level of any backend to DEBUG unless for very specific
 // debugging reasons. If you do so, expect a lot of logs and a considerable slowing down in performance.
+type LogConfig struct {
+
+	// Terminal enables the logs of level TerminalLevel or higher on stderr, so
+	// that they are not mixed with the results printed on stdout.
+	Terminal      bool
+	TerminalLevel logging.Level
+
+	// File enables the logs of level FileLevel or higher in the rolling log
+	// files of Dir, named after the day they were started.
+	File      bool
+	FileLevel logging.Level
+
+	// Dir is the directory of the log files. When empty, DefaultLogDir is used.
+	Dir string
+
+	// Rotation policy of the log files: a file is rotated once it reaches
+	// MaxSize megabytes, and the rotated files are removed once there are more
+	// than MaxBackups of them or once they are older than MaxAge days. The
+	// rotated files are compressed if Compress is true. When 0, the defaults
+	// are used, and a negative MaxBackups or MaxAge keeps all the files.
+	MaxSize    int
+	MaxBackups int
+	MaxAge     int
+	Compress   bool
+
+	// Format is LogText or LogJSON. When empty, LogText is used.
+	Format string
+
+	// PhaseLevels overrides the level of the given phases or modules, in both
+	// the terminal and the file logs.
+	PhaseLevels map[string]logging.Level
+}
+
+// fileLogger is the rolling file of the logs, when they are written to a file.
+// It is guarded by fileLoggerMu, since RotateLogs is called on SIGHUP.
+var (
+	fileLoggerMu sync.Mutex
+	fileLogger   *lumberjack.Logger
+)
+
+// ConfigureLogger allows to enable file and terminal outputs for the log messages,
+// with the default settings of LogConfig. See ConfigureLogging for the other settings.
 func ConfigureLogger(file bool, fileLevel logging.Level, terminal bool, terminalLevel logging.Level) {
-	var backendList []logging.Backend
+	ConfigureLogging(&LogConfig{File: file, FileLevel: fileLevel, Terminal: terminal,
+		TerminalLevel: terminalLevel})
+}
+
+// ConfigureLogging configures the terminal and the file outputs of the log
+// messages. An error is returned if the configuration is not valid, in which
+// case the current configuration is kept.
+func ConfigureLogging(c *LogConfig) error {
 
-	if terminal || file { loggingEnabled = true }
+	// Check preconditions
+	if c.Format != "" && c.Format != LogText && c.Format != LogJSON {
+		return errors.New(fmt.Sprintf("Unknown log format %v, expected %v or %v", c.Format, LogText, LogJSON))
+	}
+	if c.MaxSize < 0 {
+		return errors.New(fmt.Sprintf("Expected a log MaxSize >= 0, but received %v", c.MaxSize))
+	}
+
+	var backendList []logging.Backend
 
-	if terminal {
+	if c.Terminal {
 		// Set a custom formatting for the logs sent to the terminal
 		terminalFormat := logging.MustStringFormatter("[%{shortfunc}] %{time:15:04:05.000} -> %{message}")
-		terminalBackend := logging.NewLogBackend(os.Stdout, "", 0)
-		terminalBackendFormatted := logging.NewBackendFormatter(terminalBackend, terminalFormat)
+		backendList = append(backendList, newLogBackend(os.Stderr, c, terminalFormat, c.TerminalLevel))
+	}
 
-		// Only messages of the specified minimum level or higher should be sent to the terminal backend
-		terminalBackendLeveled := logging.AddModuleLevel(terminalBackendFormatted)
-		terminalBackendLeveled.SetLevel(terminalLevel, "")
+	var rolling *lumberjack.Logger
+	if c.File {
+		dir := c.Dir
+		if dir == "" {
+			dir = DefaultLogDir
+		}
 
-		backendList = append(backendList, terminalBackendLeveled)
-	}
+		// Let the file logs be saved on a rolling basis
+		rolling = &lumberjack.Logger{
+			Filename:   filepath.Join(dir, fmt.Sprintf("logFile.%d%.2d%.2d.log",
+				time.Now().Year(), time.Now().Month(), time.Now().Day())),
+			MaxSize:    withDefault(c.MaxSize, DefaultLogMaxSize),
+			MaxBackups: withDefault(c.MaxBackups, DefaultLogMaxBackups),
+			MaxAge:     withDefault(c.MaxAge, DefaultLogMaxAge),
+			Compress:   c.Compress,
+		}
 
-	if file {
 		// Set a custom formatting for the logs sent to files
 		fileFormat := logging.MustStringFormatter("[%{pid} - %{shortfunc}] %{time:15:04:05.000} -> %{message}")
+		backendList = append(backendList, newLogBackend(rolling, c, fileFormat, c.FileLevel))
+	}
 
-		// Let the file logs be saved on a rolling basis
-		fileBackend := logging.NewLogBackend(&lumberjack.Logger{
-			Filename:   fmt.Sprintf(".logs/logFile.%d%.2d%.2d.log",
-				time.Now().Year(), time.Now().Month(), time.Now().Day()),
-			MaxSize:    100, // megabytes
-			MaxBackups: 2,
-			MaxAge:     3, //days
-		}, "", 0)
+	// Set the enabled backends for the logger
+	fileLoggerMu.Lock()
+	if old := fileLogger; old != nil && old != rolling {
+		old.Close()
+	}
+	fileLogger = rolling
+	fileLoggerMu.Unlock()
 
-		// Only messages of the specified minimum level or higher should be sent to the file backend
-		fileBackendFormatted := logging.NewBackendFormatter(fileBackend, fileFormat)
-		fileBackendLeveled := logging.AddModuleLevel(fileBackendFormatted)
-		fileBackendLeveled.SetLevel(fileLevel, "")
+	loggingEnabled = c.Terminal || c.File
+	logging.SetBackend(backendList...)
+	return nil
+}
 
-		backendList = append(backendList, fileBackendLeveled)
+// withDefault returns the default value d for a setting of 0, and 0 (which
+// means no limit for lumberjack) for a negative setting.
+func withDefault(v, d int) int {
+	switch {
+	case v == 0:
+		return d
+	case v < 0:
+		return 0
 	}
+	return v
+}
 
-	// Set the enabled backends for the logger
-	logging.SetBackend(backendList...)
+// newLogBackend returns a backend writing the messages of the given level or
+// higher to w, in the format of c, with the levels of c.PhaseLevels.
+func newLogBackend(w io.Writer, c *LogConfig, textFormat logging.Formatter, level logging.Level) logging.LeveledBackend {
+	var backend logging.Backend
+	if c.Format == LogJSON {
+		backend = &jsonBackend{w: w}
+	} else {
+		backend = logging.NewBackendFormatter(logging.NewLogBackend(w, "", 0), textFormat)
+	}
+
+	// Only messages of the specified minimum level or higher should be sent to the backend
+	leveled := logging.AddModuleLevel(backend)
+	leveled.SetLevel(level, "")
+	for phase, l := range c.PhaseLevels {
+		leveled.SetLevel(l, phase)
+	}
+	return leveled
+}
+
+// RotateLogs closes the current log file and starts a new one, as after a
+// SIGHUP. It does nothing when the logs are not written to a file.
+func RotateLogs() error {
+	fileLoggerMu.Lock()
+	defer fileLoggerMu.Unlock()
+
+	if fileLogger == nil {
+		return nil
+	}
+	return fileLogger.Rotate()
+}
+
+// A logField is a named value of a log message. It is printed as its value in
+// the text logs, and as a field of its own in the JSON logs.
+type logField struct {
+	name  string
+	value interface{}
+}
+
+func (f logField) String() string {
+	return fmt.Sprint(f.value)
+}
+
+// iterationField is the iteration i of U(i) in a log message.
+func iterationField(i int64) logField {
+	return logField{"iteration", i}
+}
+
+// residueField is a RES64 residue in a log message.
+func residueField(r uint64) logField {
+	return logField{"residue", fmt.Sprintf("%016X", r)}
+}
+
+// numberField is the tested number in a log message.
+func numberField(R *RieselNumber) logField {
+	return logField{"number", R.String()}
+}
+
+// jsonBackend writes every log message as a JSON object on its own line:
+//
+//		{"time":"...","level":"INFO","phase":"un","func":"Test","pid":42,"message":"...","iteration":8}
+//
+// The phase is the module of the logger, and the logFields of the message
+// are added as fields of the object.
+type jsonBackend struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (b *jsonBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	fields := map[string]interface{}{
+		"time":    rec.Time.UTC().Format(time.RFC3339Nano),
+		"level":   level.String(),
+		"phase":   rec.Module,
+		"pid":     os.Getpid(),
+		"message": rec.Message(),
+	}
+	if pc, _, _, ok := runtime.Caller(calldepth + 1); ok {
+		if f := runtime.FuncForPC(pc); f != nil {
+			name := f.Name()
+			fields["func"] = name[strings.LastIndex(name, ".") + 1:]
+		}
+	}
+	for _, arg := range rec.Args {
+		if f, ok := arg.(logField); ok {
+			fields[f.name] = f.value
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err = b.w.Write(append(data, '\n'))
+	return err
 }
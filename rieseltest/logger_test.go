@@ -0,0 +1,95 @@
+package rieseltest
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/op/go-logging"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	defer ConfigureLogger(false, 0, false, 0)
+
+	dir := t.TempDir()
+	err := ConfigureLogging(&LogConfig{File: true, FileLevel: logging.WARNING, Dir: dir, Format: LogJSON,
+		PhaseLevels: map[string]logging.Level{PhaseUN: logging.INFO}})
+	if err != nil {
+		t.Fatalf("ConfigureLogging returned an error: %v", err)
+	}
+
+	R, _ := NewRieselNumber(3, 1274)
+	if _, err := Test(R, &Options{V1Method: RODSETH, InterimInterval: 500}); err != nil {
+		t.Fatalf("Test(%v) returned an error: %v", R, err)
+	}
+	ConfigureLogger(false, 0, false, 0)
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "logFile.*.log"))
+	if len(paths) != 1 {
+		t.Fatalf("Expected one log file in %v, but found %v", dir, paths)
+	}
+	file, err := os.Open(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var interim []map[string]interface{}
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(s.Bytes(), &fields); err != nil {
+			t.Fatalf("The log line %q is not a JSON object: %v", s.Text(), err)
+		}
+		if fields["phase"] != PhaseUN {
+			t.Errorf("The log line %q should have been filtered out by the WARNING level", s.Text())
+		}
+		if _, ok := fields["iteration"]; ok {
+			interim = append(interim, fields)
+		}
+	}
+
+	if len(interim) != 2 {
+		t.Fatalf("Expected the interim residues of U(500) and U(1000) in the logs, but found %v", interim)
+	}
+	for i, fields := range interim {
+		if fields["iteration"] != float64(500 * (i + 1)) || fields["level"] != "INFO" || fields["func"] == "" ||
+			len(fields["residue"].(string)) != 16 {
+			t.Errorf("Unexpected fields of the interim residue in the logs: %v", fields)
+		}
+	}
+}
+
+func TestConfigureLoggingErrors(t *testing.T) {
+	defer ConfigureLogger(false, 0, false, 0)
+
+	for _, c := range []*LogConfig{{Format: "xml"}, {File: true, MaxSize: -1}} {
+		if err := ConfigureLogging(c); err == nil {
+			t.Errorf("ConfigureLogging(%+v) did not return an error", c)
+		}
+	}
+}
+
+func TestRotateLogsConcurrently(t *testing.T) {
+	defer ConfigureLogger(false, 0, false, 0)
+
+	dir := t.TempDir()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if err := RotateLogs(); err != nil {
+				t.Errorf("RotateLogs returned an error: %v", err)
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if err := ConfigureLogging(&LogConfig{File: i % 2 == 0, FileLevel: logging.WARNING, Dir: dir}); err != nil {
+			t.Fatalf("ConfigureLogging returned an error: %v", err)
+		}
+	}
+	<-done
+}
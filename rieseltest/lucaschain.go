@@ -0,0 +1,234 @@
+package rieseltest
+
+import (
+	"errors"
+	"fmt"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// GenU2 available algorithms
+const (
+	LADDER uint8 = iota
+	PRAC
+)
+
+// maxPracH is the largest h for which genU2Prac uses a PRAC chain, so that the
+// arithmetic on d and e never overflows an int64. Larger h use the ladder.
+const maxPracH = 1 << 60
+
+// pracWindow is the number of r tried on each side of h / golden ratio.
+const pracWindow = 16
+
+// invGoldenRatio is 1 / ((1 + sqrt(5)) / 2).
+const invGoldenRatio = 0.61803398874989484820
+
+// pracRule is one of the rules of Table 4 of Montgomery's PRAC algorithm.
+type pracRule uint8
+
+// pracCost is the number of multiplications mod N performed by each rule.
+var pracCost = [10]int{0, 3, 2, 1, 2, 2, 4, 4, 4, 2}
+
+// pracRules computes the PRAC chain for V(h) starting from the given r, with
+// h / 2 < r < h, and returns its rules and its cost in multiplications mod N.
+// The last return value is false if r does not lead to a valid chain.
+//
+// The chain keeps A = V(a), B = V(b) and C = V(a - b) while reducing the pair
+// (d, e), with the invariant h = d * a + e * b. It starts from a = 2, b = 1,
+// d = h - r and e = 2 * r - h, and ends when d == e == 1, where V(h) = V(a + b).
+func pracRules(h, r int64) ([]pracRule, int, bool) {
+	if r <= h / 2 || r >= h || gcd(h, r) != 1 {
+		return nil, 0, false
+	}
+
+	var rules []pracRule
+	cost := 2	// the initial doubling and the final addition
+	d := h - r
+	e := 2 * r - h
+
+	for d != e {
+		if d < e {
+			d, e = e, d
+			rules = append(rules, 0)
+		}
+
+		var rule pracRule
+		switch {
+		case 4 * d <= 5 * e && (d + e) % 3 == 0:
+			rule = 1
+			d, e = (2 * d - e) / 3, (2 * e - d) / 3
+		case 4 * d <= 5 * e && (d - e) % 6 == 0:
+			rule = 2
+			d = (d - e) / 2
+		case d <= 4 * e:
+			rule = 3
+			d -= e
+		case (d + e) % 2 == 0:
+			rule = 4
+			d = (d - e) / 2
+		case d % 2 == 0:
+			rule = 5
+			d /= 2
+		case d % 3 == 0:
+			rule = 6
+			d = d / 3 - e
+		case (d + e) % 3 == 0:
+			rule = 7
+			d = (d - 2 * e) / 3
+		case (d - e) % 3 == 0:
+			rule = 8
+			d = (d - e) / 3
+		case e % 2 == 0:
+			rule = 9
+			e /= 2
+		default:
+			return nil, 0, false
+		}
+
+		rules = append(rules, rule)
+		cost += pracCost[rule]
+	}
+
+	return rules, cost, d == 1
+}
+
+// pracChain returns the cheapest PRAC chain found for V(h), trying the r
+// closest to h / golden ratio. The second return value is false if no chain
+// was found.
+func pracChain(h int64) ([]pracRule, bool) {
+	var best []pracRule
+	bestCost := -1
+
+	r0 := int64(float64(h) * invGoldenRatio + 0.5)
+	for r := r0 - pracWindow; r <= r0 + pracWindow; r++ {
+		rules, cost, ok := pracRules(h, r)
+		if ok && (bestCost == -1 || cost < bestCost) {
+			best, bestCost = rules, cost
+		}
+	}
+
+	return best, bestCost != -1
+}
+
+// gcd returns the greatest common divisor of a, b >= 0.
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a % b
+	}
+	return a
+}
+
+// genU2Prac computes U(2) = V(h) like GenU2, but evaluates V(h) with a Lucas
+// chain found by Montgomery's PRAC algorithm [Ref6] instead of the binary
+// ladder.
+//
+// This function requires:
+//		a) n >= 2
+//		b) h >= 1
+//		c) h mod 2 == 1
+//		d) v1 >= 3
+//
+// Since V(x + y) = V(x) * V(y) - V(x - y), V(x + y) can be computed from V(x),
+// V(y) and V(x - y) with one multiplication, and V(2 * x) with one squaring.
+// The binary ladder always costs two multiplications per bit of h, while a
+// PRAC chain costs about 1.5 per bit. The ladder computes its two
+// multiplications in parallel, though, so PRAC is only faster in terms of
+// total work, which matters when several tests run at once.
+func genU2Prac(R *RieselNumber, v1 int64) (*big.Int, error) {
+
+	// Check preconditions
+	if R.h < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected h >= 1, but received h = %v", R.h))
+	}
+	if R.n < 2 {
+		return nil, errors.New(fmt.Sprintf("Expected n >= 2, but received n = %v", R.n))
+	}
+	if R.h % 2 == 0 {
+		return nil, errors.New(fmt.Sprintf("Expected odd h, but received h = %v", R.h))
+	}
+	if v1 < 3 {
+		return nil, errors.New(fmt.Sprintf("Expected v1 >= 3, but received v1 = %v", v1))
+	}
+
+	if R.h == 1 || R.h > maxPracH {
+		return GenU2(R, v1)
+	}
+
+	rules, ok := pracChain(R.h)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Could not find a Lucas chain for h = %v", R.h))
+	}
+
+	// add returns V(x + y) = V(x) * V(y) - V(x - y) mod N
+	add := func(vX, vY, vXMinusY *big.Int) *big.Int {
+		tmp := new(big.Int).Mul(vX, vY)
+		tmp.Sub(tmp, vXMinusY)
+		if tmp.Sign() < 0 {
+			tmp.Add(tmp, R.N)
+		}
+		rieselMod(tmp, R)
+		return tmp
+	}
+
+	// double returns V(2 * x) = V(x)^2 - 2 mod N
+	double := func(vX *big.Int) *big.Int {
+		tmp := new(big.Int).Mul(vX, vX)
+		tmp.Sub(tmp, two)
+		if tmp.Sign() < 0 {
+			tmp.Add(tmp, R.N)
+		}
+		rieselMod(tmp, R)
+		return tmp
+	}
+
+	// A = V(2), B = V(1), C = V(1)
+	B := new(big.Int).SetInt64(v1)
+	rieselMod(B, R)
+	C := new(big.Int).Set(B)
+	A := double(B)
+
+	for _, rule := range rules {
+
+		// In the comments below, A = V(a), B = V(b) and C = V(a - b)
+		switch rule {
+		case 0:
+			A, B = B, A
+		case 1:
+			// A = V(2a + b), B = V(a + 2b)
+			T := add(A, B, C)
+			A, B = add(T, A, B), add(B, T, A)
+		case 2, 4:
+			// A = V(2a), B = V(a + b)
+			A, B = double(A), add(A, B, C)
+		case 3:
+			// B = V(a + b), C = V(b)
+			B, C = add(B, A, C), B
+		case 5:
+			// A = V(2a), C = V(2a - b)
+			A, C = double(A), add(C, A, B)
+		case 6:
+			// A = V(3a), B = V(3a + b), C = V(b)
+			T := double(A)
+			T2 := add(A, B, C)
+			A, B, C = add(T, A, A), add(T, T2, C), B
+		case 7:
+			// A = V(3a), B = V(2a + b)
+			T := add(A, B, C)
+			A, B = add(A, double(A), A), add(T, A, B)
+		case 8:
+			// A = V(3a), B = V(a + b), C = V(2a - b)
+			T := add(A, B, C)
+			A, B, C = add(A, double(A), A), T, add(C, A, B)
+		case 9:
+			// B = V(2b), C = V(a - 2b)
+			B, C = double(B), add(C, B, A)
+		}
+
+		if loggingEnabled { logU2.Debugf("rule %v: A = %v, B = %v", rule, getLastDigits(A), getLastDigits(B)) }
+	}
+
+	// At this point d == e == 1, so h = a + b
+	return add(A, B, C), nil
+}
@@ -3,6 +3,7 @@ package rieseltest
 import (
 	"errors"
 	"math"
+	"math/bits"
 	"fmt"
 
 	big "math/big"
@@ -14,6 +15,7 @@ var zero = new(big.Int).SetInt64(0)
 var one = new(big.Int).SetInt64(1)
 var two = new(big.Int).SetInt64(2)
 var maxInt64 = new(big.Int).SetInt64(math.MaxInt64)
+var maxUint64 = new(big.Int).SetUint64(math.MaxUint64)
 
 // lowerNonZeroBit returns the position of the lower non zero bit of a given number.
 // The position is counted from least significant bit starting from 0.
@@ -103,12 +105,24 @@ func lround(a float64) int64 {
 // In other words: given x, it computes d, without square factor, and b, such as:
 // 		x = d*b^2
 //
+// The sign of x is kept in d, and reduce(0) = (1, 0).
+//
 // NOTE: This function has been copied from the LLR software's "reduce" [Ref3].
 func reduce(x int64) (b int64, d int64) {
 
+	// Handle negative numbers through their absolute value. The absolute value
+	// of math.MinInt64 = -2 * (2^31)^2 does not fit in an int64.
+	if x == math.MinInt64 {
+		return 1 << 31, -2
+	}
+	if x < 0 {
+		b, d = reduce(-x)
+		return b, -d
+	}
+
 	// Handle small easy cases
 	if x < 4 {
-		return x, 1
+		return 1, x
 	}
 
 	d, b = x, 1
@@ -120,11 +134,12 @@ func reduce(x int64) (b int64, d int64) {
 	}
 
 	for div := int64(3); ; div += 2 {
-		sq := div * div
 
-		if sq > d {
+		// Stop when div^2 > d, without computing div^2, which could overflow
+		if div > d / div {
 			break
 		}
+		sq := div * div
 
 		// Divide d by even powers of increasing odd factors.
 		for d % sq == 0 {
@@ -159,6 +174,9 @@ func isPerfectSquare(n int64) (bool, error) {
 // modExp performs a modular exponentiation to compute (base^exponent mod modulus)
 // using the right-to-left binary method.
 //
+// The result is always in [0, modulus), even for a negative base, and the
+// products are computed on 128 bits so that they do not overflow.
+//
 // This function requires:
 //		a) modulus > 0
 //		b) exponent >= 0
@@ -175,36 +193,46 @@ func modExp(base, exponent, modulus int64) (int64, error) {
 		return 0, errors.New(fmt.Sprintf("Expected exponent >= 0, but received exponent = %v", exponent))
 	}
 
-	if base == 0 || modulus == 1 { return 0, nil }
+	if modulus == 1 { return 0, nil }
 	result := int64(1)
 	base = base % modulus
+	if base < 0 {
+		base += modulus
+	}
 
 	for exponent > 0 {
 		if exponent&1 == 1 {
-			result = (result * base) % modulus
+			result = mulMod(result, base, modulus)
 		}
 
 		exponent >>= 1
-		base = (base * base) % modulus
+		base = mulMod(base, base, modulus)
 	}
 
 	return result, nil
 }
 
+// mulMod computes (a * b mod modulus) for 0 <= a, b < modulus, without overflow.
+func mulMod(a, b, modulus int64) int64 {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	return int64(bits.Rem64(hi, lo, uint64(modulus)))
+}
+
 // rieselMod computes (a mod N), where N = (h * 2^n - 1) in an efficient
 // way using the shift and add method.
 //
 // Read [Ref4] for more information on this method.
+//
+// The result is always in [0, N), even for a negative a.
 func rieselMod(a *big.Int, R *RieselNumber) {
-	if R.N.Cmp(maxInt64) == -1 {
+	if R.N.Cmp(maxInt64) == -1 || a.Sign() < 0 {
 		a.Mod(a, R.N)
 
 	} else {
-		for a.Cmp(R.N) == 1 {
-			if int64(a.BitLen()) <= R.n {
-				break	// QUESTION: is this code ever reached?
-			}
 
+		// Since N >= 2^n, any a > N has more than n bits: the high part j
+		// below is never 0, and every step makes a smaller.
+		for a.Cmp(R.N) == 1 {
 			j := new(big.Int)
 			j.Rsh(a, uint(R.n))
 
@@ -244,6 +272,71 @@ func rieselMod(a *big.Int, R *RieselNumber) {
 // function), and pass its pointer to the repeated invocations of efficientJacobi.
 func efficientJacobi(x, h, n int64, cache map[int64]int) (int, error) {
 
+	// jacobiNx computes Jacobi(N, x) for an odd x, or gets it from the cache
+	jacobiNx := func(x, hModX int64) (int, error) {
+		if val, ok := cache[x]; ok {
+			return val, nil
+		}
+
+		jNx, err := smallJacobiNx(x, hModX, n)
+		if err != nil {
+			return 0, err
+		}
+
+		// Store the computed Jacobi(N, x) in the cache for potential later use
+		if cache != nil {
+			cache[x] = jNx
+		}
+		return jNx, nil
+	}
+
+	return efficientJacobiWith(x, h, n, jacobiNx)
+}
+
+// smallJacobiNx computes Jacobi(N, x) for an odd x that does not divide h,
+// where N = h * 2^n - 1 and hModX = h mod x.
+//
+// An error is returned if x and N are not coprime.
+func smallJacobiNx(x, hModX, n int64) (int, error) {
+
+	// Jacobi(N, x) = Jacobi(((h mod x) * (2^n mod x) - 1) mod x, x).
+	twoNModX, err := modExp(2, n, x)
+	if err != nil {
+		return 0, errors.New("Something went wrong: n or x were negative")
+	}
+
+	NModX := mulMod(hModX, twoNModX, x) - 1
+	if NModX < 0 {
+		NModX += x
+	}
+
+	// Check if x divides N (just in case)
+	if (NModX == 0) && (x != 1) {
+		return 0, errors.New("N has a known factor, it does not need to be tested further.")
+	}
+
+	// Now we can compute Jacobi(N, x) on smaller numbers
+	jNx := big.Jacobi(new(big.Int).SetInt64(NModX), new(big.Int).SetInt64(x))
+
+	// Check if GCD(N, x) != 1
+	// If GCD(N, x) != 1, then N has a divisor > 1, and does not need to be tested further.
+	if jNx == 0 {
+		return 0, errors.New("N has a known factor, it does not need to be tested further.")
+	}
+
+	return jNx, nil
+}
+
+// efficientJacobiWith computes the Jacobi symbol for (x, h * 2^n - 1) like
+// efficientJacobi, getting the Jacobi(N, x) of the general case from jacobiNx,
+// which lets the callers decide how to cache them.
+func efficientJacobiWith(x, h, n int64, jacobiNx func(x, hModX int64) (int, error)) (int, error) {
+
+	// Jacobi(0, N) == 0, since N > 1: like any x sharing a factor with N
+	if x == 0 {
+		return 0, errors.New("Jacobi(0, N) == 0, x and N are not coprime")
+	}
+
 	// true == +1
 	sign := true
 
@@ -268,7 +361,17 @@ func efficientJacobi(x, h, n int64, cache map[int64]int) (int, error) {
 		if n == 2 { sign = !sign }
 	}
 
-	// At this point, we know that x is odd, and we can
+	// When x is negative, we have:
+	//		Jacobi(x, N) == Jacobi(-1, N) * Jacobi(-x, N)
+	//
+	// And Jacobi(-1, N) = (-1)^((N-1)/2) == -1, since (N-1)/2 = h*2^(n-1)-1 is odd.
+	// Now that x is odd, -x does not overflow.
+	if x < 0 {
+		x = -x
+		sign = !sign
+	}
+
+	// At this point, we know that x is odd and positive, and we can
 	// proceed with computing Jacobi(x, N).
 	if hModX := h % x; hModX == 0 {
 
@@ -308,40 +411,11 @@ func efficientJacobi(x, h, n int64, cache map[int64]int) (int, error) {
 		// 		Jacobi(x, N) = Jacobi(N, x) * (-1)^((N-1)/2*(x-1)/2) =
 		// 		= Jacobi(((h mod x) * (2^n mod x) - 1) mod x, x) * (-1)^((h*2^(n-1)-1)*(x-1)/2)
 		//
-		// Let's start by computing Jacobi(N, x) or getting it from the computedJNX
-		// cache if it was already computed.
-		var jNx int
-		if val, ok := cache[x]; ok {
-			jNx = val
-
-		} else {
-
-			// Jacobi(N, x) = Jacobi(((h mod x) * (2^n mod x) - 1) mod x, x).
-			twoNModX, err := modExp(2, n, x)
-			if err != nil {
-				return 0, errors.New("Something went wrong: n or x were negative")
-			}
-
-			NModX := (hModX * twoNModX - 1 + x) % x
-
-			// Check if x divides N (just in case)
-			if (NModX == 0) && (x != 1) {
-				return 0, errors.New("N has a known factor, it does not need to be tested further.")
-			}
-
-			// Now we can compute Jacobi(N, x) on smaller numbers
-			jNx = big.Jacobi(new(big.Int).SetInt64(NModX), new(big.Int).SetInt64(x))
-
-			// Check if GCD(N, x) != 1
-			// If GCD(N, x) != 1, then N has a divisor > 1, and does not need to be tested further.
-			if jNx == 0 {
-				return 0, errors.New("N has a known factor, it does not need to be tested further.")
-			}
-
-			// Store the computed Jacobi(N, x) in the cache for potential later use
-			if cache != nil {
-				cache[x] = jNx
-			}
+		// Let's start by computing Jacobi(N, x) or getting it from the cache
+		// if it was already computed.
+		jNx, err := jacobiNx(x, hModX)
+		if err != nil {
+			return 0, err
 		}
 
 		// The last thing that we have to do is to compute the value of (-1)^((N-1)/2*(x-1)/2).
@@ -362,6 +436,14 @@ func efficientJacobi(x, h, n int64, cache map[int64]int) (int, error) {
 	}
 }
 
+// Screened returns true if R is a prime < 257 or has a prime < 257 as a
+// factor, in which case Test decides it without running the test, and its
+// Result has no residue.
+func Screened(R *RieselNumber) bool {
+	check, err := screenEasyPrimes(R)
+	return err == nil && check != 0
+}
+
 // screenEasyPrimes checks whether R is a small known prime < 257 or whether it
 // has a small known prime < 257 as a factor.
 //
@@ -378,7 +460,7 @@ func screenEasyPrimes(R *RieselNumber) (int, error) {
 
 	// Catch the degenerate case of h*2^n-1 == 1
 	if R.h == 1 && R.n == 1 {
-		log.Debugf("N = %v = 1 is not prime", R)
+		logScreening.Debugf("N = %v = 1 is not prime", R)
 		return -1, nil	// 1*2^1-1 == 1 is not prime
 	}
 
@@ -387,11 +469,11 @@ func screenEasyPrimes(R *RieselNumber) (int, error) {
 	// n == 2 and 0 < h < 2^n  ->  0 < h < 4
 	// Since h is odd  ->  h == 1 or h == 3
 	if R.h == 1 && R.n == 2 {
-		log.Debugf("N = %v = 3 is prime", R)
+		logScreening.Debugf("N = %v = 3 is prime", R)
 		return +1, nil		// 1*2^2-1 == 3 is prime
 	}
 	if R.h == 3 && R.n == 2 {
-		log.Debugf("N = %v = 11 is prime", R)
+		logScreening.Debugf("N = %v = 11 is prime", R)
 		return +1, nil		// 3*2^2-1 == 11 is prime
 	}
 
@@ -401,26 +483,26 @@ func screenEasyPrimes(R *RieselNumber) (int, error) {
 	// violate the checks above.
 	if R.h == 1 {
 		if R.n == 3 || R.n == 5 || R.n == 7 {
-			log.Debugf("N = %v is prime", R)
+			logScreening.Debugf("N = %v is prime", R)
 			return +1, nil		// 3, 7, 31, 127 are prime
 		}
 	}
 	if R.h == 3 {
 		if R.n == 2 || R.n == 3 || R.n == 4 || R.n == 6 {
-			log.Debugf("N = %v is prime", R)
+			logScreening.Debugf("N = %v is prime", R)
 			return +1, nil		// 11, 23, 47, 191 are prime
 		}
 	}
 	if R.h == 5 && R.n == 4 {
-		log.Debugf("N = %v = 79 is prime", R)
+		logScreening.Debugf("N = %v = 79 is prime", R)
 		return +1, nil 	// 79 is prime
 	}
 	if R.h == 7 && R.n == 5 {
-		log.Debugf("N = %v = 223 is prime", R)
+		logScreening.Debugf("N = %v = 223 is prime", R)
 		return +1, nil		// 223 is prime
 	}
 	if R.h == 15 && R.n == 4 {
-		log.Debugf("N = %v = 239 is prime", R)
+		logScreening.Debugf("N = %v = 239 is prime", R)
 		return +1, nil		// 239 is prime
 	}
 
@@ -428,7 +510,7 @@ func screenEasyPrimes(R *RieselNumber) (int, error) {
 	// The product of primes up to 28, excluding 2 is:
 	// 		111546435
 	if new(big.Int).GCD(nil, nil, R.N, new(big.Int).SetInt64(111546435)).Cmp(one) != 0 {
-		log.Debugf("N = %v is not prime: a small 3 <= prime < 29 divides it", R)
+		logScreening.Debugf("N = %v is not prime: a small 3 <= prime < 29 divides it", R)
 		return -1, nil	// a small 3 <= prime < 29 divides N
 	}
 
@@ -436,7 +518,7 @@ func screenEasyPrimes(R *RieselNumber) (int, error) {
 	// The product of primes from 28 to 46 is:
 	//		5864229
 	if new(big.Int).GCD(nil, nil, R.N, new(big.Int).SetInt64(58642669)).Cmp(one) != 0 {
-		log.Debugf("N = %v is not prime: a small 29 <= prime < 47 divides it", R)
+		logScreening.Debugf("N = %v is not prime: a small 29 <= prime < 47 divides it", R)
 		return -1, nil 	// a small 29 <= prime < 47 divides N
 	}
 
@@ -449,11 +531,11 @@ func screenEasyPrimes(R *RieselNumber) (int, error) {
 			10)
 
 		if success != true {
-			log.Warning("Was not able to initialize pprod256, the big.Int product of " +
+			logScreening.Warning("Was not able to initialize pprod256, the big.Int product of " +
 				"primes between 47 and 256. Skipping this pre-check.")
 
 		} else if new(big.Int).GCD(nil, nil, R.N, pprod256).Cmp(one) != 0 {
-			log.Debugf("N = %v is not prime: a small 47 <= prime < 257 divides it", R)
+			logScreening.Debugf("N = %v is not prime: a small 47 <= prime < 257 divides it", R)
 			return -1, nil	// a small 47 <= prime < 257 divides N
 		}
 	}
@@ -461,6 +543,14 @@ func screenEasyPrimes(R *RieselNumber) (int, error) {
 	return 0, nil
 }
 
+// residue64 returns the RES64 of the given number, i.e. its lowest 64 bits.
+//
+// The RES64 of U(n) mod N is what LLR-like software prints for composite
+// numbers, and it is used to compare the results of independent runs.
+func residue64(a *big.Int) uint64 {
+	return new(big.Int).And(a, maxUint64).Uint64()
+}
+
 var mod = new(big.Int).SetInt64(100000000)
 func getLastDigits(a *big.Int) string {
 	tmp := new(big.Int)
@@ -1,6 +1,7 @@
 package rieseltest
 
 import (
+	"math"
 	"testing"
 
 	big "math/big"
@@ -128,13 +129,17 @@ func TestReduce(t *testing.T) {
 		{24, 2, 6},
 		{38, 1, 38},
 		{27, 3, 3},
-		{3, 3, 1},
+		{3, 1, 3},
+		{1, 1, 1},
+		{0, 1, 0},
+		{-72, 6, -2},
+		{-75, 5, -3},
 		{415800, 30, 462},
 	}
 
 	for _, c := range testCases {
 		b, d := reduce(c.x)
-		if c.b != b && c.d != d {
+		if c.b != b || c.d != d {
 			t.Errorf("reduce(%v) == (%v, %v), but we expected (%v, %v)", c.x, b, d, c.b, c.d)
 		}
 	}
@@ -168,7 +173,8 @@ func TestModExp(t *testing.T) {
 		{0, 981409, 132421, 0},
 		{1000, 1000, 19, 7},
 		{12983194, 0, 19, 1},
-		{-81792, 73363, 233, -161},
+		{-81792, 73363, 233, 72},
+		{3037000500, 2, 9223372036854775807, 145474193},
 	}
 
 	for _, c := range testCases {
@@ -238,6 +244,8 @@ func TestEfficientJacobi(t *testing.T) {
 		{131233, 41231029, 217523, -1},
 		{66, 742329, 2, -1},
 		{131230, 742329, 20523, -1},
+		{-1, 3, 10, -1},
+		{9223372036854775807, 1099511627775, 5, -1},
 	}
 
 	for _, c := range testCases {
@@ -248,3 +256,150 @@ func TestEfficientJacobi(t *testing.T) {
 		}
 	}
 }
+
+// The fuzz targets below compare the arithmetic primitives with straightforward
+// math/big references. Run them with, for example:
+//
+//		go test -fuzz FuzzEfficientJacobi -fuzztime 1m
+
+func FuzzLowerNonZeroBit(f *testing.F) {
+	for _, n := range []int64{0, 1, -1, 2, 24, 1 << 62, math.MaxInt64, math.MinInt64} {
+		f.Add(n)
+	}
+
+	f.Fuzz(func(t *testing.T, n int64) {
+		actual, err := lowerNonZeroBit(n)
+		if n < 1 {
+			if err == nil {
+				t.Errorf("lowerNonZeroBit(%v) should return an error, but it didn't", n)
+			}
+			return
+		}
+
+		if expected := big.NewInt(n).TrailingZeroBits(); err != nil || actual != expected {
+			t.Errorf("lowerNonZeroBit(%v) == %v, %v, but we expected %v", n, actual, err, expected)
+		}
+	})
+}
+
+func FuzzReduce(f *testing.F) {
+	for _, x := range []int64{0, 1, 2, 3, 4, -1, -4, -12, 415800, 1 << 40, 999999999989, math.MinInt64} {
+		f.Add(x)
+	}
+
+	f.Fuzz(func(t *testing.T, x int64) {
+
+		// Keep the reference factorization fast
+		if x != math.MinInt64 && (x > 1 << 40 || x < -(1 << 40)) {
+			x %= 1 << 40
+		}
+
+		b, d := reduce(x)
+
+		// The reference computes d and b from the factorization of |x|
+		expectedB, expectedD := big.NewInt(1), big.NewInt(1)
+		if x == 0 {
+			expectedD.SetInt64(0)
+		}
+		rest := new(big.Int).Abs(big.NewInt(x))
+		for p := big.NewInt(2); rest.Cmp(one) > 0; p.Add(p, one) {
+			if new(big.Int).Mul(p, p).Cmp(rest) > 0 {
+				expectedD.Mul(expectedD, rest)
+				break
+			}
+			for e := 0; new(big.Int).Mod(rest, p).Sign() == 0; e++ {
+				rest.Quo(rest, p)
+				if e % 2 == 0 {
+					expectedD.Mul(expectedD, p)
+				} else {
+					expectedD.Quo(expectedD, p)
+					expectedB.Mul(expectedB, p)
+				}
+			}
+		}
+		if x < 0 {
+			expectedD.Neg(expectedD)
+		}
+
+		if big.NewInt(b).Cmp(expectedB) != 0 || big.NewInt(d).Cmp(expectedD) != 0 {
+			t.Errorf("reduce(%v) == (%v, %v), but we expected (%v, %v)", x, b, d, expectedB, expectedD)
+		}
+	})
+}
+
+func FuzzModExp(f *testing.F) {
+	f.Add(int64(111), int64(123), int64(53))
+	f.Add(int64(-81792), int64(73363), int64(233))
+	f.Add(int64(0), int64(0), int64(7))
+	f.Add(int64(3037000500), int64(2), int64(math.MaxInt64))
+	f.Add(int64(math.MinInt64), int64(math.MaxInt64), int64(math.MaxInt64 - 24))
+	f.Add(int64(2), int64(-1), int64(5))
+	f.Add(int64(2), int64(10), int64(0))
+
+	f.Fuzz(func(t *testing.T, base, exponent, modulus int64) {
+		actual, err := modExp(base, exponent, modulus)
+		if modulus <= 0 || exponent < 0 {
+			if err == nil {
+				t.Errorf("modExp(%v, %v, %v) should return an error, but it didn't", base, exponent, modulus)
+			}
+			return
+		}
+
+		expected := new(big.Int).Exp(big.NewInt(base), big.NewInt(exponent), big.NewInt(modulus))
+		if err != nil || big.NewInt(actual).Cmp(expected) != 0 {
+			t.Errorf("modExp(%v, %v, %v) == %v, %v, but we expected %v", base, exponent, modulus, actual, err,
+				expected)
+		}
+	})
+}
+
+func FuzzRieselMod(f *testing.F) {
+	f.Add([]byte{1, 2, 3}, false, uint32(13), uint16(17))
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, true, uint32(1), uint16(64))
+	f.Add([]byte{}, false, uint32(45), uint16(415))
+	f.Add([]byte{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, false, uint32(1), uint16(127))
+
+	f.Fuzz(func(t *testing.T, b []byte, negative bool, h uint32, n uint16) {
+		R, _ := NewRieselNumber(int64(h | 1), int64(n % 600 + 2))
+
+		a := new(big.Int).SetBytes(b)
+		if negative {
+			a.Neg(a)
+		}
+		expected := new(big.Int).Mod(a, R.N)
+
+		actual := new(big.Int).Set(a)
+		rieselMod(actual, R)
+		if actual.Cmp(expected) != 0 {
+			t.Errorf("rieselMod(%v, %v) == %v, but we expected %v", a, R, actual, expected)
+		}
+	})
+}
+
+func FuzzEfficientJacobi(f *testing.F) {
+	f.Add(int64(9), uint64(507), uint16(2175))
+	f.Add(int64(66), uint64(742329), uint16(2))
+	f.Add(int64(0), uint64(3), uint16(10))
+	f.Add(int64(-5), uint64(3), uint16(10))
+	f.Add(int64(math.MinInt64), uint64(1), uint16(61))
+	f.Add(int64(math.MaxInt64), uint64(1 << 40 - 1), uint16(3))
+
+	f.Fuzz(func(t *testing.T, x int64, h uint64, n uint16) {
+		R, _ := NewRieselNumber(int64(h % (1 << 40) | 1), int64(n % 2000 + 2))
+
+		actual, err := efficientJacobi(x, R.h, R.n, nil)
+
+		// efficientJacobi returns an error when x and N are not coprime
+		expected := big.Jacobi(big.NewInt(x), R.N)
+		if expected == 0 {
+			if err == nil {
+				t.Errorf("efficientJacobi(%v, %v, %v) == %v, but we expected an error", x, R.h, R.n, actual)
+			}
+			return
+		}
+		if err != nil || actual != expected {
+			t.Errorf("efficientJacobi(%v, %v, %v) == %v, %v, but we expected %v", x, R.h, R.n, actual, err,
+				expected)
+		}
+	})
+}
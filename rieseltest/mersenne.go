@@ -0,0 +1,97 @@
+package rieseltest
+
+import (
+	"math/bits"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// DefaultMersenneFactorLimit is the largest candidate factor tried by the
+// trial factoring of Mersenne numbers, used when Options.MersenneFactorLimit
+// is not set.
+const DefaultMersenneFactorLimit = 1 << 28
+
+// TestMersenne performs a Lucas-Lehmer test on the Mersenne number 2^p - 1.
+// It is equivalent to calling Test on the RieselNumber 1 * 2^p - 1.
+func TestMersenne(p int64, opts *Options) (*Result, error) {
+	R, err := NewRieselNumber(1, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return Test(R, opts)
+}
+
+// mersenneMod computes (a mod N), where N = 2^n - 1, using only shifts and
+// additions: since 2^n == 1 (mod N), writing a = j * 2^n + k gives a == j + k.
+//
+// The t argument is a temporary used to avoid allocations in the main loop
+// of the test.
+func mersenneMod(a *big.Int, R *RieselNumber, t *big.Int) {
+	if a.Sign() < 0 {
+		a.Mod(a, R.N)
+		return
+	}
+
+	for int64(a.BitLen()) > R.n {
+		t.Rsh(a, uint(R.n))
+		a.And(a, R.N)
+		a.Add(a, t)
+	}
+
+	if a.Cmp(R.N) == 0 {
+		a.SetInt64(0)
+	}
+}
+
+// mersenneFactor looks for a factor of the Mersenne number N = 2^p - 1 without
+// running the Lucas-Lehmer test, and returns nil if none was found.
+//
+// When p is composite, with a prime factor q, 2^q - 1 divides N. Otherwise,
+// every prime factor of N is in the form 2*k*p + 1 and is +/-1 (mod 8), so we
+// try those up to limit, or up to sqrt(N) if smaller. Since every prime factor
+// of a candidate is also in that form, the first candidate dividing N is its
+// smallest prime factor.
+func mersenneFactor(p int64, limit uint64) *big.Int {
+	if factors := primeFactors(p); factors[0] != p {
+		f := new(big.Int).Lsh(one, uint(factors[0]))
+		return f.Sub(f, one)
+	}
+
+	step := 2 * uint64(p)
+	for q := step + 1; q <= limit && q > step; q += step {
+
+		// q^2 > N
+		if 2 * (bits.Len64(q) - 1) >= int(p) {
+			break
+		}
+
+		if r := q % 8; r != 1 && r != 7 {
+			continue
+		}
+		if pow2Mod(uint64(p), q) == 1 {
+			return new(big.Int).SetUint64(q)
+		}
+	}
+
+	return nil
+}
+
+// pow2Mod returns 2^e mod q, for an odd q > 1, using the left-to-right binary
+// method, so that the multiplications by 2 are simple doublings.
+func pow2Mod(e, q uint64) uint64 {
+	result := uint64(1)
+	for i := bits.Len64(e) - 1; i >= 0; i-- {
+		hi, lo := bits.Mul64(result, result)
+		result = bits.Rem64(hi, lo, q)
+
+		if e & (1 << uint(i)) != 0 {
+			lo, hi = bits.Add64(result, result, 0)
+			result = bits.Rem64(hi, lo, q)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,132 @@
+package rieseltest
+
+import (
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// The exponents p <= 2281 of the known Mersenne primes 2^p - 1.
+var mersenneExponents = map[int64]bool{
+	2: true, 3: true, 5: true, 7: true, 13: true, 17: true, 19: true, 31: true, 61: true, 89: true,
+	107: true, 127: true, 521: true, 607: true, 1279: true, 2203: true, 2281: true,
+}
+
+func TestTestMersenne(t *testing.T) {
+	for p := int64(2); p <= 1300; p++ {
+		result, err := TestMersenne(p, nil)
+		if err != nil {
+			t.Fatalf("TestMersenne(%v) returned an error: %v", p, err)
+		}
+		if result.Prime != mersenneExponents[p] {
+			t.Errorf("TestMersenne(%v) returned %v, but we expected %v", p, result.Prime, mersenneExponents[p])
+		}
+		if result.Factor != nil {
+			if r := new(big.Int).Mod(result.R.N, result.Factor); r.Sign() != 0 {
+				t.Errorf("TestMersenne(%v) returned the factor %v, which doesn't divide N", p, result.Factor)
+			}
+		}
+	}
+
+	for _, p := range []int64{2203, 2281} {
+		if result, err := TestMersenne(p, nil); err != nil || !result.Prime {
+			t.Errorf("TestMersenne(%v) returned %v, %v, but we expected true", p, result, err)
+		}
+	}
+}
+
+func TestTestMersenneWithoutPrecheck(t *testing.T) {
+	// Without the precheck, the Lucas-Lehmer test must reach the same conclusion
+	var testCases = []struct {
+		p     int64
+		prime bool
+	}{
+		{11, false},
+		{23, false},
+		{29, false},
+		{31, true},
+		{37, false},
+		{61, true},
+		{67, false},
+		{89, true},
+		{127, true},
+		{521, true},
+		{523, false},
+	}
+
+	opts := &Options{MersennePrecheck: false}
+	for _, c := range testCases {
+		result, err := TestMersenne(c.p, opts)
+		if err != nil {
+			t.Fatalf("TestMersenne(%v) returned an error: %v", c.p, err)
+		}
+		if result.Prime != c.prime || result.Factor != nil {
+			t.Errorf("TestMersenne(%v) returned prime = %v, factor = %v, but we expected %v without a factor",
+				c.p, result.Prime, result.Factor, c.prime)
+		}
+		if c.prime && result.Certificate == nil {
+			t.Errorf("TestMersenne(%v) returned no certificate", c.p)
+		}
+	}
+}
+
+func TestMersenneFactor(t *testing.T) {
+	var testCases = []struct {
+		p      int64
+		factor string
+	}{
+		{11, "23"},
+		{23, "47"},
+		{29, "233"},
+		{37, "223"},
+		{43, "431"},
+		{15, "7"},
+		{35, "31"},
+		{221, "8191"},
+		{31, ""},
+		{61, ""},
+		{67, "193707721"},
+	}
+
+	for _, c := range testCases {
+		f := mersenneFactor(c.p, DefaultMersenneFactorLimit)
+		if c.factor == "" {
+			if f != nil {
+				t.Errorf("mersenneFactor(%v) returned %v, but we expected nil", c.p, f)
+			}
+			continue
+		}
+		if f == nil || f.String() != c.factor {
+			t.Errorf("mersenneFactor(%v) returned %v, but we expected %v", c.p, f, c.factor)
+		}
+	}
+}
+
+func TestMersenneMod(t *testing.T) {
+	R, _ := NewRieselNumber(1, 89)
+	tmp := new(big.Int)
+
+	for _, s := range []string{"0", "1", "-2", "618970019642690137449562111", "618970019642690137449562112",
+		"123456789012345678901234567890123456789012345678901234567890", "-123456789012345678901234567890"} {
+		a, _ := new(big.Int).SetString(s, 10)
+		expected := new(big.Int).Mod(a, R.N)
+
+		mersenneMod(a, R, tmp)
+		if a.Cmp(expected) != 0 {
+			t.Errorf("mersenneMod(%v) returned %v, but we expected %v", s, a, expected)
+		}
+	}
+}
+
+func TestPow2Mod(t *testing.T) {
+	for _, q := range []uint64{3, 7, 1000003, 4294967291, 18446744073709551557} {
+		for _, e := range []uint64{0, 1, 2, 63, 64, 65, 1000, 1 << 40} {
+			expected := new(big.Int).Exp(two, new(big.Int).SetUint64(e), new(big.Int).SetUint64(q))
+			if r := pow2Mod(e, q); r != expected.Uint64() {
+				t.Errorf("pow2Mod(%v, %v) returned %v, but we expected %v", e, q, r, expected)
+			}
+		}
+	}
+}
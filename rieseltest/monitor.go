@@ -0,0 +1,27 @@
+package rieseltest
+
+// A Monitor follows the progress of the tests run by Test (see Options.Monitor),
+// for example to report it as metrics.
+//
+// Its methods are called by the goroutine running the test, in the middle of
+// the computation: they must return quickly, and they must be safe for
+// concurrent use when the same options are shared by several tests, as in a Pool.
+type Monitor interface {
+
+	// Started is called when the test of R starts.
+	Started(R *RieselNumber)
+
+	// Iteration is called once U(i) has been computed.
+	Iteration(R *RieselNumber, i int64)
+
+	// CheckpointSaved is called once the checkpoint at U(i) has been saved.
+	CheckpointSaved(R *RieselNumber, i int64)
+
+	// Error is called for every error detected during the test of R, whether
+	// it makes the test fail or not, like an unreadable checkpoint.
+	Error(R *RieselNumber, err error)
+
+	// Done is called when the test of R ends, with its result, or with the
+	// error returned by Test, which is ErrInterrupted for an interrupted test.
+	Done(R *RieselNumber, result *Result, err error)
+}
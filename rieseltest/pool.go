@@ -0,0 +1,458 @@
+package rieseltest
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EstimateCost returns an estimate of the relative cost of testing R.
+//
+// GenUN dominates the cost of the test: it performs about n squarings of
+// numbers of about n + log2(h) bits. We assume a Karatsuba-like multiplication,
+// whose cost grows as bits^log2(3).
+func EstimateCost(R *RieselNumber) float64 {
+	bits := float64(R.N.BitLen())
+	return float64(R.n) * math.Pow(bits, math.Log2(3))
+}
+
+// PoolResult is the outcome of a Riesel number submitted to a Pool.
+type PoolResult struct {
+	*Result
+
+	// Err is not nil if the test could not be completed. In that case, only
+	// the R field of the Result is set.
+	Err error
+
+	// Worker is the index of the worker that performed the test, or -1 if the
+	// result was recorded by a previous run in the checkpoint directory.
+	Worker int
+}
+
+// Pool tests many Riesel numbers concurrently, one number per worker.
+//
+// For mid-sized n, running one test per core is more efficient than
+// parallelizing the computations of a single test.
+//
+// The pending numbers are served cheapest first according to EstimateCost,
+// but a number is never overtaken by more than a queue length of cheaper ones,
+// so that expensive numbers are not starved. Submit blocks while the queue
+// is full, which bounds the memory used by the pool.
+//
+// If the pool has a checkpoint directory, every worker saves the state of
+// its current test in its own checkpoint file, and every completed test is
+// recorded in a "completed" file. A new pool using the same directory resumes
+// the interrupted tests and does not repeat the completed ones. The
+// checkpoints of a previous run that are not resumed are removed once the
+// pool finishes without being stopped.
+type Pool struct {
+	workers  int
+	capacity int
+	dir      string
+	opts     Options
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	closed   bool
+	pending  int
+	byCost   costQueue
+	bySeq    []*poolItem
+	seq      int64
+	popped   int64
+
+	// Results of a previous run, and checkpoints left by a previous run.
+	completed map[[2]int64]*Result
+	resumable map[[2]int64]string
+	record    *os.File
+
+	results chan *PoolResult
+	wg      sync.WaitGroup
+}
+
+// NewPool starts a pool with the given number of workers, whose queue can hold
+// up to capacity pending numbers.
+//
+// If dir is not empty, it is the directory used for the checkpoint files, and
+// it is created if it does not exist.
+//
+// The numbers are tested with the given options, or with DefaultOptions() if
+// opts is nil. Their CheckpointFile is replaced by the one of each worker.
+// When opts.Stop is closed, the tests in progress are interrupted, the pool
+// is closed and the numbers still pending are not tested.
+func NewPool(workers, capacity int, dir string, opts *Options) (*Pool, error) {
+
+	// Check preconditions
+	if workers < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected workers >= 1, but received workers = %v", workers))
+	}
+	if capacity < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected capacity >= 1, but received capacity = %v", capacity))
+	}
+
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	p := &Pool{
+		opts:      *opts,
+		workers:   workers,
+		capacity:  capacity,
+		dir:       dir,
+		completed: make(map[[2]int64]*Result),
+		resumable: make(map[[2]int64]string),
+		results:   make(chan *PoolResult, workers),
+	}
+	p.notEmpty = sync.NewCond(&p.mu)
+	p.notFull = sync.NewCond(&p.mu)
+
+	if dir != "" {
+		if err := p.loadDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work(i)
+	}
+
+	// Close the results channel once all the workers are done
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		if p.record != nil {
+			p.record.Close()
+		}
+
+		// The numbers left in resumable were not resubmitted, and their
+		// checkpoints are kept only if the pool was stopped
+		if !p.stopped() {
+			p.mu.Lock()
+			for key := range p.resumable {
+				p.dropResumable(key)
+			}
+			p.mu.Unlock()
+		}
+		close(p.results)
+		close(done)
+	}()
+
+	// Once Options.Stop is closed, the tests in progress save their checkpoints
+	// and the pool stops serving numbers
+	if opts.Stop != nil {
+		go func() {
+			select {
+			case <-opts.Stop:
+				p.Close()
+			case <-done:
+			}
+		}()
+	}
+
+	return p, nil
+}
+
+// Submit adds R to the numbers to be tested. It blocks while the queue is full.
+func (p *Pool) Submit(R *RieselNumber) error {
+	if R == nil {
+		return errors.New("Received R == nil")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.pending >= p.capacity && !p.closed {
+		p.notFull.Wait()
+	}
+	if p.closed {
+		return errors.New("The pool is closed")
+	}
+
+	item := &poolItem{R: R, cost: EstimateCost(R), seq: p.seq, popped: p.popped}
+	p.seq++
+	p.pending++
+	heap.Push(&p.byCost, item)
+	p.bySeq = append(p.bySeq, item)
+
+	p.notEmpty.Signal()
+	return nil
+}
+
+// Close signals that no more numbers will be submitted. The results channel
+// is closed once all the submitted numbers have been tested, or as soon as the
+// tests in progress are interrupted if Options.Stop was closed.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	p.notEmpty.Broadcast()
+	p.notFull.Broadcast()
+}
+
+// Results returns the channel on which the results are sent, in the order in
+// which the tests complete. The channel must be drained, otherwise the workers
+// block once it is full.
+func (p *Pool) Results() <-chan *PoolResult {
+	return p.results
+}
+
+// next removes and returns the next number to be tested, or nil if the pool
+// is closed and empty.
+func (p *Pool) next() *poolItem {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.pending == 0 && !p.closed {
+		p.notEmpty.Wait()
+	}
+	if p.pending == 0 || p.stopped() {
+		return nil
+	}
+
+	// Drop the items already served from the front of the submission order
+	for p.bySeq[0].taken {
+		p.bySeq = p.bySeq[1:]
+	}
+
+	// Serve the oldest item if it has been overtaken too many times,
+	// otherwise serve the cheapest one.
+	var item *poolItem
+	if oldest := p.bySeq[0]; p.popped-oldest.popped >= int64(p.capacity) {
+		item = oldest
+		heap.Remove(&p.byCost, oldest.index)
+	} else {
+		item = heap.Pop(&p.byCost).(*poolItem)
+	}
+
+	item.taken = true
+	p.popped++
+	p.pending--
+	p.notFull.Signal()
+
+	return item
+}
+
+// stopped returns true once Options.Stop is closed.
+func (p *Pool) stopped() bool {
+	select {
+	case <-p.opts.Stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// work is the loop of the worker with the given index.
+func (p *Pool) work(index int) {
+	defer p.wg.Done()
+
+	opts := p.opts
+	opts.CheckpointFile = ""
+	if p.dir != "" {
+		opts.CheckpointFile = filepath.Join(p.dir, fmt.Sprintf("worker-%d.ckpt", index))
+	}
+
+	for item := p.next(); item != nil; item = p.next() {
+		key := [2]int64{item.R.h, item.R.n}
+
+		if result, ok := p.completed[key]; ok {
+			p.results <- &PoolResult{Result: &Result{R: item.R, Prime: result.Prime, Residue: result.Residue},
+				Worker: -1}
+			continue
+		}
+
+		if p.dir != "" {
+			p.adoptCheckpoint(key, opts.CheckpointFile)
+		}
+
+		log.Infof("Worker %v is testing N = %v", index, item.R)
+		result, err := Test(item.R, &opts)
+		if err != nil {
+			result = &Result{R: item.R}
+		} else if p.dir != "" {
+			p.recordCompleted(result)
+		}
+
+		p.results <- &PoolResult{Result: result, Err: err, Worker: index}
+	}
+}
+
+// adoptCheckpoint moves the checkpoint left by a previous run for the given
+// number, if any, to the checkpoint file of the current worker.
+func (p *Pool) adoptCheckpoint(key [2]int64, path string) {
+	p.mu.Lock()
+	old, ok := p.resumable[key]
+	delete(p.resumable, key)
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if err := os.Rename(old, path); err != nil {
+		logCheckpoint.Warningf("Could not resume from the checkpoint %v: %v", old, err)
+	}
+}
+
+// dropResumable removes the checkpoint left by a previous run for the given
+// number, if any. The caller must hold p.mu.
+func (p *Pool) dropResumable(key [2]int64) {
+	path, ok := p.resumable[key]
+	if !ok {
+		return
+	}
+
+	delete(p.resumable, key)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logCheckpoint.Warningf("Could not remove the checkpoint %v: %v", path, err)
+	}
+}
+
+// recordCompleted appends the given result to the "completed" file, and
+// removes the checkpoint left by a previous run for the same number.
+func (p *Pool) recordCompleted(result *Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dropResumable([2]int64{result.R.h, result.R.n})
+
+	_, err := fmt.Fprintf(p.record, "%v %v %v %v\n", result.R.h, result.R.n, result.Prime, result.ResidueString())
+	if err == nil {
+		err = p.record.Sync()
+	}
+	if err != nil {
+		log.Warningf("Could not record the result of N = %v: %v", result.R, err)
+	}
+}
+
+// loadDir prepares the checkpoint directory, loading the results and the
+// checkpoints left by a previous run.
+//
+// The checkpoints of the previous workers are renamed after the number they
+// refer to, since the same number might now be served by a different worker.
+func (p *Pool) loadDir() error {
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(p.dir, "worker-*.ckpt"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		c, err := LoadCheckpoint(path)
+		if err != nil {
+			logCheckpoint.Warningf("Ignoring the checkpoint %v: %v", path, err)
+			continue
+		}
+
+		resume := filepath.Join(p.dir, fmt.Sprintf("resume-%d-%d.ckpt", c.H, c.N))
+		if err := os.Rename(path, resume); err != nil {
+			return err
+		}
+	}
+
+	paths, err = filepath.Glob(filepath.Join(p.dir, "resume-*.ckpt"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if c, err := LoadCheckpoint(path); err == nil {
+			p.resumable[[2]int64{c.H, c.N}] = path
+		}
+	}
+
+	// Each line of the completed file has the format: h n prime residue
+	path := filepath.Join(p.dir, "completed")
+	if file, err := os.Open(path); err == nil {
+		s := bufio.NewScanner(file)
+
+		for s.Scan() {
+			words := strings.Fields(s.Text())
+			if len(words) != 4 {
+				continue
+			}
+
+			h, errH := strconv.ParseInt(words[0], 10, 64)
+			n, errN := strconv.ParseInt(words[1], 10, 64)
+			prime, errP := strconv.ParseBool(words[2])
+			residue, errR := strconv.ParseUint(words[3], 16, 64)
+			if errH != nil || errN != nil || errP != nil || errR != nil {
+				log.Warningf("Ignoring the malformed line %q in %v", s.Text(), path)
+				continue
+			}
+
+			p.completed[[2]int64{h, n}] = &Result{Prime: prime, Residue: residue}
+		}
+
+		err = s.Err()
+		file.Close()
+		if err != nil {
+			return err
+		}
+
+		// The checkpoints of the completed numbers will never be resumed
+		for key := range p.completed {
+			p.dropResumable(key)
+		}
+
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	p.record, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	return err
+}
+
+// poolItem is a number waiting in the queue of a Pool.
+type poolItem struct {
+	R      *RieselNumber
+	cost   float64
+	seq    int64 // submission order
+	popped int64 // number of items served before this one was submitted
+	index  int   // position in the costQueue
+	taken  bool
+}
+
+// costQueue is a heap of poolItems ordered by cost and then by submission order.
+type costQueue []*poolItem
+
+func (q costQueue) Len() int { return len(q) }
+
+func (q costQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q costQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *costQueue) Push(x interface{}) {
+	item := x.(*poolItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *costQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,208 @@
+package rieseltest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+func TestPool(t *testing.T) {
+	var testCases = []struct {
+		h, n     int64
+		expected bool
+	}{
+		{15, 5, true},
+		{9, 7, true},
+		{375, 9, true},
+		{105, 8, true},
+		{8565, 15, true},
+		{507, 2005, false},
+		{5, 2, false},
+		{3, 1274, true},
+		{11, 500, false},
+	}
+
+	expected := make(map[[2]int64]bool)
+	numbers := make([]*RieselNumber, len(testCases))
+	for i, c := range testCases {
+		numbers[i], _ = NewRieselNumber(c.h, c.n)
+		expected[[2]int64{numbers[i].h, numbers[i].n}] = c.expected
+	}
+
+	pool, err := NewPool(3, 2, "", nil)
+	if err != nil {
+		t.Fatalf("NewPool returned an error: %v", err)
+	}
+
+	go func() {
+		for _, R := range numbers {
+			pool.Submit(R)
+		}
+		pool.Close()
+	}()
+
+	count := 0
+	for r := range pool.Results() {
+		count++
+		if r.Err != nil {
+			t.Errorf("Testing %v returned an error: %v", r.R, r.Err)
+		} else if r.Prime != expected[[2]int64{r.R.h, r.R.n}] {
+			t.Errorf("Testing %v returned %v, but we expected %v", r.R, r.Prime, !r.Prime)
+		}
+	}
+
+	if count != len(testCases) {
+		t.Errorf("The pool returned %v results, but we expected %v", count, len(testCases))
+	}
+}
+
+func TestPoolResume(t *testing.T) {
+	dir := t.TempDir()
+
+	// Simulate a previous run, interrupted while testing 3*2^1274-1
+	// and which already completed 15*2^5-1.
+	R, _ := NewRieselNumber(3, 1274)
+	v1, _ := GenV1(R, RODSETH)
+	u, _ := GenU2(R, v1)
+	interrupted := errors.New("interrupted")
+	_, err := genUNFrom(R, u, 3, func(i int64, u *big.Int) error {
+		if i < 1000 {
+			return nil
+		}
+		if err := SaveCheckpoint(filepath.Join(dir, "worker-4.ckpt"), &Checkpoint{H: 3, N: 1274, V1: v1,
+			Iteration: i, U: u}); err != nil {
+			t.Fatal(err)
+		}
+		return interrupted
+	})
+	if err != interrupted {
+		t.Fatalf("genUNFrom returned %v, but we expected it to be interrupted", err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "completed"), []byte("15 5 true 0000000000000000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := NewPool(1, 1, dir, nil)
+	if err != nil {
+		t.Fatalf("NewPool returned an error: %v", err)
+	}
+
+	go func() {
+		for _, c := range [][2]int64{{15, 5}, {3, 1274}} {
+			R, _ := NewRieselNumber(c[0], c[1])
+			pool.Submit(R)
+		}
+		pool.Close()
+	}()
+
+	for r := range pool.Results() {
+		switch {
+		case r.Err != nil:
+			t.Errorf("Testing %v returned an error: %v", r.R, r.Err)
+		case !r.Prime:
+			t.Errorf("Testing %v returned false, but we expected true", r.R)
+		case r.R.h == 15 && r.Worker != -1:
+			t.Errorf("%v should have been taken from the completed file, but it was tested again", r.R)
+		case r.R.h == 3 && r.ResumedFrom != 1000:
+			t.Errorf("%v should have been resumed from U(1000), but it was resumed from U(%v)", r.R, r.ResumedFrom)
+		}
+	}
+
+	// Both results must now be in the completed file
+	pool, _ = NewPool(1, 1, dir, nil)
+	pool.Close()
+	for range pool.Results() {
+	}
+	if len(pool.completed) != 2 {
+		t.Errorf("The completed file contains %v results, but we expected 2", len(pool.completed))
+	}
+}
+
+func TestPoolRemovesCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+
+	// Simulate a previous run which left the checkpoints of 15*2^5-1, since
+	// completed, of 9*2^7-1, resubmitted, and of 11*2^500-1, never resubmitted
+	for i, c := range [][2]int64{{15, 5}, {9, 7}, {11, 500}} {
+		path := filepath.Join(dir, fmt.Sprintf("worker-%d.ckpt", i))
+		if err := SaveCheckpoint(path, &Checkpoint{H: c[0], N: c[1], V1: 3, Iteration: 2, U: big.NewInt(4)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "completed"), []byte("15 5 true 0000000000000000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := NewPool(1, 1, dir, nil)
+	if err != nil {
+		t.Fatalf("NewPool returned an error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "resume-15-5.ckpt")); !os.IsNotExist(err) {
+		t.Errorf("The checkpoint of the completed 15 * 2^5 - 1 was not removed")
+	}
+
+	R, _ := NewRieselNumber(9, 7)
+	pool.Submit(R)
+	pool.Close()
+	for r := range pool.Results() {
+		if r.Err != nil {
+			t.Errorf("Testing %v returned an error: %v", r.R, r.Err)
+		}
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.ckpt"))
+	if len(paths) != 0 {
+		t.Errorf("The finished pool left the checkpoints %v", paths)
+	}
+}
+
+func TestPoolStop(t *testing.T) {
+	dir := t.TempDir()
+	stop := make(chan struct{})
+
+	opts := DefaultOptions()
+	opts.Stop = stop
+	pool, err := NewPool(1, 1, dir, opts)
+	if err != nil {
+		t.Fatalf("NewPool returned an error: %v", err)
+	}
+
+	// Stop the pool while the first number is being tested: the next ones
+	// must not be tested, and the submission must fail
+	errc := make(chan error, 1)
+	go func() {
+		defer pool.Close()
+		for n := int64(20000); n < 20010; n++ {
+			R, _ := NewRieselNumber(3, n)
+			if err := pool.Submit(R); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- nil
+	}()
+
+	count := 0
+	for r := range pool.Results() {
+		count++
+		if count == 1 {
+			close(stop)
+		}
+		if r.Err != nil && r.Err != ErrInterrupted {
+			t.Errorf("Testing %v returned an error: %v", r.R, r.Err)
+		}
+	}
+
+	if count > 2 {
+		t.Errorf("The stopped pool returned %v results, but we expected at most 2", count)
+	}
+	if err := <-errc; err == nil {
+		t.Errorf("Submitting to the stopped pool did not fail")
+	}
+}
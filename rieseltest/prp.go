@@ -0,0 +1,73 @@
+package rieseltest
+
+import (
+	"errors"
+	"fmt"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// DefaultPRPBase is the base of the Fermat test used when none is given.
+const DefaultPRPBase = 3
+
+// FermatPRP performs a Fermat probable prime test of R in the given base b,
+// i.e. it checks whether b^(N-1) == 1 mod N. It returns true if N is a
+// probable prime, and the RES64 of b^(N-1) mod N, which is 1 in that case.
+//
+// Unlike the Lucas-Lehmer-Riesel test, a Fermat test cannot prove that N is
+// prime, but it is a quick way to cross-check a result with an independent
+// algorithm.
+//
+// This function requires:
+//		a) 2 <= b <= 256
+//
+// Since N+1 = h*2^n, we compute b^(N+1) as b^h squared n times, with the fast
+// reduction of rieselMod, and then b^(N-1) = b^(N+1) / b^2 mod N. When stop is
+// closed, the test is interrupted and ErrInterrupted is returned.
+func FermatPRP(R *RieselNumber, b int64, stop <-chan struct{}) (bool, uint64, error) {
+
+	// Check preconditions
+	if R == nil {
+		return false, 0, errors.New("Received R == nil")
+	}
+	if b < 2 || b > 256 {
+		return false, 0, errors.New(fmt.Sprintf("Expected 2 <= b <= 256, but received b = %v", b))
+	}
+
+	// The small factors of N also give the PRP test its answer, and they
+	// make sure that b is invertible mod N
+	check, err := screenEasyPrimes(R)
+	if err != nil {
+		return false, 0, err
+	}
+	if check != 0 {
+		return check == 1, residue64(new(big.Int).Exp(big.NewInt(b), new(big.Int).Sub(R.N, one), R.N)), nil
+	}
+
+	bBig := big.NewInt(b)
+	x := new(big.Int).Exp(bBig, R.hBig, R.N)
+	for i := int64(1); i <= R.n; i++ {
+		if stop != nil && i % 1000 == 0 {
+			select {
+			case <-stop:
+				return false, 0, ErrInterrupted
+			default:
+			}
+		}
+
+		x.Mul(x, x)
+		rieselMod(x, R)
+	}
+
+	// b^(N-1) = b^(N+1) * (b^2)^-1 mod N
+	inv := new(big.Int).Mul(bBig, bBig)
+	if inv.ModInverse(inv, R.N) == nil {
+		return false, 0, errors.New(fmt.Sprintf("%v is not invertible mod N = %v", b, R))
+	}
+	x.Mul(x, inv)
+	x.Mod(x, R.N)
+
+	return x.Cmp(one) == 0, residue64(x), nil
+}
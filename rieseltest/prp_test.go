@@ -0,0 +1,54 @@
+package rieseltest
+
+import (
+	"testing"
+)
+
+func TestFermatPRP(t *testing.T) {
+	var testCases = []struct {
+		h, n             int64
+		b                int64
+		expected_prp     bool
+		expected_residue uint64
+	}{
+		{3, 1274, 3, true, 1},
+		{3, 1274, 2, true, 1},
+		{8565, 15, 3, true, 1},
+		{375, 9, 5, true, 1},
+		{1, 127, 3, true, 1},
+		{5, 148, 3, true, 1},
+		{3, 2, 3, true, 1},
+		{507, 2005, 3, false, 0x149780394F329D4E},
+		{1, 67, 3, false, 0x2E99406CF50FC7F1},
+		{9, 10, 3, false, 0x400},
+	}
+
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		prp, residue, err := FermatPRP(R, c.b, nil)
+		if err != nil {
+			t.Errorf("FermatPRP(%v, %v) returned an error: %v", R, c.b, err)
+		} else if prp != c.expected_prp || residue != c.expected_residue {
+			t.Errorf("FermatPRP(%v, %v) == %v, %016X, but we expected %v, %016X", R, c.b, prp, residue,
+				c.expected_prp, c.expected_residue)
+		}
+	}
+}
+
+func TestFermatPRPErrors(t *testing.T) {
+	R, _ := NewRieselNumber(3, 1274)
+	for _, b := range []int64{-3, 0, 1, 257} {
+		if _, _, err := FermatPRP(R, b, nil); err == nil {
+			t.Errorf("FermatPRP(%v, %v) did not return an error", R, b)
+		}
+	}
+	if _, _, err := FermatPRP(nil, 3, nil); err == nil {
+		t.Errorf("FermatPRP(nil, 3) did not return an error")
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if _, _, err := FermatPRP(R, 3, stop); err != ErrInterrupted {
+		t.Errorf("FermatPRP(%v, 3) with a closed stop returned %v, but we expected ErrInterrupted", R, err)
+	}
+}
@@ -3,6 +3,10 @@ package rieseltest
 import (
 	"fmt"
 	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 
 	big "math/big"
 	// big "github.com/arcetri/gmp"
@@ -20,34 +24,57 @@ type RieselNumber struct {
 	N *big.Int	// h*2^n-1
 }
 
+// MaxN is the largest n of the numbers h*2^n-1 which can be constructed, so
+// that an n too large for N to fit in memory is refused before N is computed.
+const MaxN = 1 << 32
+
+// RieselParams returns the odd h and the n of the number h*2^n-1, as they are
+// normalized by NewRieselNumber, without computing N. An error is returned if
+// h and n do not satisfy the requirements of NewRieselNumber.
+func RieselParams(h, n int64) (int64, int64, error) {
+
+	// Check preconditions
+	if h < 1 {
+		return 0, 0, errors.New(fmt.Sprintf("Expected h > 0, but received h = %v", h))
+	}
+	if n < 2 {
+		return 0, 0, errors.New(fmt.Sprintf("Expected n > 1, but received n = %v", n))
+	}
+	if n > MaxN {
+		return 0, 0, errors.New(fmt.Sprintf("Expected n <= %v, but received n = %v", int64(MaxN), n))
+	}
+
+	// Make h odd by moving powers of two over 2^n
+	if lbit, err := lowerNonZeroBit(h); err == nil && lbit > 0 {
+		n += int64(lbit)
+		h >>= lbit
+	}
+	if n > MaxN {
+		return 0, 0, errors.New(fmt.Sprintf("Expected n <= %v once h is made odd, but received n = %v",
+			int64(MaxN), n))
+	}
+
+	return h, n, nil
+}
+
 // NewRieselNumber constructs a new RieselNumber instance with the given h and n
 //
 // This function requires:
 //		a) h >= 1
-//		b) n >= 2
+//		b) 2 <= n <= MaxN
 //
 // When h is even, we will reduce it to odd and add number of times we had
 // to divide it by two to n.
 func NewRieselNumber(h, n int64) (*RieselNumber, error) {
-
-	// Check preconditions
-	if h < 1 {
-		return nil, errors.New(fmt.Sprintf("Expected h > 0, but received h = %v", h))
-	}
-	if n < 2 {
-		return nil, errors.New(fmt.Sprintf("Expected n > 1, but received n = %v", n))
+	h, n, err := RieselParams(h, n)
+	if err != nil {
+		return nil, err
 	}
 
 	r := new(RieselNumber)
 	r.h = h
 	r.n = n
 
-	// Make h odd by moving powers of two over 2^n
-	if lbit, err := lowerNonZeroBit(r.h); err == nil && lbit > 0 {
-		r.n += int64(lbit)
-		r.h >>= lbit
-	}
-
 	r.hBig = new(big.Int).SetInt64(r.h)
 	r.nBig = new(big.Int).SetInt64(r.n)
 
@@ -60,7 +87,81 @@ func NewRieselNumber(h, n int64) (*RieselNumber, error) {
 	return r, nil
 }
 
+// Params returns the h and n of the Riesel number h*2^n-1.
+//
+// Notice that h is always odd, since NewRieselNumber moves its powers of two over 2^n.
+func (R *RieselNumber) Params() (h, n int64) {
+	return R.h, R.n
+}
+
 // Custom "toString" functionality to print instances of RieselNumber as h*2^n-1
 func (R *RieselNumber) String() string {
 	return fmt.Sprintf("%v * 2^%v - 1", R.h, R.n)
-}
\ No newline at end of file
+}
+
+// Digits returns the number of decimal digits of N = h*2^n-1.
+//
+// Large numbers are not converted to decimal: the digits are counted from the
+// logarithm of h*2^n, which has as many digits as N since it is not a power of
+// 10 (it would require h == 5^n with n > 27, but h < 2^63).
+func (R *RieselNumber) Digits() int64 {
+	if R.N.BitLen() <= 1 << 12 {
+		return int64(len(R.N.String()))
+	}
+
+	return int64(math.Floor(math.Log10(float64(R.h)) + float64(R.n) * math.Log10(2))) + 1
+}
+
+// expressionRegexp matches the expressions h*2^n-1 and 2^n-1, without spaces.
+var expressionRegexp = regexp.MustCompile(`^(?:([0-9]+)\*)?2\^([0-9]+)-1$`)
+
+// decimalRegexp matches the decimal numbers.
+var decimalRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+// ParseRieselNumber parses a Riesel number written either as an expression,
+// such as "3*2^1000-1" or "2^127-1", or as the decimal digits of N, such as
+// "8191". The spaces are ignored. A decimal N must have the form h*2^n-1 with
+// n >= 2 and h < 2^63.
+func ParseRieselNumber(s string) (*RieselNumber, error) {
+	h, n, err := ParseRieselParams(s)
+	if err != nil {
+		return nil, err
+	}
+	return NewRieselNumber(h, n)
+}
+
+// ParseRieselParams parses a Riesel number like ParseRieselNumber, and
+// returns its h and n as RieselParams does, without computing N.
+func ParseRieselParams(s string) (int64, int64, error) {
+	expr := strings.Join(strings.Fields(s), "")
+
+	if m := expressionRegexp.FindStringSubmatch(expr); m != nil {
+		h := int64(1)
+		if m[1] != "" {
+			var err error
+			if h, err = strconv.ParseInt(m[1], 10, 64); err != nil {
+				return 0, 0, errors.New(fmt.Sprintf("Invalid h in %q: %v", s, err))
+			}
+		}
+		n, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return 0, 0, errors.New(fmt.Sprintf("Invalid n in %q: %v", s, err))
+		}
+		return RieselParams(h, n)
+	}
+
+	if decimalRegexp.MatchString(expr) {
+		N, _ := new(big.Int).SetString(expr, 10)
+
+		// N + 1 = h * 2^n, with h odd
+		hBig := new(big.Int).Add(N, one)
+		n := int64(hBig.TrailingZeroBits())
+		hBig.Rsh(hBig, uint(n))
+		if n < 2 || hBig.BitLen() > 63 {
+			return 0, 0, errors.New(fmt.Sprintf("%v is not of the form h*2^n-1 with n >= 2 and h < 2^63", expr))
+		}
+		return RieselParams(hBig.Int64(), n)
+	}
+
+	return 0, 0, errors.New(fmt.Sprintf("Invalid number %q: expected h*2^n-1, 2^n-1 or the decimal digits of N", s))
+}
@@ -1,8 +1,11 @@
 package rieseltest
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
-// Test that NewRieselNumber accepts only h >= 1 and n >= 2
+// Test that NewRieselNumber accepts only h >= 1 and 2 <= n <= MaxN
 func TestNewRieselNumberErrors(t *testing.T) {
 	var testCases = []struct {
 		h int64
@@ -15,6 +18,10 @@ func TestNewRieselNumberErrors(t *testing.T) {
 		{9401, 0},
 		{77, -2},
 		{0, 0},
+		{3, MaxN + 1},
+		{4, MaxN - 1},
+		{3, math.MaxInt64},
+		{4, math.MaxInt64},
 	}
 
 	for _, c := range testCases {
@@ -46,4 +53,67 @@ func TestNewRieselNumber(t *testing.T) {
 				"we got R.h = %v and R.n = %v", c.h, c.n, c.expected_h, c.expected_n, R.h, R.n)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestParseRieselNumber(t *testing.T) {
+	var testCases = []struct {
+		s          string
+		expected_h int64
+		expected_n int64
+	}{
+		{"3*2^1000-1", 3, 1000},
+		{"2^127-1", 1, 127},
+		{" 375 * 2^9 - 1 ", 375, 9},
+		{"6*2^5-1", 3, 6},
+		{"8191", 1, 13},
+		{"191999", 375, 9},
+		{"11", 3, 2},
+	}
+
+	for _, c := range testCases {
+		h, n, err := ParseRieselParams(c.s)
+		if err != nil || h != c.expected_h || n != c.expected_n {
+			t.Errorf("ParseRieselParams(%q) = %v, %v, %v, but we expected %v, %v", c.s, h, n, err, c.expected_h,
+				c.expected_n)
+		}
+
+		R, err := ParseRieselNumber(c.s)
+		if err != nil {
+			t.Errorf("ParseRieselNumber(%q) returned an error: %v", c.s, err)
+		} else if h, n := R.Params(); h != c.expected_h || n != c.expected_n {
+			t.Errorf("ParseRieselNumber(%q) = %v, but we expected %v * 2^%v - 1", c.s, R, c.expected_h, c.expected_n)
+		}
+	}
+}
+
+func TestParseRieselNumberErrors(t *testing.T) {
+	for _, s := range []string{"", "abc", "3*2^1000+1", "3*5^10-1", "2^1-1", "3*2^-5-1", "0*2^5-1", "9",
+		"13", "99999999999999999999*2^5-1", "-5", "3*2^1000-1x", "3*2^9223372036854775807-1",
+		"3*2^99999999999-1"} {
+		if R, err := ParseRieselNumber(s); err == nil {
+			t.Errorf("ParseRieselNumber(%q) = %v, but we expected an error", s, R)
+		}
+	}
+}
+
+func TestDigits(t *testing.T) {
+	var testCases = []struct {
+		h, n     int64
+		expected int64
+	}{
+		{3, 2, 2},
+		{25, 2, 2},
+		{375, 9, 6},
+		{1, 127, 39},
+		{3, 1274, 384},
+		{5, 148, 46},
+		{3, 6090515, 1833429},
+	}
+
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		if actual := R.Digits(); actual != c.expected {
+			t.Errorf("Digits() of %v == %v, but we expected %v", R, actual, c.expected)
+		}
+	}
+}
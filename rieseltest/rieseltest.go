@@ -7,6 +7,10 @@ import (
 	"fmt"
 	"math"
 	"errors"
+	"os"
+	"time"
+
+	"github.com/arcetri/goprime/rieseltest/knownprimes"
 
 	// Mathematical library implementing the necessary methods
 	big "math/big"
@@ -31,54 +35,350 @@ func init() {
 // The following conditions must be true for the test to work:
 //		a) n >= 2
 //		b) h >= 1
+//
+// IsPrime is equivalent to calling Test with the default options.
 func IsPrime(R *RieselNumber) (bool, error) {
+	result, err := Test(R, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return result.Prime, nil
+}
+
+// DefaultCheckpointInterval is the number of U(i) iterations between two
+// checkpoints, used when Options.CheckpointInterval is not set.
+const DefaultCheckpointInterval = 10000
+
+// Options configures a primality test performed through Test.
+type Options struct {
+
+	// V1Method is the algorithm used by GenV1 (RIESEL, RODSETH or PENNE).
+	V1Method uint8
+
+	// V1Selector, when set and handling the same h as the tested number, is
+	// used to select V(1) instead of GenV1.
+	V1Selector *V1Selector
+
+	// V1Table, when set and for the same h as the tested number, is looked up
+	// for V(1) before it is generated. The V(1) of the table is verified
+	// through its V1Proof, so that a wrong table cannot produce a wrong result.
+	V1Table *V1Table
+
+	// U2Method is the algorithm used to generate U(2) = V(h) (LADDER or PRAC).
+	U2Method uint8
+
+	// CheckpointFile is the file where the state of GenUN is periodically saved.
+	// If the file already contains a checkpoint for the same h and n, the test
+	// resumes from it instead of starting over. Once the test is complete, the
+	// file is removed. When empty, no checkpoint is written.
+	CheckpointFile string
+
+	// CheckpointInterval is the number of U(i) iterations between two checkpoints.
+	CheckpointInterval int64
+
+	// Stop, when closed, interrupts the test at the next U(i) iteration. If a
+	// CheckpointFile is set, a checkpoint is saved before Test returns ErrInterrupted.
+	Stop <-chan struct{}
+
+	// InterimInterval, when positive, records the RES64 of U(i) in the Result
+	// and in the log every InterimInterval iterations, so that two runs of the
+	// same test can be compared before they complete.
+	InterimInterval int64
+
+	// InterimPowersOfTwo records the RES64 of U(i) when i is a power of two,
+	// in addition to the iterations selected by InterimInterval.
+	InterimPowersOfTwo bool
+
+	// MersennePrecheck, when h == 1, looks for a factor of N before running
+	// the Lucas-Lehmer test: N is composite if n is, and otherwise its factors
+	// in the form 2*k*n + 1 are tried up to MersenneFactorLimit.
+	MersennePrecheck bool
+
+	// MersenneFactorLimit is the largest factor tried by MersennePrecheck.
+	// When 0, DefaultMersenneFactorLimit is used.
+	MersenneFactorLimit uint64
+
+	// KnownPrimes, when set, is looked up before testing N: a known prime is
+	// reported as such without being tested again. It is not set by
+	// DefaultOptions, so that every number is actually tested.
+	KnownPrimes *knownprimes.DB
+
+	// Monitor, when set, is notified of the progress of the test.
+	Monitor Monitor
+}
+
+// ErrInterrupted is returned by Test when the test is stopped through Options.Stop.
+var ErrInterrupted = errors.New("The test was interrupted")
+
+// DefaultOptions returns the options used by IsPrime.
+func DefaultOptions() *Options {
+	return &Options{
+		V1Method:           RODSETH,
+		CheckpointInterval: DefaultCheckpointInterval,
+		MersennePrecheck:   true,
+	}
+}
+
+// Result holds the outcome of a primality test performed through Test.
+type Result struct {
+	R     *RieselNumber
+	Prime bool
+
+	// Residue is the RES64 of U(n) mod N, i.e. its lowest 64 bits. It is 0 when
+	// N is prime, and it is the value that two independent runs should agree on.
+	Residue uint64
+
+	// V1 is the V(1) used for the test. It is 0 when the test was decided by
+	// the screening of small primes.
+	V1 int64
+
+	// Screened is true when the result was decided by screenEasyPrimes,
+	// without running the Lucas-Lehmer-Riesel test.
+	Screened bool
+
+	// Known is true when N was found in Options.KnownPrimes, without running
+	// the Lucas-Lehmer-Riesel test.
+	Known bool
+
+	// Factor is a factor of N found by Options.MersennePrecheck, or nil.
+	Factor *big.Int
+
+	// ResumedFrom is the iteration of the checkpoint from which the test was
+	// resumed, or 0 if the test started from the beginning.
+	ResumedFrom int64
+
+	// Certificate is the proof that N is prime. It is only set when the
+	// primality was proven by the Lucas-Lehmer-Riesel test.
+	Certificate *Certificate
+
+	// Interim are the RES64 of the U(i) selected by Options.InterimInterval
+	// and Options.InterimPowersOfTwo, sorted by iteration.
+	Interim []InterimResidue
+
+	// Time spent generating V(1), U(2) and U(n). When the test is resumed from
+	// a checkpoint, these only account for the current run.
+	V1Time time.Duration
+	U2Time time.Duration
+	UNTime time.Duration
+}
+
+// ResidueString returns the RES64 of the result in the usual 16 hex digits format.
+func (r *Result) ResidueString() string {
+	return fmt.Sprintf("%016X", r.Residue)
+}
+
+// Test performs a full Lucas-Lehmer-Riesel primality test on the given
+// Riesel number R = h * 2^n - 1 (see IsPrime), and returns the details of the
+// computation in a Result.
+//
+// If opts is nil, DefaultOptions() are used.
+func Test(R *RieselNumber, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if opts.Monitor == nil || R == nil {
+		return test(R, opts)
+	}
+
+	opts.Monitor.Started(R)
+	result, err := test(R, opts)
+	if err != nil && err != ErrInterrupted {
+		opts.Monitor.Error(R, err)
+	}
+	opts.Monitor.Done(R, result, err)
+	return result, err
+}
+
+// test implements Test, with non nil options.
+func test(R *RieselNumber, opts *Options) (*Result, error) {
 
 	// Check preconditions
+	if R == nil {
+		return nil, errors.New("Received R == nil")
+	}
 	if R.h < 1 {
-		return false, errors.New(fmt.Sprintf("Expected h >= 1, but received h = %v", R.h))
+		return nil, errors.New(fmt.Sprintf("Expected h >= 1, but received h = %v", R.h))
 	}
 	if R.n < 2 {
-		return false, errors.New(fmt.Sprintf("Expected n >= 2, but received n = %v", R.n))
+		return nil, errors.New(fmt.Sprintf("Expected n >= 2, but received n = %v", R.n))
 	}
 
+	result := &Result{R: R}
+
 	// Check if N is a small prime or a multiple of a small prime
 	if check, err := screenEasyPrimes(R); err == nil && check != 0 {
+		result.Screened = true
+
 		if check == 1 {
-			log.Infof("N = %v is a known prime < 257", R)
-			return true, nil
+			logScreening.Infof("N = %v is a known prime < 257", numberField(R))
+			result.Prime = true
+			return result, nil
 		}
 
-		log.Infof("N = %v has a known factor < 257", R)
-		return false, nil
+		logScreening.Infof("N = %v has a known factor < 257", numberField(R))
+		return result, nil
 	}
 
-	// Step 1: Get a V(1) for the Riesel candidate.
-	//
-	// The 'RIESEL' and 'RODSETH' methods are equivalent.
-	// The 'PENNE' method can be faster but finds a higher V(1),
-	// which might slow down the following steps of the test.
-	v1, err := GenV1(R, RODSETH)
-	if err != nil { return false, err }
-	log.Infof("Generated V(1) = %v", v1)
-
-	// Step 2: Use the generated V(1) to generate U(2) = V(h)
-	u2, err := GenU2(R, v1)
-	if err != nil { return false, err }
-	if loggingEnabled { log.Infof("Generated U(2) = V(h). Last 8 digits: %v", getLastDigits(u2)) }
-
-	// Step 3: Use the generated U(2) to generate U(n)
-	uN, err := GenUN(R, u2)
-	if err != nil { return false, err }
-	if loggingEnabled { log.Infof("Generated U(n). Last 8 digits: %v", getLastDigits(uN)) }
+	// Known primes do not need to be tested again
+	if opts.KnownPrimes != nil && opts.KnownPrimes.Contains(R.h, R.n) {
+		logScreening.Infof("N = %v is a known prime", numberField(R))
+		result.Known = true
+		result.Prime = true
+		return result, nil
+	}
+
+	// Look for a factor of a Mersenne number before running the test
+	if R.h == 1 && opts.MersennePrecheck {
+		limit := opts.MersenneFactorLimit
+		if limit == 0 {
+			limit = DefaultMersenneFactorLimit
+		}
+
+		if f := mersenneFactor(R.n, limit); f != nil {
+			logScreening.Infof("N = %v has the factor %v", numberField(R), f)
+			result.Screened = true
+			result.Factor = f
+			return result, nil
+		}
+	}
+
+	// If a checkpoint of a previous run exists, resume from it
+	var u *big.Int
+	var hashes []InterimHash
+	start := int64(3)
+	if opts.CheckpointFile != "" {
+		if c, err := LoadCheckpoint(opts.CheckpointFile); err == nil && c.H == R.h && c.N == R.n {
+			logCheckpoint.Infof("Resuming N = %v from the checkpoint at U(%v)", numberField(R), iterationField(c.Iteration))
+			result.V1 = c.V1
+			result.ResumedFrom = c.Iteration
+			u = c.U
+			hashes = c.Hashes
+			result.Interim = c.Interim
+			start = c.Iteration + 1
+		} else if err != nil && !os.IsNotExist(err) {
+			logCheckpoint.Warningf("Ignoring the checkpoint %v: %v", opts.CheckpointFile, err)
+			if opts.Monitor != nil {
+				opts.Monitor.Error(R, err)
+			}
+		}
+	}
+
+	if u == nil && R.h == 1 {
+
+		// Mersenne numbers: V(1) = 4, and U(2) = V(h) = V(1), so the
+		// Lucas-Lehmer-Riesel test is the Lucas-Lehmer test.
+		result.V1 = 4
+		u = big.NewInt(4)
+		logUN.Infof("Running the Lucas-Lehmer test of the Mersenne number N = %v", numberField(R))
+
+	} else if u == nil {
+
+		// Step 1: Get a V(1) for the Riesel candidate.
+		//
+		// The 'RIESEL' and 'RODSETH' methods are equivalent.
+		// The 'PENNE' method can be faster but finds a higher V(1),
+		// which might slow down the following steps of the test.
+		//
+		// A V1Selector for the same h uses the 'RODSETH' method, sharing its
+		// Jacobi symbols with the other tests of the same h.
+		//
+		// A V1Table for the same h replaces the 'RODSETH' method for the n
+		// it covers.
+		begin := time.Now()
+		v1, err := selectV1(R, opts)
+		if err != nil { return nil, err }
+		result.V1 = v1
+		result.V1Time = time.Since(begin)
+		logV1.Infof("Generated V(1) = %v", v1)
+
+		// Step 2: Use the generated V(1) to generate U(2) = V(h)
+		begin = time.Now()
+		if opts.U2Method == PRAC {
+			u, err = genU2Prac(R, v1)
+		} else {
+			u, err = GenU2(R, v1)
+		}
+		if err != nil { return nil, err }
+		result.U2Time = time.Since(begin)
+		if loggingEnabled { logU2.Infof("Generated U(2) = V(h). Last 8 digits: %v", getLastDigits(u)) }
+	}
+
+	// Step 3: Use the generated U(2) to generate U(n), saving a checkpoint
+	// every opts.CheckpointInterval iterations if requested.
+	interval := opts.CheckpointInterval
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+
+	step := func(i int64, u *big.Int) error {
+		if opts.Monitor != nil {
+			opts.Monitor.Iteration(R, i)
+		}
+		if isHashedIteration(i, R.n) {
+			hashes = append(hashes, hashResidue(i, u))
+		}
+		if isInterimIteration(i, opts) {
+			r := InterimResidue{Iteration: i, Residue: residue64(u)}
+			result.Interim = append(result.Interim, r)
+			logUN.Infof("U(%v) RES64: %v", iterationField(i), residueField(r.Residue))
+		}
+
+		// The last iteration completes the test, there is no need to stop it
+		stopped := false
+		if i < R.n {
+			select {
+			case <-opts.Stop:
+				stopped = true
+			default:
+			}
+		}
+
+		if opts.CheckpointFile != "" && i < R.n && (stopped || i % interval == 0) {
+			err := SaveCheckpoint(opts.CheckpointFile, &Checkpoint{H: R.h, N: R.n, V1: result.V1, Iteration: i, U: u,
+				Hashes: hashes, Interim: result.Interim})
+			if err != nil { return err }
+			if opts.Monitor != nil {
+				opts.Monitor.CheckpointSaved(R, i)
+			}
+		}
+
+		if stopped {
+			logUN.Infof("Interrupted the test of N = %v at U(%v)", numberField(R), iterationField(i))
+			return ErrInterrupted
+		}
+		return nil
+	}
+
+	begin := time.Now()
+	uN, err := genUNFrom(R, u, start, step)
+	if err != nil { return nil, err }
+	result.UNTime = time.Since(begin)
+	if loggingEnabled { logUN.Infof("Generated U(n). Last 8 digits: %v", getLastDigits(uN)) }
+
+	if opts.CheckpointFile != "" {
+		if err := os.Remove(opts.CheckpointFile); err != nil && !os.IsNotExist(err) {
+			logCheckpoint.Warningf("Could not remove the checkpoint %v: %v", opts.CheckpointFile, err)
+		}
+	}
 
 	// Step 4: Check if U(n) == 0 (mod N)
+	result.Residue = residue64(uN)
 	if uN.Cmp(zero) == 0 {
-		log.Infof("N = %v is prime!", R)
-		return true, nil
+		logUN.Infof("N = %v is prime!", numberField(R))
+		result.Prime = true
+
+		if result.Certificate, err = newCertificate(R, result.V1, hashes); err != nil {
+			logV1.Warningf("Could not certify V(1) = %v for N = %v: %v", result.V1, R, err)
+			if opts.Monitor != nil {
+				opts.Monitor.Error(R, err)
+			}
+		}
 	} else {
-		log.Infof("N = %v is composite!", R)
-		return false, nil
+		logUN.Infof("N = %v is composite! RES64: %v", numberField(R), residueField(result.Residue))
 	}
+
+	return result, nil
 }
 
 // GenV1 available algorithms
@@ -163,7 +463,7 @@ func GenV1(R *RieselNumber, method uint8) (int64, error) {
 		}
 
 		// In all these cases, we have that v(1) = 4
-		log.Debugf("h = %v is a multiple of 3, thus V(1) = 4", R.h)
+		logV1.Debugf("h = %v is a multiple of 3, thus V(1) = 4", R.h)
 		return 4, nil
 	}
 
@@ -179,6 +479,41 @@ func GenV1(R *RieselNumber, method uint8) (int64, error) {
 	}
 }
 
+// selectV1 returns the V(1) of R, from the V1Table or the V1Selector of the
+// options when they handle the h of R, and from GenV1 otherwise.
+func selectV1(R *RieselNumber, opts *Options) (int64, error) {
+	selector := opts.V1Selector
+	if selector != nil && selector.h != R.h {
+		selector = nil
+	}
+
+	if opts.V1Table != nil && opts.V1Table.H == R.h {
+		if v1, ok := opts.V1Table.Lookup(R.n); ok {
+			var err error
+			if selector != nil {
+				_, err = selector.Verify(R, v1)
+			} else {
+				_, err = NewV1Proof(R, v1)
+			}
+			if err != nil {
+				return -1, errors.New(fmt.Sprintf("The V(1) table of h = %v is wrong for n = %v: %v", R.h, R.n,
+					err))
+			}
+			logV1.Debugf("Found V(1) = %v in the table of h = %v", v1, R.h)
+			return v1, nil
+		}
+	}
+
+	if selector != nil {
+		proof, err := selector.Select(R.n)
+		if err != nil {
+			return -1, err
+		}
+		return proof.V1, nil
+	}
+	return GenV1(R, opts.V1Method)
+}
+
 // genV1Rodseth computes a valid V(1) value for the given Riesel candidate.
 //
 // This function assumes:
@@ -205,6 +540,18 @@ func genV1Rodseth(h, n int64) (int64, error) {
 		return -1, errors.New(fmt.Sprintf("Expected odd h, but received h = %v", h))
 	}
 
+	// This cache is used by the efficientJacobi function when we do repeated calls of it.
+	efficientJacobiCache := make(map[int64]int)
+
+	return genV1RodsethWith(func(x int64) (int, error) {
+		return efficientJacobi(x, h, n, efficientJacobiCache)
+	})
+}
+
+// genV1RodsethWith finds the V(1) of genV1Rodseth, computing the Jacobi
+// symbols Jacobi(x, N) through the given function.
+func genV1RodsethWith(jacobi func(x int64) (int, error)) (int64, error) {
+
 	// OPTIMIZATION: Store a cache of already computed Jacobi symbols.
 	//
 	// The cache will work as follows:
@@ -226,11 +573,8 @@ func genV1Rodseth(h, n int64) (int64, error) {
 	// The cache will be used to store this information for the Jacobi symbols of the P+2 cases.
 	cache := make(map[int64]bool)
 
-	// This cache is used by the efficientJacobi function when we do repeated calls of it.
-	efficientJacobiCache := make(map[int64]int)
-
 	// This function is used to compute the Jacobi(P-2, N) case.
-	jacobi_minus := func (x, h, n int64) (int, error) {
+	jacobi_minus := func (x int64) (int, error) {
 
 		// Check in the cache if that symbol was already computed before.
 		// If it was computed for a P'=P-4, that means that it must have been Jacobi(P'+2, N) == 1,
@@ -242,7 +586,7 @@ func genV1Rodseth(h, n int64) (int64, error) {
 		// we have computed it in the cache, because we will not need to compute Jacobi(P-2) again for
 		// other increasing values of P.
 		if _, ok := cache[x]; ok {
-			log.Debugf("Retrieved Jacobi(%v, N) from the cache", x)
+			logV1.Debugf("Retrieved Jacobi(%v, N) from the cache", x)
 
 			// We won't need this value anymore, so we can remove it
 			// from the cache to keep it smaller.
@@ -250,18 +594,18 @@ func genV1Rodseth(h, n int64) (int64, error) {
 			return 1, nil
 
 		} else {
-			return efficientJacobi(x, h, n, efficientJacobiCache)
+			return jacobi(x)
 		}
 	}
 
 	// This function is used to compute the Jacobi(P+2, N) case.
-	jacobi_plus := func (x, h, n int64) (int, error) {
+	jacobi_plus := func (x int64) (int, error) {
 
 		// Since we might need to compute Jacobi(P+2, N) again for a P'=P+4 later,
 		// we store the fact that we have computed it in the cache before returning it.
 		cache[x] = true
 
-		return efficientJacobi(x, h, n, efficientJacobiCache)
+		return jacobi(x)
 	}
 
 	// Check if there is a P which satisfies Rodseth conditions.
@@ -275,12 +619,12 @@ func genV1Rodseth(h, n int64) (int64, error) {
 		var err error
 
 		// Compute Jacobi(P - 2, N) and check for condition 1
-		if j_minus, err = jacobi_minus(P - 2, h, n); err == nil && j_minus == 1 {
-			log.Debugf("Jacobi(%v - 2, N) == 1: 1st condition passed", P)
+		if j_minus, err = jacobi_minus(P - 2); err == nil && j_minus == 1 {
+			logV1.Debugf("Jacobi(%v - 2, N) == 1: 1st condition passed", P)
 
 			// Compute Jacobi(P + 2, N) and check for condition 2
-			if j_plus, err = jacobi_plus(P + 2, h, n); err == nil && j_plus == -1 {
-				log.Debugf("Jacobi(%v + 2, N) == -1: 2nd condition passed", P)
+			if j_plus, err = jacobi_plus(P + 2); err == nil && j_plus == -1 {
+				logV1.Debugf("Jacobi(%v + 2, N) == -1: 2nd condition passed", P)
 				return P, nil
 			}
 		}
@@ -393,7 +737,7 @@ func genV1Riesel(h, n int64) (int64, error) {
 			// We can immediately verify if the current candidate for V(1) is valid:
 			//      when sign == false, then Jacobi(D, N) = -1  ->  Check VALID
 			//      when sign == true, then Jacobi(D, N) = 1  -> Check NOT VALID
-			log.Debugf("[C1] Jacobi(%v, N) = 1: %v is not a valid candidate for V(1)", D, v)
+			logV1.Debugf("[C1] Jacobi(%v, N) = 1: %v is not a valid candidate for V(1)", D, v)
 			continue
 
 		} else if hmodd != 0 {
@@ -415,7 +759,7 @@ func genV1Riesel(h, n int64) (int64, error) {
 			// Compute Jacobi(N, dred) or get it from the cache if it was already computed.
 			var jNd int
 			if val, ok := cache[dred]; ok {
-				log.Debugf("Retrieved Jacobi(N, %v) from the cache", dred)
+				logV1.Debugf("Retrieved Jacobi(N, %v) from the cache", dred)
 				jNd = val
 
 			} else {
@@ -452,7 +796,7 @@ func genV1Riesel(h, n int64) (int64, error) {
 			// This is the first condition of the Riesel theorem. If the candidate
 			// V(1) does not satisfy it, we can move to the next candidate.
 			if ((sign == true) && (jNd == 1)) || ((sign == false) && (jNd == -1)) {
-				log.Debugf("[C1] Jacobi(%v, N) = 1: %v is not a valid candidate for V(1)", D, v)
+				logV1.Debugf("[C1] Jacobi(%v, N) = 1: %v is not a valid candidate for V(1)", D, v)
 				continue
 			}
 		}
@@ -468,7 +812,7 @@ func genV1Riesel(h, n int64) (int64, error) {
 		//
 		// Read TODO paper for a better explanation of this
 		if issquare, err := isPerfectSquare(v - 2); issquare && err == nil {
-			log.Debugf("%v-2 is a perfect square -> alpha = epsilon^2 -> %v is a valid V(1) candidate", v, v)
+			logV1.Debugf("%v-2 is a perfect square -> alpha = epsilon^2 -> %v is a valid V(1) candidate", v, v)
 			return v, nil
 
 		} else if err != nil {
@@ -554,7 +898,7 @@ func genV1Riesel(h, n int64) (int64, error) {
 				// We can immediately verify if the current candidate for V(1) is valid:
 				//      when sign == false, then C2 = -1  ->  Check VALID
 				//      when sign == true, then C2 = 1  -> Check NOT VALID
-				log.Debugf("[C2] Jacobi(%v, N) * sign = 1: %v is NOT a valid candidate for V(1)", ared, v)
+				logV1.Debugf("[C2] Jacobi(%v, N) * sign = 1: %v is NOT a valid candidate for V(1)", ared, v)
 				continue
 
 			} else if hmoda != 0 {
@@ -575,7 +919,7 @@ func genV1Riesel(h, n int64) (int64, error) {
 				// Compute Jacobi(N,ared) or get it from the cache if it was already computed.
 				var jNa int
 				if val, ok := cache[ared]; ok {
-					log.Debugf("Retrieved Jacobi(N, %v) from the cache", ared)
+					logV1.Debugf("Retrieved Jacobi(N, %v) from the cache", ared)
 					jNa = val
 
 				} else {
@@ -610,7 +954,7 @@ func genV1Riesel(h, n int64) (int64, error) {
 				// Verify that C2 is verified.
 				// If the candidate V(1) does not satisfy it, we move to the next candidate.
 				if ((sign == true) && (jNa == 1)) || ((sign == false) && (jNa == -1)) {
-					log.Debugf("[C2] Jacobi(%v, N) * sign = 1: %v is not a valid candidate for V(1)", ared, v)
+					logV1.Debugf("[C2] Jacobi(%v, N) * sign = 1: %v is not a valid candidate for V(1)", ared, v)
 					continue
 				}
 			}
@@ -812,7 +1156,21 @@ func genV1Penne(h, n int64) (int64, error) {
 //		V(2*x+1) = V(x+1) * V(x) - V(1)
 //
 // To prevent V(x) from growing too large, we will replace all V(x) with (V(x) mod N).
+//
+// The two products of every step of the ladder are computed in parallel.
 func GenU2(R *RieselNumber, v1 int64) (*big.Int, error) {
+	return genU2Ladder(R, v1, true)
+}
+
+// GenU2Sequential computes U(2) like GenU2, but computes the two products of
+// every step of the ladder one after the other, so that both can be compared.
+func GenU2Sequential(R *RieselNumber, v1 int64) (*big.Int, error) {
+	return genU2Ladder(R, v1, false)
+}
+
+// genU2Ladder implements GenU2, computing the two products of every step of
+// the ladder in parallel if requested.
+func genU2Ladder(R *RieselNumber, v1 int64, parallel bool) (*big.Int, error) {
 
 	// Check preconditions
 	if R.h < 1 {
@@ -873,9 +1231,10 @@ func GenU2(R *RieselNumber, v1 int64) (*big.Int, error) {
 	s.Sub(s, two)
 
 	// These two channels will be used for the parallel computation
-	// of r and s at every iteration.
-	c_r := make(chan *big.Int)
-	c_s := make(chan *big.Int)
+	// of r and s at every iteration. They are buffered, so that the
+	// sequential computation does not block on them.
+	c_r := make(chan *big.Int, 1)
+	c_s := make(chan *big.Int, 1)
 
 	bitLen, err := bitLen(R.h)
 	if err != nil {
@@ -894,9 +1253,14 @@ func GenU2(R *RieselNumber, v1 int64) (*big.Int, error) {
 			// 		r = V(2*x+1)
 			// 		s = V(2*x+2)
 			//
-			// These two operations are done in parallel
-			go vTwoXPlusOne(s, r, c_r)
-			go vTwoX(s, c_s)
+			// These two operations are done in parallel, if requested
+			if parallel {
+				go vTwoXPlusOne(s, r, c_r)
+				go vTwoX(s, c_s)
+			} else {
+				vTwoXPlusOne(s, r, c_r)
+				vTwoX(s, c_s)
+			}
 
 			// Receive the resulting r and s from the respective
 			// channels when the threads are done
@@ -904,8 +1268,8 @@ func GenU2(R *RieselNumber, v1 int64) (*big.Int, error) {
 			s = <- c_s
 
 			if loggingEnabled {
-				log.Debugf("r = %v", getLastDigits(r))
-				log.Debugf("s = %v", getLastDigits(s))
+				logU2.Debugf("r = %v", getLastDigits(r))
+				logU2.Debugf("s = %v", getLastDigits(s))
 			}
 
 		} else {
@@ -914,9 +1278,14 @@ func GenU2(R *RieselNumber, v1 int64) (*big.Int, error) {
 			// 		s = V(2*x+1)
 			// 		r = V(2*x)
 			//
-			// These two operations are done in parallel
-			go vTwoXPlusOne(s, r, c_s)
-			go vTwoX(r, c_r)
+			// These two operations are done in parallel, if requested
+			if parallel {
+				go vTwoXPlusOne(s, r, c_s)
+				go vTwoX(r, c_r)
+			} else {
+				vTwoXPlusOne(s, r, c_s)
+				vTwoX(r, c_r)
+			}
 
 			// Receive the resulting r and s from the respective
 			// channels when the threads are done
@@ -924,8 +1293,8 @@ func GenU2(R *RieselNumber, v1 int64) (*big.Int, error) {
 			r = <- c_r
 
 			if loggingEnabled {
-				log.Debugf("_r = %v", getLastDigits(r))
-				log.Debugf("_s = %v", getLastDigits(s))
+				logU2.Debugf("_r = %v", getLastDigits(r))
+				logU2.Debugf("_s = %v", getLastDigits(s))
 			}
 		}
 	}
@@ -936,7 +1305,7 @@ func GenU2(R *RieselNumber, v1 int64) (*big.Int, error) {
 	r.Sub(r, v1_big)
 	rieselMod(r, R)
 
-	if loggingEnabled { log.Debugf(".r = %v", getLastDigits(r)) }
+	if loggingEnabled { logU2.Debugf(".r = %v", getLastDigits(r)) }
 
 	// At this point r = V(h)
 	return r, nil
@@ -970,16 +1339,43 @@ func GenUN(R *RieselNumber, u *big.Int) (*big.Int, error) {
 		return nil, errors.New(fmt.Sprintf("Expected u > 0, but received u = %v", u))
 	}
 
-	// TODO add checkpoints and correctness checks here
-	for i := int64(3); i <= R.n; i++ {
+	return genUNFrom(R, u, 3, nil)
+}
+
+// genUNFrom computes U(n) for the given Riesel candidate, starting from
+// u = U(start - 1).
+//
+// If step is not nil, it is called after every computed U(i) with the current
+// value of i and U(i) mod N. The callback must not modify u. If it returns an
+// error, the computation is stopped and the error is returned.
+func genUNFrom(R *RieselNumber, u *big.Int, start int64, step func(i int64, u *big.Int) error) (*big.Int, error) {
+
+	// Mersenne numbers are reduced by shift and add only
+	var t *big.Int
+	if R.h == 1 {
+		t = new(big.Int)
+	}
+
+	// TODO add correctness checks here
+	for i := start; i <= R.n; i++ {
 
 		// u = (u^2 - 2) mod N
 		u.Mul(u, u)
 		u.Sub(u, two)
-		rieselMod(u, R)
+		if t != nil {
+			mersenneMod(u, R, t)
+		} else {
+			rieselMod(u, R)
+		}
 
-		if loggingEnabled { log.Debugf("U(%v) mod N = %v", i, getLastDigits(u)) }
+		if loggingEnabled { logUN.Debugf("U(%v) mod N = %v", iterationField(i), getLastDigits(u)) }
+
+		if step != nil {
+			if err := step(i, u); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return u, nil
-}
\ No newline at end of file
+}
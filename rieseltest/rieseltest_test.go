@@ -7,6 +7,8 @@ import (
 	"strings"
 	"strconv"
 
+	"github.com/arcetri/goprime/rieseltest/knownprimes"
+
 	big "math/big"
 	// big "github.com/arcetri/gmp"
 	// big "github.com/arcetri/go.flint/fmpz"
@@ -179,6 +181,68 @@ func TestGenU2(t *testing.T) {
 		if actual.Cmp(c.expected) != 0 {
 			t.Errorf("GenU2(%v, %v) == %v, but we expected %v", R, v1, actual, c.expected)
 		}
+
+		actual, _ = GenU2Sequential(R, v1)
+		if actual.Cmp(c.expected) != 0 {
+			t.Errorf("GenU2Sequential(%v, %v) == %v, but we expected %v", R, v1, actual, c.expected)
+		}
+	}
+}
+
+func TestGenU2Prac(t *testing.T) {
+
+	// Test that the PRAC chains compute the same U(2) = V(h) as the ladder
+	var testCases = []struct {
+		h, n int64
+		v1 int64
+	}{
+		{507, 217588, 3},
+		{8565, 15, 9},
+		{391581, 216193, 4},
+		{1<<59 + 1, 5000, 7},
+		{1<<60 - 1, 5000, 5},
+		{1<<62 + 3, 5000, 3},
+	}
+	for h := int64(1); h <= 5001; h += 2 {
+		testCases = append(testCases, struct{ h, n, v1 int64 }{h, 61, 3 + h % 7})
+		testCases = append(testCases, struct{ h, n, v1 int64 }{h, 200, 3 + h % 5})
+	}
+
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		expected, err := GenU2(R, c.v1)
+		if err != nil {
+			t.Fatalf("GenU2(%v, %v) returned an error: %v", R, c.v1, err)
+		}
+
+		actual, err := genU2Prac(R, c.v1)
+		if err != nil {
+			t.Errorf("genU2Prac(%v, %v) returned an error: %v", R, c.v1, err)
+		} else if actual.Cmp(expected) != 0 {
+			t.Errorf("genU2Prac(%v, %v) == %v, but we expected %v", R, c.v1, getLastDigits(actual),
+				getLastDigits(expected))
+		}
+	}
+}
+
+func TestPracChain(t *testing.T) {
+
+	// A PRAC chain must exist, and be cheaper than the ladder for large h
+	for _, h := range []int64{3, 5, 7, 9, 15, 2047, 123456789, 1<<40 + 15, 1<<60 - 1} {
+		rules, ok := pracChain(h)
+		if !ok {
+			t.Errorf("pracChain(%v) returned no chain", h)
+			continue
+		}
+
+		cost := 2
+		for _, rule := range rules {
+			cost += pracCost[rule]
+		}
+		bits, _ := bitLen(h)
+		if h > 1000 && cost >= 2 * int(bits) {
+			t.Errorf("pracChain(%v) costs %v multiplications, but the ladder costs %v", h, cost, 2 * bits)
+		}
 	}
 }
 
@@ -454,3 +518,63 @@ func BenchmarkGenU2Penne(b *testing.B) {
 	}
 }
 
+func TestKnownPrimes(t *testing.T) {
+	db := knownprimes.New()
+	db.Add(8565, 15)
+
+	// A known prime is reported without being tested
+	R, _ := NewRieselNumber(8565, 15)
+	result, err := Test(R, &Options{V1Method: RODSETH, KnownPrimes: db})
+	if err != nil || !result.Prime || !result.Known || result.V1 != 0 {
+		t.Errorf("Test(%v) returned %+v, %v, but we expected a known prime", R, result, err)
+	}
+
+	// Other numbers are tested
+	R, _ = NewRieselNumber(375, 9)
+	result, err = Test(R, &Options{V1Method: RODSETH, KnownPrimes: db})
+	if err != nil || !result.Prime || result.Known || result.V1 != 9 {
+		t.Errorf("Test(%v) returned %+v, %v, but we expected a tested prime", R, result, err)
+	}
+
+	// The embedded database holds 3 * 2^6090515 - 1
+	R, _ = NewRieselNumber(3, 6090515)
+	result, err = Test(R, &Options{V1Method: RODSETH, KnownPrimes: knownprimes.Embedded()})
+	if err != nil || !result.Prime || !result.Known {
+		t.Errorf("Test(%v) returned %+v, %v, but we expected a known prime", R, result, err)
+	}
+
+	// The default options test every number
+	if opts := DefaultOptions(); opts.KnownPrimes != nil {
+		t.Errorf("DefaultOptions() should not look up the known primes, but it has %v", opts.KnownPrimes)
+	}
+}
+
+// benchmarkGenU2Cases are large h, for which the choice of the chain matters
+var benchmarkGenU2Cases = [][2]int64{
+	{1<<40 + 15, 20000},
+	{123456789012345, 20000},
+	{1<<59 + 1, 20000},
+	{987654321987654321, 20000},
+}
+
+func BenchmarkGenU2Ladder(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, c := range benchmarkGenU2Cases {
+			R, _ := NewRieselNumber(c[0], c[1])
+			if _, err := GenU2(R, 5); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+func BenchmarkGenU2Prac(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, c := range benchmarkGenU2Cases {
+			R, _ := NewRieselNumber(c[0], c[1])
+			if _, err := genU2Prac(R, 5); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package rieseltest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// Formats of the scripts written by WriteScript
+const (
+	ScriptCalc = "calc"	// calc, by Landon Curt Noll [Ref5]
+	ScriptGP   = "gp"	// PARI/GP
+)
+
+// scriptData holds the values used by the script templates.
+type scriptData struct {
+	R      *RieselNumber
+	H      int64
+	N      int64
+	Method string
+
+	// Results of goprime, which the script checks
+	V1     int64
+	Prime  bool
+	Res64  uint64
+}
+
+// calcScript is the template of the calc scripts. In calc, the % operator
+// always returns a non-negative value for a positive modulus.
+var calcScript = template.Must(template.New("calc").Parse(`/*
+ * Lucas-Lehmer-Riesel test of N = {{.R}}, exported by goprime.
+ *
+ * This script performs the same steps as goprime with plain calc arithmetic,
+ * and checks that it reaches the same results. Run it with:
+ *
+ *	calc -f script.calc
+ */
+
+h = {{.H}};
+n = {{.N}};
+N = h * 2^n - 1;
+
+/* Step 1: V(1) */
+{{- if eq .Method "case1"}}
+/* h mod 3 != 0, so V(1) = 4 */
+v1 = 4;
+{{- else if eq .Method "rodseth"}}
+/* The first P with jacobi(P-2, N) == 1 and jacobi(P+2, N) == -1 (Rodseth) */
+v1 = 3;
+while (jacobi(v1 - 2, N) != 1 || jacobi(v1 + 2, N) != -1) {
+	v1++;
+}
+{{- else if eq .Method "riesel"}}
+/* The first v with jacobi(v^2-4, N) == -1, and v-2 a square or jacobi(v-2, N) == 1 (Riesel) */
+v1 = 3;
+while (jacobi(v1^2 - 4, N) != -1 || (!issq(v1 - 2) && jacobi(v1 - 2, N) != 1)) {
+	v1++;
+}
+{{- else}}
+/* V(1) = x^2+2 for the first x with jacobi(x^2+4, N) == -1 (Penne) */
+x = 1;
+while (jacobi(x^2 + 4, N) != -1) {
+	x++;
+}
+v1 = x^2 + 2;
+{{- end}}
+
+/* Step 2: U(2) = V(h), with the binary ladder r = V(x), s = V(x+1) */
+r = v1 % N;
+s = (v1^2 - 2) % N;
+for (i = highbit(h) - 1; i >= 0; i--) {
+	if (bit(h, i)) {
+		r = (r * s - v1) % N;
+		s = (s^2 - 2) % N;
+	} else {
+		s = (r * s - v1) % N;
+		r = (r^2 - 2) % N;
+	}
+}
+u = r;
+
+/* Step 3: U(i) = U(i-1)^2 - 2 mod N, for 3 <= i <= n */
+for (i = 3; i <= n; i++) {
+	u = (u^2 - 2) % N;
+}
+
+/* Step 4: verdict, and comparison with goprime */
+res64 = u % 2^64;
+print "V(1) = " : v1;
+if (u == 0) {
+	print "N is prime";
+} else {
+	print "N is composite, RES64 = " : res64;
+}
+if (v1 == {{.V1}} && (u == 0) == {{if .Prime}}1{{else}}0{{end}} && res64 == {{.Res64}}) {
+	print "OK: the results agree with goprime";
+} else {
+	print "MISMATCH: goprime found V(1) = {{.V1}}, {{if .Prime}}prime{{else}}composite{{end}}, RES64 = {{.Res64}}";
+}
+`))
+
+// gpScript is the template of the PARI/GP scripts. The U(i) are computed as
+// Mod objects, so that they are always reduced mod N.
+var gpScript = template.Must(template.New("gp").Parse(`\\ Lucas-Lehmer-Riesel test of N = {{.R}}, exported by goprime.
+\\
+\\ This script performs the same steps as goprime with plain PARI/GP arithmetic,
+\\ and checks that it reaches the same results. Run it with:
+\\
+\\	gp -q script.gp
+
+h = {{.H}};
+n = {{.N}};
+N = h * 2^n - 1;
+
+\\ Step 1: V(1)
+{{- if eq .Method "case1"}}
+\\ h mod 3 != 0, so V(1) = 4
+v1 = 4;
+{{- else if eq .Method "rodseth"}}
+\\ The first P with kronecker(P-2, N) == 1 and kronecker(P+2, N) == -1 (Rodseth)
+v1 = 3;
+while (kronecker(v1 - 2, N) != 1 || kronecker(v1 + 2, N) != -1, v1++);
+{{- else if eq .Method "riesel"}}
+\\ The first v with kronecker(v^2-4, N) == -1, and v-2 a square or kronecker(v-2, N) == 1 (Riesel)
+v1 = 3;
+while (kronecker(v1^2 - 4, N) != -1 || (!issquare(v1 - 2) && kronecker(v1 - 2, N) != 1), v1++);
+{{- else}}
+\\ V(1) = x^2+2 for the first x with kronecker(x^2+4, N) == -1 (Penne)
+x = 1;
+while (kronecker(x^2 + 4, N) != -1, x++);
+v1 = x^2 + 2;
+{{- end}}
+
+\\ Step 2: U(2) = V(h), with the binary ladder r = V(x), s = V(x+1)
+r = Mod(v1, N);
+s = r^2 - 2;
+forstep (i = #binary(h) - 2, 0, -1, if (bittest(h, i), r = r * s - v1; s = s^2 - 2, s = r * s - v1; r = r^2 - 2));
+u = r;
+
+\\ Step 3: U(i) = U(i-1)^2 - 2 mod N, for 3 <= i <= n
+for (i = 3, n, u = u^2 - 2);
+
+\\ Step 4: verdict, and comparison with goprime
+res64 = lift(u) % 2^64;
+print("V(1) = ", v1);
+if (u == 0, print("N is prime"), print("N is composite, RES64 = ", res64));
+if (v1 == {{.V1}} && (u == 0) == {{if .Prime}}1{{else}}0{{end}} && res64 == {{.Res64}}, print("OK: the results agree with goprime"), print("MISMATCH: goprime found V(1) = {{.V1}}, {{if .Prime}}prime{{else}}composite{{end}}, RES64 = {{.Res64}}"));
+quit;
+`))
+
+// WriteScript tests R with the given V(1) method, and writes to w a script in
+// the given format (ScriptCalc or ScriptGP) which performs the same test with
+// an independent tool: the same choice of V(1), the same computation of U(2)
+// and the same U(i) loop. The script then checks that it agrees with the V(1),
+// the verdict and the RES64 found by goprime.
+//
+// An error is returned if N is decided by the screening of small primes,
+// since there is no test to reproduce.
+func WriteScript(w io.Writer, format string, R *RieselNumber, method uint8) error {
+
+	// Check preconditions
+	if R == nil {
+		return errors.New("Received R == nil")
+	}
+
+	var t *template.Template
+	switch format {
+	case ScriptCalc:
+		t = calcScript
+	case ScriptGP:
+		t = gpScript
+	default:
+		return errors.New(fmt.Sprintf("Unknown script format %v, expected %v or %v", format, ScriptCalc, ScriptGP))
+	}
+
+	data := &scriptData{R: R, H: R.h, N: R.n}
+	switch {
+	case R.h % 3 != 0:
+		data.Method = "case1"
+	case method == RIESEL:
+		data.Method = "riesel"
+	case method == RODSETH:
+		data.Method = "rodseth"
+	case method == PENNE:
+		data.Method = "penne"
+	default:
+		return errors.New("The specified method to generate v1 is not valid")
+	}
+
+	result, err := Test(R, &Options{V1Method: method})
+	if err != nil {
+		return err
+	}
+	if result.Screened {
+		return errors.New(fmt.Sprintf("N = %v is decided by the screening of small primes, " +
+			"there is no test to export", R))
+	}
+
+	data.V1 = result.V1
+	data.Prime = result.Prime
+	data.Res64 = result.Residue
+
+	return t.Execute(w, data)
+}
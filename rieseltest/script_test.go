@@ -0,0 +1,109 @@
+package rieseltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteScript(t *testing.T) {
+	var testCases = []struct {
+		h, n   int64
+		method uint8
+		name   string
+	}{
+		{375, 9, RODSETH, "rodseth"},
+		{375, 9, RIESEL, "riesel"},
+		{375, 9, PENNE, "penne"},
+		{5, 148, RODSETH, "rodseth"},
+		{507, 2005, RODSETH, "rodseth"},
+	}
+
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		for _, format := range []string{ScriptCalc, ScriptGP} {
+			path := fmt.Sprintf("testfiles/script_%v_%v_%v.%v", c.h, c.n, c.name, format)
+			golden, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var actual bytes.Buffer
+			if err := WriteScript(&actual, format, R, c.method); err != nil {
+				t.Errorf("WriteScript(%v, %v, %v) returned an error: %v", format, R, c.name, err)
+				continue
+			}
+			if !bytes.Equal(actual.Bytes(), golden) {
+				t.Errorf("WriteScript(%v, %v, %v) does not match %v", format, R, c.name, path)
+			}
+			if err := checkDelimiters(actual.String(), format); err != nil {
+				t.Errorf("The %v script of %v is not well formed: %v", format, R, err)
+			}
+		}
+	}
+}
+
+func TestWriteScriptErrors(t *testing.T) {
+	var b bytes.Buffer
+	R, _ := NewRieselNumber(375, 9)
+	if err := WriteScript(&b, "maple", R, RODSETH); err == nil {
+		t.Errorf("WriteScript accepted an unknown format")
+	}
+
+	// 7 * 2^5 - 1 = 223 is decided by the screening
+	R, _ = NewRieselNumber(7, 5)
+	if err := WriteScript(&b, ScriptCalc, R, RODSETH); err == nil {
+		t.Errorf("WriteScript exported %v, which is decided by the screening", R)
+	}
+}
+
+// checkDelimiters checks that the parentheses and braces of a script are
+// balanced, outside of its comments and strings. In PARI/GP every statement
+// must also be complete on its own line.
+func checkDelimiters(script, format string) error {
+	depth := 0
+	inComment := false
+	for l, line := range strings.Split(script, "\n") {
+		inString := false
+		for i := 0; i < len(line); i++ {
+			switch {
+			case inComment:
+				if strings.HasPrefix(line[i:], "*/") {
+					inComment = false
+					i++
+				}
+			case inString:
+				if line[i] == '"' {
+					inString = false
+				}
+			case format == ScriptCalc && strings.HasPrefix(line[i:], "/*"):
+				inComment = true
+				i++
+			case format == ScriptGP && strings.HasPrefix(line[i:], "\\\\"):
+				i = len(line)
+			case line[i] == '"':
+				inString = true
+			case line[i] == '(' || line[i] == '{':
+				depth++
+			case line[i] == ')' || line[i] == '}':
+				depth--
+				if depth < 0 {
+					return errors.New(fmt.Sprintf("unbalanced %q on line %v", line[i], l + 1))
+				}
+			}
+		}
+		if inString {
+			return errors.New(fmt.Sprintf("unterminated string on line %v", l + 1))
+		}
+		if format == ScriptGP && depth != 0 {
+			return errors.New(fmt.Sprintf("unbalanced delimiters on line %v", l + 1))
+		}
+	}
+	if depth != 0 || inComment {
+		return errors.New("unbalanced delimiters at the end of the script")
+	}
+	return nil
+}
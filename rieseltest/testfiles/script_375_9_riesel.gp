@@ -0,0 +1,31 @@
+\\ Lucas-Lehmer-Riesel test of N = 375 * 2^9 - 1, exported by goprime.
+\\
+\\ This script performs the same steps as goprime with plain PARI/GP arithmetic,
+\\ and checks that it reaches the same results. Run it with:
+\\
+\\	gp -q script.gp
+
+h = 375;
+n = 9;
+N = h * 2^n - 1;
+
+\\ Step 1: V(1)
+\\ The first v with kronecker(v^2-4, N) == -1, and v-2 a square or kronecker(v-2, N) == 1 (Riesel)
+v1 = 3;
+while (kronecker(v1^2 - 4, N) != -1 || (!issquare(v1 - 2) && kronecker(v1 - 2, N) != 1), v1++);
+
+\\ Step 2: U(2) = V(h), with the binary ladder r = V(x), s = V(x+1)
+r = Mod(v1, N);
+s = r^2 - 2;
+forstep (i = #binary(h) - 2, 0, -1, if (bittest(h, i), r = r * s - v1; s = s^2 - 2, s = r * s - v1; r = r^2 - 2));
+u = r;
+
+\\ Step 3: U(i) = U(i-1)^2 - 2 mod N, for 3 <= i <= n
+for (i = 3, n, u = u^2 - 2);
+
+\\ Step 4: verdict, and comparison with goprime
+res64 = lift(u) % 2^64;
+print("V(1) = ", v1);
+if (u == 0, print("N is prime"), print("N is composite, RES64 = ", res64));
+if (v1 == 9 && (u == 0) == 1 && res64 == 0, print("OK: the results agree with goprime"), print("MISMATCH: goprime found V(1) = 9, prime, RES64 = 0"));
+quit;
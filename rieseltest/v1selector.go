@@ -0,0 +1,234 @@
+package rieseltest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// V1Proof justifies the V(1) used to test N = h*2^n-1 with the Jacobi symbols
+// Jacobi(V(1)-2, N) and Jacobi(V(1)+2, N).
+//
+// V(1) is valid if either:
+//		a) Jacobi(V(1)-2, N) == 1 and Jacobi(V(1)+2, N) == -1 (Rödseth criterion [Ref2])
+//		b) V(1) == 4, h mod 3 != 0 and N mod 3 != 0 (Riesel criterion [Ref1])
+type V1Proof struct {
+	H  int64
+	N  int64
+	V1 int64
+
+	JacobiMinus int
+	JacobiPlus  int
+}
+
+// NewV1Proof verifies that v1 is a valid V(1) for R, and returns its proof.
+// An error is returned if v1 is not valid.
+func NewV1Proof(R *RieselNumber, v1 int64) (*V1Proof, error) {
+	if R == nil {
+		return nil, errors.New("Received R == nil")
+	}
+
+	return newV1Proof(R.h, R.n, v1, func(x int64) (int, error) {
+		return efficientJacobi(x, R.h, R.n, nil)
+	})
+}
+
+// newV1Proof builds the proof of v1, computing the Jacobi symbols through the
+// given function.
+func newV1Proof(h, n, v1 int64, jacobi func(x int64) (int, error)) (*V1Proof, error) {
+	if v1 < 3 {
+		return nil, errors.New(fmt.Sprintf("Expected V(1) >= 3, but received V(1) = %v", v1))
+	}
+
+	p := &V1Proof{H: h, N: n, V1: v1}
+
+	var err error
+	if p.JacobiMinus, err = jacobi(v1 - 2); err != nil {
+		return nil, err
+	}
+	if p.JacobiPlus, err = jacobi(v1 + 2); err != nil {
+		return nil, err
+	}
+
+	if err = p.checkCriteria(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Verify checks the proof, recomputing its Jacobi symbols rather than
+// trusting them. Since V(1) is small and N has the form h*2^n-1, this is
+// cheap even for a huge N.
+func (p *V1Proof) Verify() error {
+	if p.H < 1 || p.H % 2 == 0 || p.N < 2 {
+		return errors.New(fmt.Sprintf("Expected odd h >= 1 and n >= 2, but the proof has h = %v and n = %v",
+			p.H, p.N))
+	}
+	if p.V1 < 3 {
+		return errors.New(fmt.Sprintf("Expected V(1) >= 3, but the proof has V(1) = %v", p.V1))
+	}
+
+	jMinus, err := efficientJacobi(p.V1 - 2, p.H, p.N, nil)
+	if err != nil {
+		return err
+	}
+	jPlus, err := efficientJacobi(p.V1 + 2, p.H, p.N, nil)
+	if err != nil {
+		return err
+	}
+
+	if jMinus != p.JacobiMinus || jPlus != p.JacobiPlus {
+		return errors.New(fmt.Sprintf("The proof records Jacobi(%v, N) = %v and Jacobi(%v, N) = %v, " +
+			"but they are %v and %v", p.V1 - 2, p.JacobiMinus, p.V1 + 2, p.JacobiPlus, jMinus, jPlus))
+	}
+
+	return p.checkCriteria()
+}
+
+// checkCriteria checks that the recorded Jacobi symbols justify V(1).
+func (p *V1Proof) checkCriteria() error {
+
+	// Rödseth criterion
+	if p.JacobiMinus == 1 && p.JacobiPlus == -1 {
+		return nil
+	}
+
+	// Riesel criterion: V(1) = 4 when h mod 3 != 0 and N mod 3 != 0
+	if p.V1 == 4 && p.H % 3 != 0 {
+		pow, err := modExp(2, p.N, 3)
+		if err != nil {
+			return err
+		}
+		if (p.H % 3 * pow - 1) % 3 != 0 {
+			return nil
+		}
+	}
+
+	return errors.New(fmt.Sprintf("V(1) = %v does not satisfy the Rödseth criterion for %v * 2^%v - 1",
+		p.V1, p.H, p.N))
+}
+
+// A V1Selector selects the V(1) of the numbers h*2^n-1 for a fixed h, with the
+// RODSETH method, sharing its Jacobi symbols across n.
+//
+// For an odd x not dividing h, Jacobi(N, x) depends on n only through 2^n mod x,
+// and thus only through n mod ord_x(2). The symbols computed for one n are
+// therefore reused by every n in the same class, so that selecting V(1) for
+// thousands of n costs little more than selecting it once.
+//
+// A V1Selector can be used by several goroutines at once.
+type V1Selector struct {
+	h int64
+
+	mu      sync.Mutex
+	symbols map[int64]*periodicJacobi
+}
+
+// periodicJacobi holds the Jacobi(N, x) already computed for an x, indexed by
+// n mod ord_x(2). A value of 0 means that x divides N.
+type periodicJacobi struct {
+	order  int64
+	values map[int64]int
+}
+
+// NewV1Selector returns a V1Selector for the numbers h*2^n-1.
+//
+// This function requires:
+//		a) h >= 1
+//		b) h mod 2 == 1
+func NewV1Selector(h int64) (*V1Selector, error) {
+
+	// Check preconditions
+	if h < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected h >= 1, but received h = %v", h))
+	}
+	if h % 2 == 0 {
+		return nil, errors.New(fmt.Sprintf("Expected odd h, but received h = %v", h))
+	}
+
+	return &V1Selector{h: h, symbols: make(map[int64]*periodicJacobi)}, nil
+}
+
+// H returns the h of the numbers h*2^n-1 handled by the selector.
+func (s *V1Selector) H() int64 {
+	return s.h
+}
+
+// Select returns the V(1) of h*2^n-1, with its proof.
+//
+// This function requires n >= 2. As for GenV1, V(1) = 4 when h mod 3 != 0,
+// and an error is returned if N has a small factor found along the way.
+func (s *V1Selector) Select(n int64) (*V1Proof, error) {
+	if n < 2 {
+		return nil, errors.New(fmt.Sprintf("Expected n >= 2, but received n = %v", n))
+	}
+
+	jacobi := s.jacobi(n)
+	if s.h % 3 != 0 {
+		return newV1Proof(s.h, n, 4, jacobi)
+	}
+
+	v1, err := genV1RodsethWith(jacobi)
+	if err != nil {
+		return nil, err
+	}
+
+	logV1.Debugf("Selected V(1) = %v for N = %v * 2^%v - 1", v1, s.h, n)
+	return newV1Proof(s.h, n, v1, jacobi)
+}
+
+// Verify verifies that v1 is a valid V(1) for R like NewV1Proof, using the
+// Jacobi symbols of the selector when R has the same h.
+func (s *V1Selector) Verify(R *RieselNumber, v1 int64) (*V1Proof, error) {
+	if R == nil {
+		return nil, errors.New("Received R == nil")
+	}
+	if R.h != s.h {
+		return NewV1Proof(R, v1)
+	}
+
+	return newV1Proof(R.h, R.n, v1, s.jacobi(R.n))
+}
+
+// jacobi returns a function computing Jacobi(x, N) for N = h*2^n-1 through
+// the shared symbols of the selector.
+func (s *V1Selector) jacobi(n int64) func(x int64) (int, error) {
+	jacobiNx := func(x, hModX int64) (int, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		p, ok := s.symbols[x]
+		if !ok {
+			p = &periodicJacobi{order: orderOfTwo(x), values: make(map[int64]int)}
+			s.symbols[x] = p
+		}
+
+		class := n % p.order
+		jNx, ok := p.values[class]
+		if !ok {
+			var err error
+			if jNx, err = smallJacobiNx(x, hModX, n); err != nil {
+				jNx = 0
+			}
+			p.values[class] = jNx
+		}
+
+		if jNx == 0 {
+			return 0, errors.New("N has a known factor, it does not need to be tested further.")
+		}
+		return jNx, nil
+	}
+
+	return func(x int64) (int, error) {
+		return efficientJacobiWith(x, s.h, n, jacobiNx)
+	}
+}
+
+// orderOfTwo returns the multiplicative order of 2 modulo an odd x >= 1.
+func orderOfTwo(x int64) int64 {
+	order := int64(1)
+	for v := 2 % x; v != 1 % x; v = v * 2 % x {
+		order++
+	}
+	return order
+}
@@ -0,0 +1,148 @@
+package rieseltest
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestV1SelectorSelect(t *testing.T) {
+
+	// Select V(1) for the known Riesel primes with h multiple of 3, sharing a
+	// selector among the n of the same h
+	file, err := os.Open("testfiles/v1_with_h_multiple_of_3.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	selectors := make(map[int64]*V1Selector)
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		words := strings.Split(s.Text(), " ")
+		h, _ := strconv.ParseInt(words[0], 10, 64)
+		n, _ := strconv.ParseInt(words[1], 10, 64)
+		expected, _ := strconv.ParseInt(words[2], 10, 64)
+
+		// Make h odd without computing N, which is huge for some of the n
+		for h % 2 == 0 {
+			h /= 2
+			n++
+		}
+
+		selector, ok := selectors[h]
+		if !ok {
+			if selector, err = NewV1Selector(h); err != nil {
+				t.Fatalf("NewV1Selector(%v) returned an error: %v", h, err)
+			}
+			selectors[h] = selector
+		}
+
+		proof, err := selector.Select(n)
+		if err != nil {
+			t.Errorf("Select(%v) for h = %v returned an error: %v", n, h, err)
+			continue
+		}
+		if proof.V1 != expected {
+			t.Errorf("Select(%v) for h = %v returned V(1) = %v, but we expected %v", n, h, proof.V1, expected)
+		}
+		if err := proof.Verify(); err != nil {
+			t.Errorf("The proof of V(1) = %v for %v * 2^%v - 1 does not verify: %v", proof.V1, h, n, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestV1SelectorRange(t *testing.T) {
+
+	// Over a range of n, the selector agrees with GenV1, including on the errors
+	for _, h := range []int64{3, 5, 7, 15, 45, 507, 8565, 391581} {
+		selector, _ := NewV1Selector(h)
+		for n := int64(2); n <= 500; n++ {
+			R, _ := NewRieselNumber(h, n)
+			expected, expectedErr := GenV1(R, RODSETH)
+
+			proof, err := selector.Select(n)
+			if (err != nil) != (expectedErr != nil) {
+				t.Errorf("Select(%v) for h = %v returned the error %v, but GenV1 returned %v", n, h, err,
+					expectedErr)
+				continue
+			}
+			if err == nil && proof.V1 != expected {
+				t.Errorf("Select(%v) for h = %v returned V(1) = %v, but GenV1 returned %v", n, h, proof.V1,
+					expected)
+			}
+		}
+	}
+}
+
+func TestV1SelectorVerify(t *testing.T) {
+	var testCases = []struct {
+		h, n  int64
+		v1    int64
+		valid bool
+	}{
+		{3, 1274, 5, true},
+		{3, 1274, 3, false},
+		{3, 1274, 4, false},
+		{8565, 15, 9, true},
+		{8565, 15, 5, false},
+		{5, 148, 4, true},
+		{5, 148, 3, false},
+		{507, 2005, 3, true},
+	}
+
+	for _, c := range testCases {
+		R, _ := NewRieselNumber(c.h, c.n)
+		selector, _ := NewV1Selector(c.h)
+
+		_, err := NewV1Proof(R, c.v1)
+		_, serr := selector.Verify(R, c.v1)
+		if (err == nil) != c.valid || (serr == nil) != c.valid {
+			t.Errorf("Verifying V(1) = %v for %v returned %v and %v, but we expected valid = %v", c.v1, R, err,
+				serr, c.valid)
+		}
+	}
+}
+
+func TestV1ProofVerify(t *testing.T) {
+	R, _ := NewRieselNumber(8565, 15)
+	proof, err := NewV1Proof(R, 9)
+	if err != nil {
+		t.Fatalf("NewV1Proof(%v, 9) returned an error: %v", R, err)
+	}
+	if err := proof.Verify(); err != nil {
+		t.Errorf("The proof of V(1) = 9 for %v does not verify: %v", R, err)
+	}
+
+	// A proof with a wrong Jacobi symbol must not verify
+	proof.JacobiPlus = 1
+	if err := proof.Verify(); err == nil {
+		t.Errorf("A proof with a wrong Jacobi symbol was verified")
+	}
+}
+
+func TestOrderOfTwo(t *testing.T) {
+	var testCases = []struct {
+		x, expected int64
+	}{
+		{1, 1},
+		{3, 2},
+		{5, 4},
+		{7, 3},
+		{9, 6},
+		{15, 4},
+		{23, 11},
+		{8191, 13},
+	}
+
+	for _, c := range testCases {
+		if actual := orderOfTwo(c.x); actual != c.expected {
+			t.Errorf("orderOfTwo(%v) == %v, but we expected %v", c.x, actual, c.expected)
+		}
+	}
+}
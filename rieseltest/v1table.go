@@ -0,0 +1,186 @@
+package rieseltest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// v1TableHeader is the first line of every V(1) table file. The trailing
+// number is the version of the format.
+const v1TableHeader = "goprime v1 table 1"
+
+// DefaultV1TableMaxP is the largest V(1) stored in a table when no limit is
+// given. The odd x <= 43 have orders of 2 dividing 27720, which is thus the
+// period of the table.
+const DefaultV1TableMaxP = 41
+
+// maxV1TablePeriod bounds the size of a V(1) table.
+const maxV1TablePeriod = 1 << 24
+
+// errBeyondTable is returned by the Jacobi symbols of the generator when
+// V(1) is larger than the limit of the table.
+var errBeyondTable = errors.New("V(1) is beyond the limit of the table")
+
+// A V1Table holds the V(1) selected by the RODSETH method for the numbers
+// h*2^n-1 with a fixed h multiple of 3, over a period of n.
+//
+// For n >= 3, Jacobi(2, N) == 1, and for an odd x the symbol Jacobi(x, N)
+// depends on n only through n mod ord_x(2) (see efficientJacobi). Since the
+// RODSETH method looks for the first P such that Jacobi(P-2, N) == 1 and
+// Jacobi(P+2, N) == -1, whenever V(1) <= MaxP it only depends on n mod Period,
+// where Period is the lcm of the ord_x(2) of the odd x <= MaxP + 2.
+type V1Table struct {
+	H      int64
+	MaxP   int64
+	Period int64
+
+	// values[n mod Period] is the V(1) of h*2^n-1, or 0 when it is larger
+	// than MaxP or when N has a small factor.
+	values []uint8
+}
+
+// NewV1Table computes the V(1) table of h, storing the V(1) <= maxP.
+//
+// This function requires:
+//		a) h >= 1
+//		b) h mod 2 == 1
+//		c) h mod 3 == 0
+//		d) 3 <= maxP <= 253
+func NewV1Table(h, maxP int64) (*V1Table, error) {
+
+	// Check preconditions
+	if h < 1 || h % 2 == 0 || h % 3 != 0 {
+		return nil, errors.New(fmt.Sprintf("Expected an odd h >= 1 multiple of 3, but received h = %v", h))
+	}
+	if maxP < 3 || maxP > 253 {
+		return nil, errors.New(fmt.Sprintf("Expected 3 <= maxP <= 253, but received maxP = %v", maxP))
+	}
+
+	period := int64(1)
+	for x := int64(3); x <= maxP + 2; x += 2 {
+		order := orderOfTwo(x)
+		period = period / gcd(period, order) * order
+		if period > maxV1TablePeriod {
+			return nil, errors.New(fmt.Sprintf("The period of the V(1) table for maxP = %v is larger than %v",
+				maxP, maxV1TablePeriod))
+		}
+	}
+
+	t := &V1Table{H: h, MaxP: maxP, Period: period, values: make([]uint8, period)}
+
+	for class := int64(0); class < period; class++ {
+
+		// Any n >= 3 in the class gives the same symbols
+		n := class
+		for n < 3 {
+			n += period
+		}
+
+		cache := make(map[int64]int)
+		v1, err := genV1RodsethWith(func(x int64) (int, error) {
+			if x > maxP + 2 {
+				return 0, errBeyondTable
+			}
+			return efficientJacobi(x, h, n, cache)
+		})
+		if err == nil {
+			t.values[class] = uint8(v1)
+		}
+	}
+
+	return t, nil
+}
+
+// Lookup returns the V(1) of h*2^n-1, and false if it is not in the table.
+// When it is not, GenV1 must be used, which also reports the small factors of N.
+func (t *V1Table) Lookup(n int64) (int64, bool) {
+	if n < 3 {
+		return 0, false
+	}
+
+	v1 := t.values[n % t.Period]
+	return int64(v1), v1 != 0
+}
+
+// Coverage returns the fraction of the classes of n whose V(1) is in the table.
+func (t *V1Table) Coverage() float64 {
+	covered := 0
+	for _, v1 := range t.values {
+		if v1 != 0 {
+			covered++
+		}
+	}
+	return float64(covered) / float64(len(t.values))
+}
+
+// WriteV1Table writes the table to the file at path, atomically.
+//
+// The file has a plain text header followed by one byte per class of n:
+//
+//		goprime v1 table 1
+//		h maxp period
+//		V(1) of the classes 0, 1, ..., period - 1
+func WriteV1Table(path string, t *V1Table) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	fmt.Fprintln(w, v1TableHeader)
+	fmt.Fprintln(w, t.H, t.MaxP, t.Period)
+	w.Write(t.values)
+
+	if err = w.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// LoadV1Table reads the table saved in the file at path.
+func LoadV1Table(path string) (*V1Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	header, err := r.ReadString('\n')
+	if err != nil || header != v1TableHeader + "\n" {
+		return nil, errors.New(fmt.Sprintf("%v is not a valid V(1) table file", path))
+	}
+
+	t := new(V1Table)
+	if _, err = fmt.Fscanln(r, &t.H, &t.MaxP, &t.Period); err != nil {
+		return nil, errors.New(fmt.Sprintf("%v is not a valid V(1) table file: %v", path, err))
+	}
+	if t.H < 1 || t.H % 6 != 3 || t.MaxP < 3 || t.MaxP > 253 || t.Period < 1 || t.Period > maxV1TablePeriod {
+		return nil, errors.New(fmt.Sprintf("%v is not a valid V(1) table file: bad parameters", path))
+	}
+
+	t.values = make([]uint8, t.Period)
+	if _, err = io.ReadFull(r, t.values); err != nil {
+		return nil, errors.New(fmt.Sprintf("%v is not a valid V(1) table file: %v", path, err))
+	}
+	for _, v1 := range t.values {
+		if v1 != 0 && (v1 < 3 || int64(v1) > t.MaxP) {
+			return nil, errors.New(fmt.Sprintf("%v is not a valid V(1) table file: bad V(1) = %v", path, v1))
+		}
+	}
+
+	return t, nil
+}
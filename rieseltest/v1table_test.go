@@ -0,0 +1,132 @@
+package rieseltest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestV1Table(t *testing.T) {
+
+	// The table must agree with genV1Rodseth on every n it covers
+	for _, h := range []int64{3, 15, 507, 8565, 14549535} {
+		table, err := NewV1Table(h, DefaultV1TableMaxP)
+		if err != nil {
+			t.Fatalf("NewV1Table(%v) returned an error: %v", h, err)
+		}
+		if table.Period != 27720 {
+			t.Errorf("NewV1Table(%v) has period %v, but we expected 27720", h, table.Period)
+		}
+
+		covered := 0
+		for n := int64(2); n <= 3000; n++ {
+			expected, err := genV1Rodseth(h, n)
+
+			v1, ok := table.Lookup(n)
+			switch {
+			case ok && (err != nil || v1 != expected):
+				t.Errorf("Lookup(%v) for h = %v returned %v, but genV1Rodseth returned %v, %v", n, h, v1,
+					expected, err)
+			case !ok && err == nil && expected <= table.MaxP && n >= 3:
+				t.Errorf("Lookup(%v) for h = %v is missing V(1) = %v", n, h, expected)
+			case ok:
+				covered++
+			}
+		}
+		if covered == 0 {
+			t.Errorf("The table of h = %v covers no n", h)
+		}
+	}
+}
+
+func TestV1TableOptions(t *testing.T) {
+	table, _ := NewV1Table(3, 11)
+	selector, _ := NewV1Selector(3)
+
+	for _, opts := range []*Options{
+		{V1Method: RODSETH, V1Table: table},
+		{V1Method: RODSETH, V1Table: table, V1Selector: selector},
+	} {
+		for n := int64(2); n <= 2000; n++ {
+			R, _ := NewRieselNumber(3, n)
+			expected, expectedErr := genV1Rodseth(3, n)
+			actual, err := selectV1(R, opts)
+			if actual != expected || (err == nil) != (expectedErr == nil) {
+				t.Errorf("selectV1(%v) with a table returned %v, %v, but we expected %v, %v", R, actual, err,
+					expected, expectedErr)
+			}
+		}
+	}
+
+	// The table of another h is ignored
+	R, _ := NewRieselNumber(5, 148)
+	if v1, err := selectV1(R, &Options{V1Method: RODSETH, V1Table: table}); v1 != 4 || err != nil {
+		t.Errorf("selectV1(%v) with the table of h = 3 returned %v, %v, but we expected 4", R, v1, err)
+	}
+}
+
+func TestV1TableWrongEntry(t *testing.T) {
+	table, _ := NewV1Table(3, 11)
+
+	// Replace the V(1) of 3*2^1274-1 by a V(1) which is not valid for it
+	R, _ := NewRieselNumber(3, 1274)
+	class := R.n % table.Period
+	for v1 := uint8(3); v1 <= 11; v1++ {
+		if _, err := NewV1Proof(R, int64(v1)); err != nil {
+			table.values[class] = v1
+			break
+		}
+	}
+
+	if _, err := Test(R, &Options{V1Method: RODSETH, V1Table: table}); err == nil {
+		t.Errorf("Test(%v) accepted the wrong V(1) = %v of the table", R, table.values[class])
+	}
+}
+
+func TestWriteV1Table(t *testing.T) {
+	table, err := NewV1Table(507, 17)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "v1-507.table")
+	if err := WriteV1Table(path, table); err != nil {
+		t.Fatalf("WriteV1Table returned an error: %v", err)
+	}
+
+	loaded, err := LoadV1Table(path)
+	if err != nil {
+		t.Fatalf("LoadV1Table returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, table) {
+		t.Errorf("LoadV1Table returned a different table than the one written")
+	}
+
+	// A truncated table is not valid
+	info, _ := os.Stat(path)
+	if err := os.Truncate(path, info.Size() - 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadV1Table(path); err == nil {
+		t.Errorf("LoadV1Table accepted a truncated table")
+	}
+}
+
+func TestNewV1TableErrors(t *testing.T) {
+	var testCases = []struct {
+		h, maxP int64
+	}{
+		{0, 41},
+		{6, 41},
+		{5, 41},
+		{3, 2},
+		{3, 254},
+	}
+
+	for _, c := range testCases {
+		if _, err := NewV1Table(c.h, c.maxP); err == nil {
+			t.Errorf("NewV1Table(%v, %v) didn't return an error", c.h, c.maxP)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package rieseltest
+
+import (
+	"reflect"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// Version is the version of goprime, recorded together with the results of the tests.
+const Version = "0.2.0"
+
+// Backend returns the import path of the multi-precision library used for the
+// arithmetic, as selected by change_multiplication_algorithm.sh.
+func Backend() string {
+	return reflect.TypeOf(big.Int{}).PkgPath()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/arcetri/goprime/ledger"
+	"github.com/arcetri/goprime/rieseltest"
+	"github.com/arcetri/goprime/rieseltest/covering"
+	"github.com/arcetri/goprime/search"
+)
+
+// runSearch implements the "goprime search" command, which finds the primes
+// h*2^n-1 for a fixed h over a range of n.
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime search -h h -nmin n -nmax n [-workers N] [-sieve limit] [-dir dir] [-v1table file]\n")
+		fmt.Print("                 [-output text|json|tsv] [-metrics addr]\n\n")
+		fmt.Print("Sieves the range and tests the remaining candidates. Running the same search again\n")
+		fmt.Print("resumes it from the state saved in its directory.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	hPtr := fs.Int64("h", 0, "The h of the numbers h*2^n-1 to search.")
+	nminPtr := fs.Int64("nmin", 0, "Smallest n of the search.")
+	nmaxPtr := fs.Int64("nmax", 0, "Largest n of the search.")
+	workersPtr := fs.Int("workers", 1, "Number of candidates tested concurrently.")
+	sievePtr := fs.Uint64("sieve", search.DefaultSieveLimit, "Largest prime used to sieve the candidates.")
+	dirPtr := fs.String("dir", "", "Directory where the state of the search is saved " +
+		"(default search-h-nmin-nmax).")
+	ledgerPtr := fs.String("ledger", "", "Ledger to which the results are appended.")
+	certificatesPtr := fs.String("certificates", "", "Directory where the certificates of the primes " +
+		"found are written.")
+	v1TablePtr := fs.String("v1table", "", "V(1) table of h written by goprime v1table.")
+	knownPtr := fs.String("known", "", "Database of the known primes, which are reported as known " +
+		"without being tested ('" + embeddedKnownPrimes + "' for the database embedded in goprime). The " +
+		"imported primes are not verified.")
+	configureLogger := loggerFlags(fs)
+	openOutput := outputFlag(fs)
+	serveMetrics := metricsFlag(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if *hPtr < 1 || *nminPtr < 2 || *nmaxPtr < *nminPtr || *workersPtr < 1 {
+		return usageError(fs, "Expected h >= 1, 2 <= nmin <= nmax and workers >= 1.")
+	}
+	out, err := openOutput()
+	if err != nil {
+		return usageError(fs, err.Error())
+	}
+	info := infoWriter(out)
+
+	v1Table, err := loadV1Table(*v1TablePtr)
+	if err != nil {
+		return err
+	}
+
+	known, err := openKnownPrimes(*knownPtr)
+	if err != nil {
+		return err
+	}
+	opts := rieseltest.DefaultOptions()
+	opts.KnownPrimes = known
+	opts.V1Table = v1Table
+	if opts.Monitor, err = serveMetrics(); err != nil {
+		return err
+	}
+	opts.Stop = handleSignals()
+
+	s := &search.Search{H: *hPtr, NMin: *nminPtr, NMax: *nmaxPtr, SieveLimit: *sievePtr, Workers: *workersPtr,
+		Dir: *dirPtr, Options: opts}
+	if s.Dir == "" {
+		s.Dir = s.DefaultDir()
+	}
+
+	// Report the expected yield of the search
+	weight, err := covering.NashWeight(s.H)
+	if err != nil {
+		return err
+	}
+	expected, err := covering.ExpectedPrimes(s.H, weight, s.NMin, s.NMax)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(info, "Nash weight of %v: %v, expected primes: %.2f\n", s.H, weight, expected)
+
+	var l *ledger.Ledger
+	if *ledgerPtr != "" {
+		if l, err = ledger.Open(*ledgerPtr); err != nil {
+			return err
+		}
+		defer l.Close()
+
+		// The n eliminated without being tested are recorded as composite, so
+		// that the range is complete in the ledger
+		s.Eliminated = func(e *search.Elimination) {
+			if err := l.Append(ledger.FromElimination(s.H, e.N, e.Reason)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+
+	var primes []string
+	err = s.Run(func(p *search.Progress) {
+		if out != nil {
+			if err := writeResult(out, p.PoolResult); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		} else if p.Err != nil {
+			fmt.Printf("[%v/%v] %v: %v\n", p.Done, p.Total, p.R, p.Err)
+		} else if p.Known {
+			fmt.Printf("[%v/%v] %v: %v (known prime)\n", p.Done, p.Total, p.R, p.Prime)
+		} else {
+			fmt.Printf("[%v/%v] %v: %v\n", p.Done, p.Total, p.R, p.Prime)
+		}
+		if p.Err != nil {
+			return
+		}
+
+		if p.Prime {
+			primes = append(primes, p.R.String())
+		}
+
+		// The results of a previous run are already in the ledger
+		if p.Worker == -1 {
+			return
+		}
+		if err := appendResult(l, p.Result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if err := saveCertificate(*certificatesPtr, p.Result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	})
+
+	if err == nil || len(primes) > 0 {
+		fmt.Fprintf(info, "Found %v primes (expected %.2f):\n", len(primes), expected)
+		for _, prime := range primes {
+			fmt.Fprintln(info, prime)
+		}
+	}
+	if err == rieseltest.ErrInterrupted {
+		fmt.Fprintf(os.Stderr, "The state of the search was saved to %v: run the same command to resume.\n",
+			s.Dir)
+	}
+
+	return err
+}
@@ -0,0 +1,339 @@
+// Package search finds the primes of the form h*2^n-1 for a fixed h over a
+// range of n.
+//
+// A search first checks whether h is a Riesel number, in which case there is
+// nothing to search. Then it sieves the range, removing the n for which
+// h*2^n-1 has a small factor or belongs to a class of n that is always
+// composite according to the covering-set analysis. Finally, it tests the
+// remaining candidates with a pool of workers, in increasing order of n. The state of the search is kept in a
+// directory, so that an interrupted search resumes from its checkpoints
+// without repeating the tests already completed.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arcetri/goprime/rieseltest"
+	"github.com/arcetri/goprime/rieseltest/covering"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("search")
+
+// Search describes a search for the primes h*2^n-1 with NMin <= n <= NMax.
+//
+// An even H is made odd by Run and Candidates, which move its powers of two
+// over 2^n and shift NMin and NMax accordingly, as NewRieselNumber does. The
+// eliminated n and the results are therefore reported for the odd h.
+type Search struct {
+	H    int64
+	NMin int64
+	NMax int64
+
+	// SieveLimit is the largest prime used by the sieve. When 0,
+	// DefaultSieveLimit is used.
+	SieveLimit uint64
+
+	// CoveringPrimeLimit and CoveringMaxModulus bound the primes and the
+	// moduli of the covering-set analysis. When 0, covering.DefaultPrimeLimit
+	// and covering.DefaultMaxModulus are used.
+	CoveringPrimeLimit uint64
+	CoveringMaxModulus int64
+
+	// Workers is the number of candidates tested concurrently. When 0, one
+	// candidate is tested at a time.
+	Workers int
+
+	// Dir is the directory holding the checkpoints and the completed tests of
+	// the search. When empty, the search is not resumable.
+	Dir string
+
+	// Options are the options of every test. When nil, rieseltest.DefaultOptions()
+	// are used.
+	Options *rieseltest.Options
+
+	// Eliminated, when set, is called by Run for every n of the range
+	// eliminated without being tested, so that it can be recorded with the
+	// results of the tests. When the search is resumed with the same Dir, the
+	// n eliminated by the previous run are not reported again.
+	Eliminated func(e *Elimination)
+}
+
+// Reasons for which an n of the range is eliminated without being tested.
+const (
+	Sieved  = "sieved"   // h*2^n-1 has a prime factor below the sieve limit
+	Covered = "covering" // n is in a class proven composite by the covering-set analysis
+)
+
+// An Elimination is an n of the range eliminated without being tested.
+type Elimination struct {
+	N      int64
+	Reason string // Sieved or Covered
+}
+
+// Progress is reported after every candidate tested by a Search.
+type Progress struct {
+	*rieseltest.PoolResult
+
+	// Done is the number of candidates tested so far, and Total the number
+	// of candidates left by the sieve.
+	Done  int
+	Total int
+}
+
+// DefaultDir returns the default state directory of the search.
+func (s *Search) DefaultDir() string {
+	return fmt.Sprintf("search-%d-%d-%d", s.H, s.NMin, s.NMax)
+}
+
+// Run performs the search, calling fn with the Progress after every test.
+// The candidates tested by a previous run with the same Dir are reported with
+// a Worker of -1, without being tested again.
+//
+// Run returns an error if the range could not be sieved or if some of the
+// candidates could not be tested, and rieseltest.ErrInterrupted if the search
+// was stopped through Options.Stop. The interrupted tests are resumed from
+// their checkpoints by the next run with the same Dir.
+func (s *Search) Run(fn func(p *Progress)) error {
+	if err := s.normalize(); err != nil {
+		return err
+	}
+
+	workers := s.Workers
+	if workers == 0 {
+		workers = 1
+	}
+
+	candidates, eliminated, err := s.sieve()
+	if err != nil {
+		return err
+	}
+
+	if s.Dir != "" {
+		if err := writeCandidates(s.Dir, candidates); err != nil {
+			return err
+		}
+	}
+	if err := s.reportEliminated(eliminated); err != nil {
+		return err
+	}
+
+	// Share the Jacobi symbols used to select V(1) among the tests
+	opts := rieseltest.DefaultOptions()
+	if s.Options != nil {
+		o := *s.Options
+		opts = &o
+	}
+	if opts.V1Selector == nil && s.H%2 == 1 {
+		if opts.V1Selector, err = rieseltest.NewV1Selector(s.H); err != nil {
+			return err
+		}
+	}
+
+	// The known primes are reported by the tests without being tested again
+	if opts.KnownPrimes != nil {
+		if known := opts.KnownPrimes.Primes(s.H, s.NMin, s.NMax); len(known) > 0 {
+			log.Infof("%v * 2^n - 1 is a known prime for n = %v, which will not be tested again", s.H, known)
+		}
+	}
+
+	pool, err := rieseltest.NewPool(workers, 4*workers, s.Dir, opts)
+	if err != nil {
+		return err
+	}
+
+	// Submit the candidates while the workers are running. The pool serves
+	// the cheapest candidates first, that is the smallest n.
+	errc := make(chan error, 1)
+	go func() {
+		defer pool.Close()
+		for _, n := range candidates {
+			R, err := rieseltest.NewRieselNumber(s.H, n)
+			if err == nil {
+				err = pool.Submit(R)
+			}
+			if err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- nil
+	}()
+
+	done, failed := 0, 0
+	for r := range pool.Results() {
+		done++
+		if r.Err != nil {
+			failed++
+		}
+		fn(&Progress{PoolResult: r, Done: done, Total: len(candidates)})
+	}
+
+	// Once stopped, the submission fails on the closed pool
+	err = <-errc
+	select {
+	case <-opts.Stop:
+		return rieseltest.ErrInterrupted
+	default:
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.New(fmt.Sprintf("%v candidates of the search could not be tested", failed))
+	}
+
+	return nil
+}
+
+// writeCandidates records the candidates left by the sieve in the directory
+// of the search, one n per line, for reference.
+func writeCandidates(dir string, candidates []int64) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filepath.Join(dir, "candidates"))
+	if err != nil {
+		return err
+	}
+
+	for _, n := range candidates {
+		if _, err = fmt.Fprintln(file, n); err != nil {
+			break
+		}
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// eliminatedFile is the file of the directory of the search which records that
+// the eliminated n were reported.
+const eliminatedFile = "eliminated"
+
+// reportEliminated calls s.Eliminated for the eliminated n, unless a previous
+// run with the same Dir already reported them.
+func (s *Search) reportEliminated(eliminated []Elimination) error {
+	if s.Eliminated == nil {
+		return nil
+	}
+
+	var path string
+	if s.Dir != "" {
+		path = filepath.Join(s.Dir, eliminatedFile)
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+	}
+
+	for i := range eliminated {
+		s.Eliminated(&eliminated[i])
+	}
+
+	if path == "" {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range eliminated {
+		if _, err = fmt.Fprintln(file, e.N, e.Reason); err != nil {
+			break
+		}
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Candidates returns the n of the range left by the sieve and by the
+// covering-set analysis, in increasing order. These are the n tested by Run.
+//
+// An error is returned if h is a Riesel number, since there is nothing to search.
+func (s *Search) Candidates() ([]int64, error) {
+	if err := s.normalize(); err != nil {
+		return nil, err
+	}
+
+	candidates, _, err := s.sieve()
+	return candidates, err
+}
+
+// normalize makes H odd, shifting NMin and NMax by the powers of two moved
+// over 2^n, so that the sieve, the tests and the reports use the same h.
+func (s *Search) normalize() error {
+	h, nmin, err := rieseltest.RieselParams(s.H, s.NMin)
+	if err != nil {
+		return err
+	}
+	_, nmax, err := rieseltest.RieselParams(s.H, s.NMax)
+	if err != nil {
+		return err
+	}
+
+	s.H, s.NMin, s.NMax = h, nmin, nmax
+	return nil
+}
+
+// sieve returns the candidates of the range, as Candidates, and the n
+// eliminated by the sieve or by the covering-set analysis, in increasing order.
+func (s *Search) sieve() ([]int64, []Elimination, error) {
+	limit := s.SieveLimit
+	if limit == 0 {
+		limit = DefaultSieveLimit
+	}
+	primeLimit := s.CoveringPrimeLimit
+	if primeLimit == 0 {
+		primeLimit = covering.DefaultPrimeLimit
+	}
+	maxModulus := s.CoveringMaxModulus
+	if maxModulus == 0 {
+		maxModulus = covering.DefaultMaxModulus
+	}
+
+	analysis, err := covering.Analyze(s.H, maxModulus, primeLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if analysis.Riesel {
+		return nil, nil, errors.New(fmt.Sprintf("%v is a Riesel number: %v * 2^n - 1 is composite for every n, " +
+			"as proven by the covering set %v", s.H, s.H, analysis.Cover))
+	}
+
+	log.Infof("Sieving %v * 2^n - 1 for %v <= n <= %v up to %v", s.H, s.NMin, s.NMax, limit)
+	sieved, err := Sieve(s.H, s.NMin, s.NMax, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var candidates []int64
+	var eliminated []Elimination
+	next := s.NMin
+	for _, n := range sieved {
+		for ; next < n; next++ {
+			eliminated = append(eliminated, Elimination{N: next, Reason: Sieved})
+		}
+		next = n + 1
+
+		if c, ok := analysis.Composite(n); ok {
+			log.Debugf("Skipping n = %v: %v", n, c)
+			eliminated = append(eliminated, Elimination{N: n, Reason: Covered})
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	for ; next <= s.NMax; next++ {
+		eliminated = append(eliminated, Elimination{N: next, Reason: Sieved})
+	}
+	log.Infof("%v candidates of %v are left by the sieve", len(candidates), s.NMax-s.NMin+1)
+
+	return candidates, eliminated, nil
+}
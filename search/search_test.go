@@ -0,0 +1,147 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/arcetri/goprime/ledger"
+)
+
+func TestSearch(t *testing.T) {
+	s := &Search{H: 3, NMin: 2, NMax: 150, SieveLimit: 1000, CoveringPrimeLimit: 1000, Workers: 2, Dir: t.TempDir()}
+	expected := []int64{2, 3, 4, 6, 7, 11, 18, 34, 38, 43, 55, 64, 76, 94, 103, 143}
+
+	// The second run resumes the first one, and tests nothing again
+	for run := 0; run < 2; run++ {
+		var primes []int64
+		total := 0
+
+		err := s.Run(func(p *Progress) {
+			if p.Err != nil {
+				t.Errorf("Testing %v returned an error: %v", p.R, p.Err)
+			}
+			if run == 1 && p.Worker != -1 {
+				t.Errorf("The resumed search tested %v again", p.R)
+			}
+			if p.Prime {
+				_, n := p.R.Params()
+				primes = append(primes, n)
+			}
+			total = p.Total
+		})
+		if err != nil {
+			t.Fatalf("Run returned an error: %v", err)
+		}
+
+		sort.Slice(primes, func(i, j int) bool { return primes[i] < primes[j] })
+		if !reflect.DeepEqual(primes, expected) {
+			t.Errorf("The search found the primes 3 * 2^n - 1 for n in %v, but we expected %v", primes, expected)
+		}
+
+		if candidates, _ := Sieve(s.H, s.NMin, s.NMax, s.SieveLimit); total != len(candidates) {
+			t.Errorf("The search reported %v candidates, but we expected %v", total, len(candidates))
+		}
+	}
+}
+
+func TestSearchRieselNumber(t *testing.T) {
+	s := &Search{H: 509203, NMin: 2, NMax: 100, CoveringPrimeLimit: 1000}
+	if err := s.Run(func(p *Progress) { t.Errorf("The search of a Riesel number tested %v", p.R) }); err == nil {
+		t.Errorf("The search of the Riesel number 509203 should return an error, but it didn't")
+	}
+}
+
+func TestSearchCandidates(t *testing.T) {
+	s := &Search{H: 3, NMin: 2, NMax: 150, SieveLimit: 1000, CoveringPrimeLimit: 1000}
+	candidates, err := s.Candidates()
+	if err != nil {
+		t.Fatalf("Candidates returned an error: %v", err)
+	}
+
+	sieved, _ := Sieve(s.H, s.NMin, s.NMax, s.SieveLimit)
+	if !reflect.DeepEqual(candidates, sieved) {
+		t.Errorf("Candidates returned %v, but we expected the n left by the sieve %v", candidates, sieved)
+	}
+
+	s = &Search{H: 509203, NMin: 2, NMax: 100, CoveringPrimeLimit: 1000}
+	if candidates, err := s.Candidates(); err == nil {
+		t.Errorf("Candidates of the Riesel number 509203 returned %v, but we expected an error", candidates)
+	}
+}
+
+func TestSearchGaps(t *testing.T) {
+	s := &Search{H: 3, NMin: 2, NMax: 200, SieveLimit: 1000, CoveringPrimeLimit: 1000, Dir: t.TempDir()}
+
+	// Record the results and the eliminated n as the goprime search command does
+	var records []*ledger.Record
+	s.Eliminated = func(e *Elimination) {
+		records = append(records, ledger.FromElimination(s.H, e.N, e.Reason))
+	}
+	err := s.Run(func(p *Progress) {
+		if p.Err != nil {
+			t.Errorf("Testing %v returned an error: %v", p.R, p.Err)
+			return
+		}
+		records = append(records, ledger.FromResult(p.Result))
+	})
+	if err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+
+	if gaps := ledger.Gaps(records, s.H, s.NMin, s.NMax); len(gaps) != 0 {
+		t.Errorf("The complete search left the gaps %v", gaps)
+	}
+
+	// The eliminated n are only reported by the first run
+	eliminated := 0
+	s.Eliminated = func(e *Elimination) { eliminated++ }
+	if err = s.Run(func(p *Progress) {}); err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	if eliminated != 0 {
+		t.Errorf("The resumed search reported %v eliminated n again", eliminated)
+	}
+}
+
+func TestSearchEvenH(t *testing.T) {
+	s := &Search{H: 6, NMin: 2, NMax: 30, SieveLimit: 1000, CoveringPrimeLimit: 1000, Dir: t.TempDir()}
+	expected := []int64{3, 4, 6, 7, 11, 18}
+
+	// The eliminated n and the results must be recorded for 3 * 2^(n+1) - 1
+	var records []*ledger.Record
+	s.Eliminated = func(e *Elimination) {
+		records = append(records, ledger.FromElimination(s.H, e.N, e.Reason))
+	}
+	err := s.Run(func(p *Progress) {
+		if p.Err != nil {
+			t.Errorf("Testing %v returned an error: %v", p.R, p.Err)
+			return
+		}
+		records = append(records, ledger.FromResult(p.Result))
+	})
+	if err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+
+	var primes []int64
+	for _, r := range records {
+		if r.H != 3 {
+			t.Errorf("The search recorded h = %v for n = %v, but we expected h = 3", r.H, r.N)
+		}
+		if r.Verdict == ledger.Prime {
+			primes = append(primes, r.N)
+		}
+	}
+	sort.Slice(primes, func(i, j int) bool { return primes[i] < primes[j] })
+	if !reflect.DeepEqual(primes, expected) {
+		t.Errorf("The search found the primes 3 * 2^n - 1 for n in %v, but we expected %v", primes, expected)
+	}
+
+	if gaps := ledger.Gaps(records, 3, 3, 31); len(gaps) != 0 {
+		t.Errorf("The complete search left the gaps %v", gaps)
+	}
+	if gaps := ledger.Gaps(records, 6, 2, 30); len(gaps) != 0 {
+		t.Errorf("The complete search left the gaps %v for h = 6", gaps)
+	}
+}
@@ -0,0 +1,158 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/arcetri/goprime/rieseltest/covering"
+)
+
+// DefaultSieveLimit is the largest prime used by Sieve when no limit is given.
+const DefaultSieveLimit = 1 << 20
+
+// Sieve returns, in increasing order, the n in [nmin, nmax] such that h*2^n-1
+// has no prime factor p with 3 <= p <= limit, unless h*2^n-1 is p itself.
+//
+// This function requires:
+//		a) h >= 1
+//		b) 2 <= nmin <= nmax
+//		c) 3 <= limit < 2^32
+//
+// For every prime p not dividing h, p divides h*2^n-1 exactly when
+// 2^n == h^-1 (mod p). The n with this property, if any, form an arithmetic
+// progression with difference ord_p(2), whose first term in the range is the
+// discrete logarithm found by baby-step giant-step in O(sqrt(p)) operations.
+func Sieve(h, nmin, nmax int64, limit uint64) ([]int64, error) {
+
+	// Check preconditions
+	if h < 1 {
+		return nil, errors.New(fmt.Sprintf("Expected h >= 1, but received h = %v", h))
+	}
+	if nmin < 2 || nmax < nmin {
+		return nil, errors.New(fmt.Sprintf("Expected 2 <= nmin <= nmax, but received nmin = %v, nmax = %v",
+			nmin, nmax))
+	}
+	if limit < 3 || limit >= 1<<32 {
+		return nil, errors.New(fmt.Sprintf("Expected 3 <= limit < 2^32, but received limit = %v", limit))
+	}
+
+	removed := make([]bool, nmax-nmin+1)
+
+	for _, p := range covering.Primes(limit) {
+		hm := uint64(h) % p
+		if hm == 0 {
+			// h*2^n-1 == -1 (mod p) for every n
+			continue
+		}
+
+		// Solve 2^m == h^-1 * 2^-nmin (mod p), so that n = nmin + m
+		inv2 := (p + 1) / 2
+		target := mulMod(powMod(hm, p-2, p), powMod(inv2, uint64(nmin)%(p-1), p), p)
+		order := multiplicativeOrder(2, p)
+
+		m, ok := discreteLog(target, order, p)
+		if !ok {
+			continue
+		}
+
+		for n := nmin + int64(m); n <= nmax; n += int64(order) {
+			if !isEqual(h, n, p) {
+				removed[n-nmin] = true
+			}
+		}
+	}
+
+	var candidates []int64
+	for i, r := range removed {
+		if !r {
+			candidates = append(candidates, nmin+int64(i))
+		}
+	}
+
+	return candidates, nil
+}
+
+// isEqual returns true if h*2^n-1 == p.
+func isEqual(h, n int64, p uint64) bool {
+	if n >= 32 || uint64(h) > p {
+		return false
+	}
+	return uint64(h)<<uint(n)-1 == p
+}
+
+// mulMod returns a*b mod p. It requires a, b < p < 2^32.
+func mulMod(a, b, p uint64) uint64 {
+	return a * b % p
+}
+
+// powMod returns base^exponent mod p. It requires base < p < 2^32.
+func powMod(base, exponent, p uint64) uint64 {
+	result := uint64(1) % p
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			result = mulMod(result, base, p)
+		}
+		base = mulMod(base, base, p)
+		exponent >>= 1
+	}
+	return result
+}
+
+// multiplicativeOrder returns the smallest d > 0 such that a^d == 1 (mod p),
+// for a prime p not dividing a.
+func multiplicativeOrder(a, p uint64) uint64 {
+	order := p - 1
+
+	// Remove from p-1 every prime factor q that keeps a^(order/q) == 1
+	rest := p - 1
+	for q := uint64(2); q*q <= rest; q++ {
+		if rest%q != 0 {
+			continue
+		}
+		for rest%q == 0 {
+			rest /= q
+		}
+		for order%q == 0 && powMod(a, order/q, p) == 1 {
+			order /= q
+		}
+	}
+	if rest > 1 {
+		for order%rest == 0 && powMod(a, order/rest, p) == 1 {
+			order /= rest
+		}
+	}
+
+	return order
+}
+
+// discreteLog returns the smallest m in [0, order) such that 2^m == target
+// (mod p), where order is ord_p(2), using the baby-step giant-step algorithm.
+// The second return value is false if there is no such m.
+func discreteLog(target, order, p uint64) (uint64, bool) {
+	steps := uint64(math.Ceil(math.Sqrt(float64(order))))
+
+	// Baby steps: 2^j for 0 <= j < steps
+	baby := make(map[uint64]uint64, steps)
+	value := uint64(1)
+	for j := uint64(0); j < steps; j++ {
+		if _, ok := baby[value]; !ok {
+			baby[value] = j
+		}
+		value = mulMod(value, 2, p)
+	}
+
+	// Giant steps: target * 2^(-i*steps) for 0 <= i <= steps
+	giant := powMod((p+1)/2, steps, p)
+	gamma := target
+	for i := uint64(0); i <= steps; i++ {
+		if j, ok := baby[gamma]; ok {
+			if m := i*steps + j; m < order {
+				return m, true
+			}
+		}
+		gamma = mulMod(gamma, giant, p)
+	}
+
+	return 0, false
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/arcetri/goprime/rieseltest/covering"
+
+	big "math/big"
+	// big "github.com/arcetri/gmp"
+	// big "github.com/arcetri/go.flint/fmpz"
+)
+
+// bruteForceSieve is a slow reference implementation of Sieve.
+func bruteForceSieve(h, nmin, nmax int64, limit uint64) []int64 {
+	var candidates []int64
+	for n := nmin; n <= nmax; n++ {
+		N := new(big.Int).Lsh(big.NewInt(h), uint(n))
+		N.Sub(N, big.NewInt(1))
+
+		removed := false
+		for _, p := range covering.Primes(limit) {
+			bp := new(big.Int).SetUint64(p)
+			if new(big.Int).Mod(N, bp).Sign() == 0 && N.Cmp(bp) != 0 {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			candidates = append(candidates, n)
+		}
+	}
+	return candidates
+}
+
+func TestSieve(t *testing.T) {
+	var testCases = []struct {
+		h, nmin, nmax int64
+		limit         uint64
+	}{
+		{1, 2, 300, 1000},
+		{3, 2, 300, 1000},
+		{5, 10, 400, 3000},
+		{507, 2, 300, 2000},
+		{8565, 100, 500, 5000},
+		{15, 2, 100, 3},
+	}
+
+	for _, c := range testCases {
+		actual, err := Sieve(c.h, c.nmin, c.nmax, c.limit)
+		if err != nil {
+			t.Fatalf("Sieve(%v, %v, %v, %v) returned an error: %v", c.h, c.nmin, c.nmax, c.limit, err)
+		}
+
+		expected := bruteForceSieve(c.h, c.nmin, c.nmax, c.limit)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Sieve(%v, %v, %v, %v) returned %v, but we expected %v", c.h, c.nmin, c.nmax, c.limit,
+				actual, expected)
+		}
+	}
+}
+
+func TestSieveError(t *testing.T) {
+	var testCases = []struct {
+		h, nmin, nmax int64
+		limit         uint64
+	}{
+		{0, 2, 10, 100},
+		{3, 1, 10, 100},
+		{3, 10, 9, 100},
+		{3, 2, 10, 2},
+		{3, 2, 10, 1 << 32},
+	}
+
+	for _, c := range testCases {
+		if _, err := Sieve(c.h, c.nmin, c.nmax, c.limit); err == nil {
+			t.Errorf("Sieve(%v, %v, %v, %v) should return an error, but it didn't", c.h, c.nmin, c.nmax, c.limit)
+		}
+	}
+}
+
+func TestMultiplicativeOrder(t *testing.T) {
+	var testCases = []struct {
+		p, expected uint64
+	}{
+		{3, 2},
+		{7, 3},
+		{31, 5},
+		{127, 7},
+		{257, 16},
+		{65537, 32},
+		{8191, 13},
+		{999983, 499991},
+		{1000003, 1000002},
+	}
+
+	for _, c := range testCases {
+		if actual := multiplicativeOrder(2, c.p); actual != c.expected {
+			t.Errorf("multiplicativeOrder(2, %v) returned %v, but we expected %v", c.p, actual, c.expected)
+		}
+	}
+}
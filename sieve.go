@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/arcetri/goprime/search"
+)
+
+// runSieve implements the "goprime sieve" command, which prints the candidates
+// of a range of n left by the sieve of a search.
+func runSieve(args []string) error {
+	fs := flag.NewFlagSet("sieve", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime sieve -h h -nmin n -nmax n [-sieve limit]\n\n")
+		fmt.Print("Prints the h*2^n-1 with nmin <= n <= nmax which have no prime factor up to the limit,\n")
+		fmt.Print("and are not ruled out by a covering set, as \"h n\" pairs. The output can be tested\n")
+		fmt.Print("with goprime test -batch.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	hPtr := fs.Int64("h", 0, "The h of the numbers h*2^n-1 to sieve.")
+	nminPtr := fs.Int64("nmin", 0, "Smallest n of the range.")
+	nmaxPtr := fs.Int64("nmax", 0, "Largest n of the range.")
+	sievePtr := fs.Uint64("sieve", search.DefaultSieveLimit, "Largest prime used to sieve the candidates.")
+	configureLogger := loggerFlags(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if *hPtr < 1 || *nminPtr < 2 || *nmaxPtr < *nminPtr || fs.NArg() != 0 {
+		return usageError(fs, "Expected h >= 1 and 2 <= nmin <= nmax.")
+	}
+
+	s := &search.Search{H: *hPtr, NMin: *nminPtr, NMax: *nmaxPtr, SieveLimit: *sievePtr}
+	candidates, err := s.Candidates()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range candidates {
+		fmt.Printf("%v %v\n", s.H, n)
+	}
+	fmt.Fprintf(os.Stderr, "%v candidates of %v are left by the sieve\n", len(candidates), s.NMax - s.NMin + 1)
+	return nil
+}
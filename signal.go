@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// handleSignals traps the signals sent to goprime, and returns a channel to be
+// used as rieseltest.Options.Stop:
+//		a) the first SIGINT or SIGTERM closes the channel, so that the tests in
+//		   progress stop at their next iteration and save their checkpoints
+//		b) a second SIGINT or SIGTERM exits immediately with exitForced
+//		c) SIGHUP starts a new log file, so that the logs can be rotated
+func handleSignals() <-chan struct{} {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	go func() {
+		stopping := false
+		for sig := range signals {
+			switch {
+			case sig == syscall.SIGHUP:
+				if err := rieseltest.RotateLogs(); err != nil {
+					fmt.Fprintf(os.Stderr, "Could not rotate the logs: %v\n", err)
+				}
+			case !stopping:
+				stopping = true
+				fmt.Fprintf(os.Stderr, "Received %v: stopping the tests at their next iteration, " +
+					"send it again to exit immediately\n", sig)
+				close(stop)
+			default:
+				fmt.Fprintf(os.Stderr, "Received %v again: exiting without saving\n", sig)
+				os.Exit(exitForced)
+			}
+		}
+	}()
+
+	return stop
+}
+
+// partialRecord describes a test interrupted by a signal. It is written as
+// JSON next to the checkpoint from which the test resumes.
+type partialRecord struct {
+	H          int64     `json:"h"`
+	N          int64     `json:"n"`
+	V1         int64     `json:"v1"`
+	Iteration  int64     `json:"iteration"`
+	Residue    string    `json:"res64"`
+	Checkpoint string    `json:"checkpoint"`
+	Version    string    `json:"version"`
+	Time       time.Time `json:"time"`
+}
+
+// partialPath returns the path of the partial result record of a checkpoint.
+func partialPath(checkpoint string) string {
+	return checkpoint + ".partial"
+}
+
+// writePartial writes the partial result record of the test interrupted with
+// the given checkpoint, and reports it on stderr.
+func writePartial(checkpoint string) error {
+	c, err := rieseltest.LoadCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	r := &partialRecord{H: c.H, N: c.N, V1: c.V1, Iteration: c.Iteration,
+		Residue: c.Residue().ResidueString(), Checkpoint: checkpoint, Version: rieseltest.Version,
+		Time: time.Now().UTC()}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(partialPath(checkpoint), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Interrupted %v * 2^%v - 1 at U(%v) of U(%v), RES64 %v. The checkpoint was saved to %v: " +
+		"run the same command, or goprime resume %v, to resume.\n", c.H, c.N, c.Iteration, c.N, r.Residue, checkpoint,
+		checkpoint)
+	return nil
+}
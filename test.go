@@ -0,0 +1,275 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/arcetri/goprime/ledger"
+	"github.com/arcetri/goprime/output"
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// runTest implements the "goprime test" command, which tests a number, or the
+// numbers of a batch, with the Lucas-Lehmer-Riesel test. It is also run when
+// goprime is invoked without a command, as in "goprime 3 1000".
+func runTest(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime test [flags] h n\n")
+		fmt.Print("  goprime test [flags] expression\n")
+		fmt.Print("  goprime test -batch file [-workers N] [-checkpoints dir] [flags]\n")
+		fmt.Print("  goprime test -base b [flags] h n\n\n")
+		fmt.Print("Tests h*2^n-1 for primality. The number is given either as h and n, or as an expression\n")
+		fmt.Print("such as 3*2^1000-1 or 2^127-1, or as the decimal digits of N. It prints true if N is prime,\n")
+		fmt.Print("and false otherwise, or a record of the test with -output json or -output tsv.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+
+	// Read command line arguments
+	//		'-t level' for outputting logs of the specified level and higher to the terminal
+	//		'-f level' for outputting logs of the specified level and higher to a file
+	configureLogger := loggerFlags(fs)
+
+	//		'-batch file' for testing all the "h n" pairs listed in a file, one per line
+	//		'-workers N' for testing N numbers of the batch concurrently
+	//		'-checkpoints dir' for saving checkpoints, so that an interrupted batch can be resumed
+	batchPtr := fs.String("batch", "", "File with the h and n of the numbers to test, one " +
+		"\"h n\" pair per line ('-' for stdin).")
+	workersPtr := fs.Int("workers", 1, "Number of numbers of the batch tested concurrently.")
+	checkpointsPtr := fs.String("checkpoints", "", "Directory where the checkpoints of the batch " +
+		"are saved. Running the same batch again with the same directory resumes it.")
+
+	//		'-ledger file' for recording the results in a ledger
+	ledgerPtr := fs.String("ledger", "", "Ledger to which the results are appended.")
+
+	//		'-certificates dir' for writing the certificates of the primes found
+	certificatesPtr := fs.String("certificates", "", "Directory where the certificates of the primes " +
+		"found are written.")
+
+	//		'-interim N' for recording the RES64 of U(i) every N iterations
+	//		'-interim-pow2' for recording the RES64 of U(i) when i is a power of two
+	interimPtr := fs.Int64("interim", 0, "Record the RES64 of U(i) every this many iterations " +
+		"in the log and the ledger (0 to disable).")
+	interimPow2Ptr := fs.Bool("interim-pow2", false, "Record the RES64 of U(i) when i is a power of two.")
+
+	//		'-base b' for testing h*b^n-1 instead of h*2^n-1
+	basePtr := fs.Int64("base", 2, "Base b of the number h*b^n-1 to test. Bases other than 2 use a slower " +
+		"N+1 Lucas test, which only prints its verdict: -output, -ledger, -certificates and -checkpoint are " +
+		"not supported with them.")
+
+	//		'-checkpoint file' for saving the state of the test of a single number
+	checkpointPtr := fs.String("checkpoint", "", "File where the state of the test of h*2^n-1 is " +
+		"saved, and from which an interrupted test resumes.")
+
+	//		'-known file' for reporting the known primes without testing them again
+	knownPtr := fs.String("known", "", "Database of the known primes, which are reported as known " +
+		"without being tested ('" + embeddedKnownPrimes + "' for the database embedded in goprime). The " +
+		"imported primes are not verified.")
+
+	//		'-output format' for writing the results as json or tsv records
+	openOutput := outputFlag(fs)
+
+	//		'-metrics addr' for serving the progress of the tests to Prometheus
+	serveMetrics := metricsFlag(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	// Check for validity of command line arguments
+	if *workersPtr < 1 || *interimPtr < 0 || *basePtr < 2 {
+		return usageError(fs, "Expected workers >= 1, interim >= 0 and base >= 2.")
+	}
+	if *batchPtr != "" && fs.NArg() != 0 {
+		return usageError(fs, "Expected either -batch or the number to test, but received both.")
+	}
+	if *batchPtr == "" && fs.NArg() != 1 && fs.NArg() != 2 {
+		return usageError(fs, "")
+	}
+	out, err := openOutput()
+	if err != nil {
+		return usageError(fs, err.Error())
+	}
+
+	// Test h*b^n-1 for other bases
+	if *basePtr != 2 {
+		if *batchPtr != "" || fs.NArg() != 2 {
+			return usageError(fs, "Expected h and n with -base.")
+		}
+		if out != nil || *ledgerPtr != "" || *certificatesPtr != "" || *checkpointPtr != "" {
+			return usageError(fs, "-output, -ledger, -certificates and -checkpoint are not supported with -base.")
+		}
+		return testGeneralized(fs.Arg(0), fs.Arg(1), *basePtr)
+	}
+
+	var R *rieseltest.RieselNumber
+	if *batchPtr == "" {
+		if R, err = parseCandidate(fs.Args()); err != nil {
+			return err
+		}
+	}
+
+	// Open the ledger of the results, if requested
+	var l *ledger.Ledger
+	if *ledgerPtr != "" {
+		if l, err = ledger.Open(*ledgerPtr); err != nil {
+			return err
+		}
+		defer l.Close()
+	}
+
+	opts := rieseltest.DefaultOptions()
+	opts.InterimInterval = *interimPtr
+	opts.InterimPowersOfTwo = *interimPow2Ptr
+	known, err := openKnownPrimes(*knownPtr)
+	if err != nil {
+		return err
+	}
+	opts.KnownPrimes = known
+	if opts.Monitor, err = serveMetrics(); err != nil {
+		return err
+	}
+
+	// On SIGINT or SIGTERM, stop the tests at their next iteration
+	opts.Stop = handleSignals()
+
+	// Test the numbers of the batch, if one was given
+	if *batchPtr != "" {
+		return runBatch(*batchPtr, *workersPtr, *checkpointsPtr, *certificatesPtr, opts, l, out)
+	}
+
+	return testOne(R, opts, *checkpointPtr, *certificatesPtr, l, out)
+}
+
+// testGeneralized tests h*b^n-1 for a base b other than 2, and prints its
+// verdict. The test is stopped by SIGINT or SIGTERM, and has no checkpoint.
+func testGeneralized(hArg, nArg string, b int64) error {
+	h, err := parseInt("h", hArg)
+	if err != nil {
+		return err
+	}
+	n, err := parseInt("n", nArg)
+	if err != nil {
+		return err
+	}
+
+	G, err := rieseltest.NewGeneralizedNumber(h, b, n)
+	if err != nil {
+		return err
+	}
+	opts := rieseltest.DefaultOptions()
+	opts.Stop = handleSignals()
+	result, err := rieseltest.TestGeneralized(G, opts)
+	if err != nil {
+		return err
+	}
+
+	if result.Prime && !result.Proven {
+		fmt.Println("probable prime")
+	} else {
+		fmt.Println(result.Prime)
+	}
+	return nil
+}
+
+// testOne tests R with the given options, saving its state to the checkpoint
+// file if it is not empty, and prints the result, as a record of out if it is
+// not nil. The result is also appended to the given ledger, if it is not nil,
+// and the certificate of a prime is written to the certificates directory, if
+// it is not empty.
+//
+// When the test is interrupted through opts.Stop, the partial result record of
+// the checkpoint is written, and rieseltest.ErrInterrupted is returned.
+func testOne(R *rieseltest.RieselNumber, opts *rieseltest.Options, checkpoint, certificates string,
+	l *ledger.Ledger, out *output.Writer) error {
+	opts.CheckpointFile = checkpoint
+
+	// Test the specified Riesel number for primality
+	result, err := rieseltest.Test(R, opts)
+	if err == rieseltest.ErrInterrupted && checkpoint != "" {
+		if perr := writePartial(checkpoint); perr != nil {
+			fmt.Fprintln(os.Stderr, perr)
+		}
+		return err
+	}
+	if err != nil {
+		if out != nil {
+			out.Write(output.FromError(R, err))
+		}
+		return err
+	}
+
+	if checkpoint != "" {
+		os.Remove(partialPath(checkpoint))
+	}
+	if out != nil {
+		err = out.Write(output.FromResult(result))
+	} else {
+		fmt.Println(result.Prime)
+	}
+
+	if lerr := appendResult(l, result); err == nil {
+		err = lerr
+	}
+	if cerr := saveCertificate(certificates, result); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// runResume implements the "goprime resume" command, which resumes the test
+// of a single number from its checkpoint.
+func runResume(args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime resume [-ledger file] [-certificates dir] [-output format] [-metrics addr] file.ckpt\n\n")
+		fmt.Print("Resumes the test saved in the checkpoint, such as the file written by an interrupted\n")
+		fmt.Print("goprime test -checkpoint file, and prints its result like goprime test.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	ledgerPtr := fs.String("ledger", "", "Ledger to which the result is appended.")
+	certificatesPtr := fs.String("certificates", "", "Directory where the certificate of a prime is written.")
+	configureLogger := loggerFlags(fs)
+	openOutput := outputFlag(fs)
+	serveMetrics := metricsFlag(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if fs.NArg() != 1 {
+		return usageError(fs, "")
+	}
+	out, err := openOutput()
+	if err != nil {
+		return usageError(fs, err.Error())
+	}
+
+	checkpoint := fs.Arg(0)
+	c, err := rieseltest.LoadCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	}
+	R, err := rieseltest.NewRieselNumber(c.H, c.N)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(infoWriter(out), "Resuming %v from U(%v)\n", R, c.Iteration)
+
+	var l *ledger.Ledger
+	if *ledgerPtr != "" {
+		if l, err = ledger.Open(*ledgerPtr); err != nil {
+			return err
+		}
+		defer l.Close()
+	}
+
+	opts := rieseltest.DefaultOptions()
+	if opts.Monitor, err = serveMetrics(); err != nil {
+		return err
+	}
+	opts.Stop = handleSignals()
+
+	return testOne(R, opts, checkpoint, *certificatesPtr, l, out)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/arcetri/goprime/rieseltest"
+)
+
+// runV1Table implements the "goprime v1table" command, which precomputes the
+// V(1) table of an h multiple of 3 for the searches over a range of n.
+func runV1Table(args []string) error {
+	fs := flag.NewFlagSet("v1table", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime v1table -h h [-maxp P] [-o file]\n\n")
+		fmt.Print("Computes the periodic table of the V(1) of h*2^n-1 over n, for an odd h multiple of 3.\n")
+		fmt.Print("Pass the file to the -v1table flag of goprime search to use it.\n\n")
+		fmt.Print("Optional flags:\n")
+		fs.PrintDefaults()
+	}
+	hPtr := fs.Int64("h", 0, "The h of the table, an odd multiple of 3.")
+	maxPPtr := fs.Int64("maxp", rieseltest.DefaultV1TableMaxP, "Largest V(1) stored in the table.")
+	outPtr := fs.String("o", "", "File where the table is written (default v1-h.table).")
+	configureLogger := loggerFlags(fs)
+	fs.Parse(args)
+	configureLogger()
+
+	if *hPtr < 1 {
+		return usageError(fs, "")
+	}
+
+	t, err := rieseltest.NewV1Table(*hPtr, *maxPPtr)
+	if err != nil {
+		return err
+	}
+
+	path := *outPtr
+	if path == "" {
+		path = fmt.Sprintf("v1-%d.table", t.H)
+	}
+	if err := rieseltest.WriteV1Table(path, t); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote the V(1) table of h = %v to %v: period %v, %.1f%% of the n covered\n", t.H, path, t.Period,
+		100 * t.Coverage())
+	return nil
+}
+
+// loadV1Table loads the V(1) table at path, or returns nil if path is empty.
+func loadV1Table(path string) (*rieseltest.V1Table, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return rieseltest.LoadV1Table(path)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/arcetri/goprime/rieseltest/covering"
+)
+
+// runWeight implements the "goprime weight" command, which prints the Nash
+// weight of h and the expected number of primes in a range of n.
+func runWeight(args []string) error {
+	fs := flag.NewFlagSet("weight", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Print("Usage:\n")
+		fmt.Print("  goprime weight h [nmin nmax]\n\n")
+		fmt.Print("Prints the Nash weight of h, the number of n in [100001, 110000] such that h*2^n-1\n")
+		fmt.Print("has no prime factor < 256, and the expected number of primes with nmin <= n <= nmax.\n")
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 && fs.NArg() != 3 {
+		return usageError(fs, "")
+	}
+
+	var values []int64
+	for i, arg := range fs.Args() {
+		v, err := parseInt([]string{"h", "nmin", "nmax"}[i], arg)
+		if err != nil {
+			return err
+		}
+		values = append(values, v)
+	}
+
+	h := values[0]
+	weight, err := covering.NashWeight(h)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Nash weight of %v: %v\n", h, weight)
+
+	if len(values) == 3 {
+		expected, err := covering.ExpectedPrimes(h, weight, values[1], values[2])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Expected primes for %v <= n <= %v: %.2f\n", values[1], values[2], expected)
+	}
+
+	return nil
+}